@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveEntry 为 yt-dlp 下载记录文件中的一行，格式为 "<提取器> <视频 ID>"。
+type ArchiveEntry struct {
+	Extractor string
+	ID        string
+}
+
+// Key 返回该条目在下载记录文件中的行。
+func (e ArchiveEntry) Key() string {
+	return archiveKey(e.Extractor, e.ID)
+}
+
+// archiveKey 与 yt-dlp 下载记录文件中的行格式一致。
+func archiveKey(extractorKey, id string) string {
+	return strings.ToLower(extractorKey) + " " + id
+}
+
+// parseArchiveLine 解析下载记录文件中的一行。
+func parseArchiveLine(line string) (ArchiveEntry, bool) {
+	extractor, id, ok := strings.Cut(strings.TrimSpace(line), " ")
+	if !ok || extractor == "" || strings.TrimSpace(id) == "" {
+		return ArchiveEntry{}, false
+	}
+	return ArchiveEntry{Extractor: extractor, ID: strings.TrimSpace(id)}, true
+}
+
+// LoadArchive 按文件中的顺序读取下载记录，文件不存在时返回空列表。
+func LoadArchive(path string) ([]ArchiveEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取下载记录: %w", err)
+	}
+	defer f.Close()
+
+	var entries []ArchiveEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if e, ok := parseArchiveLine(scanner.Text()); ok {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("无法读取下载记录: %w", err)
+	}
+	return entries, nil
+}
+
+// readArchive 读取下载记录并返回以行为键的集合。
+func readArchive(path string) (map[string]bool, error) {
+	entries, err := LoadArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	archive := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		archive[e.Key()] = true
+	}
+	return archive, nil
+}
+
+// SaveArchive 写入下载记录，先写临时文件再替换，避免中途失败损坏原文件。
+func SaveArchive(path string, entries []ArchiveEntry) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("无法创建下载记录目录: %w", err)
+		}
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString(e.Key())
+		b.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("无法写入下载记录: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("无法写入下载记录: %w", err)
+	}
+	return nil
+}
+
+// RemoveArchiveEntries 从下载记录中删除指定的行，返回删除的数量。
+func RemoveArchiveEntries(path string, keys map[string]bool) (int, error) {
+	entries, err := LoadArchive(path)
+	if err != nil {
+		return 0, err
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if !keys[e.Key()] {
+			kept = append(kept, e)
+		}
+	}
+	removed := len(entries) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, SaveArchive(path, kept)
+}
+
+// ImportHistoryToArchive 将历史记录中的视频加入下载记录，已存在的跳过，返回新增的数量。
+func ImportHistoryToArchive(path string, history []HistoryEntry) (int, error) {
+	entries, err := LoadArchive(path)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		seen[e.Key()] = true
+	}
+	added := 0
+	for _, h := range history {
+		if h.Extractor == "" || h.ID == "" {
+			continue
+		}
+		e := ArchiveEntry{Extractor: strings.ToLower(h.Extractor), ID: h.ID}
+		if seen[e.Key()] {
+			continue
+		}
+		seen[e.Key()] = true
+		entries = append(entries, e)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	return added, SaveArchive(path, entries)
+}
+
+// filterArchive 返回提取器或 ID 包含 query 的条目，不区分大小写。
+func filterArchive(entries []ArchiveEntry, query string) []ArchiveEntry {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return entries
+	}
+	var result []ArchiveEntry
+	for _, e := range entries {
+		if strings.Contains(strings.ToLower(e.Key()), query) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func archiveKeys(entries []ArchiveEntry) []string {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key()
+	}
+	return keys
+}
+
+func TestLoadArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.txt")
+	if err := os.WriteFile(path, []byte("youtube abc\n\nbilibili BV1xx\ninvalid\nyoutube  def \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := LoadArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"youtube abc", "bilibili BV1xx", "youtube def"}
+	if got := archiveKeys(entries); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+
+	if entries, err := LoadArchive(filepath.Join(t.TempDir(), "none.txt")); err != nil || len(entries) != 0 {
+		t.Errorf("文件不存在时应返回空列表: %v %v", entries, err)
+	}
+}
+
+func TestRemoveArchiveEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.txt")
+	if err := SaveArchive(path, []ArchiveEntry{{"youtube", "a"}, {"youtube", "b"}, {"vimeo", "c"}}); err != nil {
+		t.Fatal(err)
+	}
+	n, err := RemoveArchiveEntries(path, map[string]bool{"youtube b": true, "youtube x": true})
+	if err != nil || n != 1 {
+		t.Fatalf("RemoveArchiveEntries = %d, %v, want 1", n, err)
+	}
+	entries, _ := LoadArchive(path)
+	if got := archiveKeys(entries); fmt.Sprint(got) != "[youtube a vimeo c]" {
+		t.Errorf("entries = %q", got)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("不应残留临时文件")
+	}
+}
+
+func TestImportHistoryToArchive(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "sub", "archive.txt")
+	historyPath := filepath.Join(dir, HistoryFileName)
+	if err := SaveArchive(archivePath, []ArchiveEntry{{"youtube", "a"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []DownloadedFile{
+		{ID: "a", Extractor: "Youtube", FilePath: "/tmp/a.mp4"},
+		{ID: "b", Extractor: "Youtube", FilePath: "/tmp/b.mp4"},
+		{ID: "b", Extractor: "Youtube", FilePath: "/tmp/b (1).mp4"},
+		{ID: "c", Extractor: "BiliBili", FilePath: "/tmp/c.mp4"},
+		{ID: "d", FilePath: "/tmp/d.mp4"},
+	} {
+		if err := AppendHistory(historyPath, HistoryEntry{Time: time.Now(), JobID: 1, DownloadedFile: f}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := LoadHistory(historyPath)
+	if err != nil || len(history) != 5 {
+		t.Fatalf("LoadHistory = %d, %v", len(history), err)
+	}
+	if history[1].FilePath != "/tmp/b.mp4" || history[1].JobID != 1 {
+		t.Errorf("history[1] = %+v", history[1])
+	}
+
+	added, err := ImportHistoryToArchive(archivePath, history)
+	if err != nil || added != 2 {
+		t.Fatalf("ImportHistoryToArchive = %d, %v, want 2", added, err)
+	}
+	entries, _ := LoadArchive(archivePath)
+	if got := archiveKeys(entries); fmt.Sprint(got) != "[youtube a youtube b bilibili c]" {
+		t.Errorf("entries = %q", got)
+	}
+
+	if added, _ := ImportHistoryToArchive(archivePath, history); added != 0 {
+		t.Errorf("重复导入不应新增记录: %d", added)
+	}
+}
+
+func TestFilterArchive(t *testing.T) {
+	entries := []ArchiveEntry{{"youtube", "AbC"}, {"bilibili", "BV1"}, {"youtube", "xyz"}}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "[youtube AbC bilibili BV1 youtube xyz]"},
+		{"abc", "[youtube AbC]"},
+		{"YouTube", "[youtube AbC youtube xyz]"},
+		{"nothing", "[]"},
+	}
+	for _, tt := range tests {
+		if got := archiveKeys(filterArchive(entries, tt.query)); fmt.Sprint(got) != tt.want {
+			t.Errorf("filterArchive(%q) = %q, want %s", tt.query, got, tt.want)
+		}
+	}
+}
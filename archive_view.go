@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showArchiveDialog 显示下载记录管理对话框，可搜索、删除条目，或从下载历史导入；
+// 下载历史中有对应任务日志（位于 logsDir）的条目可打开日志。
+func showArchiveDialog(w fyne.Window, archivePath, historyPath, logsDir string) {
+	var all, shown []ArchiveEntry
+	var logFiles map[string]string
+
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("按网站或视频 ID 搜索")
+	countLabel := widget.NewLabel("")
+
+	var list *widget.List
+	apply := func() {
+		shown = filterArchive(all, searchEntry.Text)
+		countLabel.SetText(fmt.Sprintf("共 %d 条，显示 %d 条", len(all), len(shown)))
+		list.Refresh()
+	}
+	reload := func() {
+		entries, err := LoadArchive(archivePath)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		all = entries
+		// 历史无法读取时只是不能打开日志
+		history, _ := LoadHistory(historyPath)
+		logFiles = historyLogFiles(history)
+		apply()
+	}
+
+	list = widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, nil, container.NewHBox(widget.NewButton("打开日志", nil), widget.NewButton("删除", nil)), label)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(shown) {
+				return
+			}
+			e := shown[id]
+			row := obj.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(e.Extractor + "  " + e.ID)
+			buttons := row.Objects[1].(*fyne.Container)
+			logBtn := buttons.Objects[0].(*widget.Button)
+			logPath, hasLog := existingJobLog(logsDir, logFiles[e.Key()])
+			logBtn.OnTapped = func() {
+				if err := openLocalPath(logPath); err != nil {
+					dialog.ShowError(fmt.Errorf("无法打开任务日志: %v", err), w)
+				}
+			}
+			if hasLog {
+				logBtn.Enable()
+			} else {
+				logBtn.Disable()
+			}
+			buttons.Objects[1].(*widget.Button).OnTapped = func() {
+				if _, err := RemoveArchiveEntries(archivePath, map[string]bool{e.Key(): true}); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				reload()
+			}
+		},
+	)
+	searchEntry.OnChanged = func(string) { apply() }
+
+	removeShownBtn := widget.NewButton("删除搜索结果", func() {
+		if len(shown) == 0 || searchEntry.Text == "" {
+			return
+		}
+		keys := make(map[string]bool, len(shown))
+		for _, e := range shown {
+			keys[e.Key()] = true
+		}
+		dialog.ShowConfirm("删除下载记录", fmt.Sprintf("确定删除 %d 条记录？删除后这些视频可再次下载。", len(keys)), func(ok bool) {
+			if !ok {
+				return
+			}
+			if _, err := RemoveArchiveEntries(archivePath, keys); err != nil {
+				dialog.ShowError(err, w)
+			}
+			reload()
+		}, w)
+	})
+	importBtn := widget.NewButton("从下载历史导入", func() {
+		history, err := LoadHistory(historyPath)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		added, err := ImportHistoryToArchive(archivePath, history)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		reload()
+		dialog.ShowInformation("导入完成", fmt.Sprintf("从 %d 条下载历史中新增 %d 条记录", len(history), added), w)
+	})
+	reloadBtn := widget.NewButton("刷新", reload)
+
+	toolbar := container.NewVBox(
+		widget.NewLabel("文件: "+filepath.Clean(archivePath)),
+		container.NewBorder(nil, nil, nil, reloadBtn, searchEntry),
+	)
+	bottom := container.NewHBox(countLabel, layout.NewSpacer(), removeShownBtn, importBtn)
+	content := container.NewBorder(toolbar, bottom, nil, nil, list)
+
+	dlg := dialog.NewCustom("下载记录", "关闭", content, w)
+	dlg.Resize(fyne.NewSize(640, 520))
+	reload()
+	dlg.Show()
+}
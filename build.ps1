@@ -30,10 +30,12 @@ function Remove-BuildArtifacts {
 function Remove-RuntimeFiles {
     Remove-Item -LiteralPath "yt-dlp-simpgo.ini" -Force -ErrorAction SilentlyContinue
     Remove-Item -LiteralPath "yt-dlp.conf" -Force -ErrorAction SilentlyContinue
+    Remove-Item -LiteralPath "instance.lock" -Force -ErrorAction SilentlyContinue
     Remove-Item -LiteralPath "yt-dlp.exe" -Force -ErrorAction SilentlyContinue
     Remove-Item -LiteralPath "yt-dlp" -Force -ErrorAction SilentlyContinue
     Remove-Item -Path ".yt-dlp-simpgo-update-*" -Force -ErrorAction SilentlyContinue
-    Remove-Item -LiteralPath ".yt-dlp-simpgo-update.ps1" -Force -ErrorAction SilentlyContinue
+    Remove-Item -LiteralPath ".yt-dlp-simpgo-update.ok" -Force -ErrorAction SilentlyContinue
+    Remove-Item -Path ".yt-dlp-simpgo-backup*" -Force -ErrorAction SilentlyContinue
     Remove-Item -LiteralPath "下载" -Recurse -Force -ErrorAction SilentlyContinue
 }
 
@@ -71,6 +73,8 @@ Write-Host "Installing modules..."
 go mod tidy
 
 $Ldflags = "-X 'main.Version=$Version' -s -w"
+# 校验程序更新签名的公钥已写在 version.go 中，设置环境变量 UPDATE_PUBLIC_KEY 时替换为测试用的公钥
+if ($env:UPDATE_PUBLIC_KEY) { $Ldflags += " -X 'main.UpdatePublicKey=$env:UPDATE_PUBLIC_KEY'" }
 
 if ($Gui) {
     Write-Host "Generating Windows icon resource..."
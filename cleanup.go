@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// leftoverStaleAge 为残留文件被视为过期的时间，过期的文件通常来自已失败或取消的下载。
+const leftoverStaleAge = 24 * time.Hour
+
+// LeftoverKind 为残留文件的类型。
+type LeftoverKind int
+
+const (
+	LeftoverPartial  LeftoverKind = iota // 未完成的下载（.part、.ytdl）
+	LeftoverFragment                     // 已下载但未合并的单独格式（如 .f137.mp4）
+	LeftoverTemp                         // 后处理的临时文件（.temp）
+	LeftoverSidecar                      // 属于未完成下载的字幕或缩略图
+)
+
+func (k LeftoverKind) String() string {
+	switch k {
+	case LeftoverPartial:
+		return "未完成的下载"
+	case LeftoverFragment:
+		return "未合并的分段"
+	case LeftoverTemp:
+		return "临时文件"
+	case LeftoverSidecar:
+		return "字幕/缩略图"
+	}
+	return "未知"
+}
+
+// Leftover 为下载目录中的一个残留文件。
+type Leftover struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Kind    LeftoverKind
+	Base    string // 对应的媒体文件路径（不含扩展名），同一下载的残留文件相同
+	Active  bool   // 正在运行的任务仍在写入
+	Source  Job    // 从任务日志中找到的下载任务，URL 为空表示找不到，可用于继续下载
+}
+
+// Stale 表示文件已超过 leftoverStaleAge 未修改。
+func (l Leftover) Stale(now time.Time) bool {
+	return now.Sub(l.ModTime) >= leftoverStaleAge
+}
+
+var (
+	partSuffixPattern   = regexp.MustCompile(`\.part(-Frag\d+(\.part)?)?$`)
+	formatSuffixPattern = regexp.MustCompile(`\.f(\d+(-\w+)?|(hls|dash|http)-[\w.-]+)$`)
+	subLangPattern      = regexp.MustCompile(`\.[A-Za-z]{2,3}([-_][\w-]+)?$`)
+)
+
+var subtitleExts = []string{".vtt", ".srt", ".ass", ".ssa", ".lrc", ".ttml", ".srv1", ".srv2", ".srv3", ".json3"}
+var thumbnailExts = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+// classifyLeftover 根据文件名判断是否为下载残留，返回类型和对应的媒体文件名（不含扩展名）。
+// 字幕和缩略图总是返回 LeftoverSidecar，是否属于残留由调用方根据同名的其他残留判断。
+func classifyLeftover(name string) (kind LeftoverKind, base string, ok bool) {
+	switch {
+	case partSuffixPattern.MatchString(name):
+		return LeftoverPartial, mediaBase(partSuffixPattern.ReplaceAllString(name, "")), true
+	case strings.HasSuffix(name, ".ytdl"):
+		return LeftoverPartial, mediaBase(strings.TrimSuffix(name, ".ytdl")), true
+	case strings.HasSuffix(name, ".temp"):
+		return LeftoverTemp, mediaBase(strings.TrimSuffix(name, ".temp")), true
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	switch {
+	case strings.HasSuffix(stem, ".temp"):
+		return LeftoverTemp, mediaBase(strings.TrimSuffix(stem, ".temp") + ext), true
+	case formatSuffixPattern.MatchString(stem):
+		return LeftoverFragment, formatSuffixPattern.ReplaceAllString(stem, ""), true
+	case slices.Contains(subtitleExts, ext):
+		return LeftoverSidecar, subLangPattern.ReplaceAllString(stem, ""), true
+	case slices.Contains(thumbnailExts, ext):
+		return LeftoverSidecar, stem, true
+	}
+	return 0, "", false
+}
+
+// mediaBase 去掉媒体文件名的扩展名和格式编号，如 "a.f137.mp4" 返回 "a"。
+func mediaBase(name string) string {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	return formatSuffixPattern.ReplaceAllString(stem, "")
+}
+
+// ScanLeftovers 扫描 dir 及其子目录中的下载残留文件，按修改时间从旧到新排列。
+// sources 为媒体文件路径（不含扩展名）到下载任务的映射，active 判断对应的下载是否正在进行。
+func ScanLeftovers(dir string, sources map[string]Job, active func(base string) bool) ([]Leftover, error) {
+	var found []Leftover
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 无法读取的子目录跳过，根目录不存在时返回错误
+			if p == dir {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		kind, base, ok := classifyLeftover(d.Name())
+		if !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		found = append(found, Leftover{
+			Path:    p,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			Kind:    kind,
+			Base:    filepath.Join(filepath.Dir(p), base),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("无法扫描下载目录: %w", err)
+	}
+
+	// 字幕和缩略图只在同一下载还有其他残留时才算作残留
+	downloads := map[string]bool{}
+	for _, l := range found {
+		if l.Kind != LeftoverSidecar {
+			downloads[l.Base] = true
+		}
+	}
+	var leftovers []Leftover
+	for _, l := range found {
+		if l.Kind == LeftoverSidecar && !downloads[l.Base] {
+			continue
+		}
+		l.Source = sources[l.Base]
+		if active != nil {
+			l.Active = active(l.Base)
+		}
+		leftovers = append(leftovers, l)
+	}
+	sort.Slice(leftovers, func(i, j int) bool { return leftovers[i].ModTime.Before(leftovers[j].ModTime) })
+	return leftovers, nil
+}
+
+// RemoveLeftovers 删除残留文件，跳过正在写入的文件，返回删除的数量和释放的空间。
+func RemoveLeftovers(items []Leftover) (removed int, freed int64, err error) {
+	var errs []error
+	for _, l := range items {
+		if l.Active || isActiveDownload(l.Base) {
+			continue
+		}
+		if rerr := os.Remove(l.Path); rerr != nil && !os.IsNotExist(rerr) {
+			errs = append(errs, rerr)
+			continue
+		}
+		removed++
+		freed += l.Size
+	}
+	if len(errs) > 0 {
+		return removed, freed, fmt.Errorf("部分文件无法删除: %w", errors.Join(errs...))
+	}
+	return removed, freed, nil
+}
+
+// jobLogSources 从任务日志中找出每个下载文件对应的任务（地址、下载方案和附加参数），
+// 返回媒体文件路径（不含扩展名）到任务的映射。
+func jobLogSources(logsDir string) map[string]Job {
+	sources := map[string]Job{}
+	entries, err := os.ReadDir(logsDir)
+	if err != nil {
+		return sources
+	}
+	// 按时间顺序读取，较新的任务覆盖较旧的
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), jobLogPrefix) && strings.HasSuffix(e.Name(), ".log") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := os.Open(filepath.Join(logsDir, name))
+		if err != nil {
+			continue
+		}
+		var job Job
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if _, rest, ok := strings.Cut(line, "[app] 下载地址: "); ok {
+				job = Job{URL: strings.TrimSpace(rest)}
+			} else if _, rest, ok := strings.Cut(line, "[app] 下载方案: "); ok {
+				job.Profile = strings.TrimSpace(rest)
+			} else if _, rest, ok := strings.Cut(line, "[app] 附加参数: "); ok {
+				if args, err := splitCommandLine(rest); err == nil {
+					job.ExtraArgs = withoutRedactedArgs(args)
+				}
+			} else if _, rest, ok := strings.Cut(line, "[download] Destination: "); ok && job.URL != "" {
+				dest := filepath.Clean(strings.TrimSpace(rest))
+				sources[filepath.Join(filepath.Dir(dest), mediaBase(filepath.Base(dest)))] = job
+			}
+		}
+		f.Close()
+	}
+	return sources
+}
+
+// withoutRedactedArgs 去掉日志中已隐去值的参数（如 --password ***），
+// 继续下载时这些设置改由 yt-dlp.conf 提供。
+func withoutRedactedArgs(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case i+1 < len(args) && args[i+1] == "***":
+			i++
+		case strings.HasSuffix(args[i], "=***"):
+		default:
+			result = append(result, args[i])
+		}
+	}
+	return result
+}
+
+// activeDownloads 记录正在运行的任务写入的文件（媒体文件路径，不含扩展名），清理时跳过。
+var activeDownloads = struct {
+	sync.Mutex
+	m map[string]int
+}{m: map[string]int{}}
+
+// markActiveDownload 记录正在写入的文件，返回结束时调用的函数。
+func markActiveDownload(dest string) (done func()) {
+	dest = filepath.Clean(dest)
+	base := filepath.Join(filepath.Dir(dest), mediaBase(filepath.Base(dest)))
+	activeDownloads.Lock()
+	activeDownloads.m[base]++
+	activeDownloads.Unlock()
+	return sync.OnceFunc(func() {
+		activeDownloads.Lock()
+		defer activeDownloads.Unlock()
+		if activeDownloads.m[base]--; activeDownloads.m[base] <= 0 {
+			delete(activeDownloads.m, base)
+		}
+	})
+}
+
+// isActiveDownload 表示对应的下载是否正在进行。
+func isActiveDownload(base string) bool {
+	activeDownloads.Lock()
+	defer activeDownloads.Unlock()
+	return activeDownloads.m[filepath.Clean(base)] > 0
+}
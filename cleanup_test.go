@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyLeftover(t *testing.T) {
+	tests := []struct {
+		name string
+		kind LeftoverKind
+		base string
+		ok   bool
+	}{
+		{name: "a.mp4.part", kind: LeftoverPartial, base: "a", ok: true},
+		{name: "a.f137.mp4.part", kind: LeftoverPartial, base: "a", ok: true},
+		{name: "a.f137.mp4.part-Frag3.part", kind: LeftoverPartial, base: "a", ok: true},
+		{name: "a.f137.mp4.part-Frag12", kind: LeftoverPartial, base: "a", ok: true},
+		{name: "a.mp4.ytdl", kind: LeftoverPartial, base: "a", ok: true},
+		{name: "a.temp.mp4", kind: LeftoverTemp, base: "a", ok: true},
+		{name: "a.f137.mp4", kind: LeftoverFragment, base: "a", ok: true},
+		{name: "a.fhls-1080p.mp4", kind: LeftoverFragment, base: "a", ok: true},
+		{name: "a.f251-drc.webm", kind: LeftoverFragment, base: "a", ok: true},
+		{name: "a.en.vtt", kind: LeftoverSidecar, base: "a", ok: true},
+		{name: "a.zh-Hans.srt", kind: LeftoverSidecar, base: "a", ok: true},
+		{name: "a.webp", kind: LeftoverSidecar, base: "a", ok: true},
+		{name: "v1.2 final.mp4", ok: false},
+		{name: "a.mp4", ok: false},
+		{name: "notes.txt", ok: false},
+	}
+	for _, tt := range tests {
+		kind, base, ok := classifyLeftover(tt.name)
+		if ok != tt.ok || (ok && (kind != tt.kind || base != tt.base)) {
+			t.Errorf("classifyLeftover(%q) = %v, %q, %v, want %v, %q, %v", tt.name, kind, base, ok, tt.kind, tt.base, tt.ok)
+		}
+	}
+}
+
+func TestScanLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	files := map[string]time.Time{
+		"a.f137.mp4.part":     old,
+		"a.en.vtt":            old,
+		"a.webp":              old,
+		"b.mp4":               {},
+		"b.en.vtt":            {},
+		"b.jpg":               {},
+		"sub/c.f140.m4a":      {},
+		"sub/c.f137.mp4.part": {},
+	}
+	for name, mtime := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if !mtime.IsZero() {
+			if err := os.Chtimes(p, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	sources := map[string]Job{filepath.Join(dir, "a"): {URL: "https://example.com/a"}}
+	active := func(base string) bool { return base == filepath.Join(dir, "sub", "c") }
+	leftovers, err := ScanLeftovers(dir, sources, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, l := range leftovers {
+		rel, _ := filepath.Rel(dir, l.Path)
+		names = append(names, filepath.ToSlash(rel))
+		switch l.Base {
+		case filepath.Join(dir, "a"):
+			if l.Source.URL != "https://example.com/a" || l.Active || !l.Stale(time.Now()) {
+				t.Errorf("%s: URL = %q, Active = %v, Stale = %v", rel, l.Source.URL, l.Active, l.Stale(time.Now()))
+			}
+		case filepath.Join(dir, "sub", "c"):
+			if !l.Active || l.Stale(time.Now()) {
+				t.Errorf("%s: Active = %v, Stale = %v", rel, l.Active, l.Stale(time.Now()))
+			}
+		}
+	}
+	// 已完成的 b.mp4 的字幕和缩略图不算残留，旧文件排在前面
+	got := strings.Join(names, ",")
+	if !strings.HasPrefix(got, "a.") || len(names) != 5 || strings.Contains(got, "b.") {
+		t.Errorf("leftovers = %v", names)
+	}
+
+	if _, err := ScanLeftovers(filepath.Join(dir, "none"), nil, nil); err == nil {
+		t.Error("目录不存在时应返回错误")
+	}
+}
+
+func TestRemoveLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string) Leftover {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("12345"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, base, _ := classifyLeftover(name)
+		return Leftover{Path: p, Size: 5, Base: filepath.Join(dir, base)}
+	}
+	a := write("a.mp4.part")
+	b := write("b.mp4.part")
+	c := write("c.mp4.part")
+	c.Active = true
+
+	done := markActiveDownload(filepath.Join(dir, "b.mp4"))
+	defer done()
+
+	removed, freed, err := RemoveLeftovers([]Leftover{a, b, c})
+	if err != nil || removed != 1 || freed != 5 {
+		t.Errorf("RemoveLeftovers = %d, %d, %v, want 1, 5, nil", removed, freed, err)
+	}
+	if _, err := os.Stat(a.Path); !os.IsNotExist(err) {
+		t.Error("a.mp4.part 应被删除")
+	}
+	for _, l := range []Leftover{b, c} {
+		if _, err := os.Stat(l.Path); err != nil {
+			t.Errorf("正在下载的文件不应被删除: %v", err)
+		}
+	}
+}
+
+func TestJobLogSources(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	logs := map[string]string{
+		"job-20240101-100000-1.log": "10:00:00.000 [app] 下载地址: https://example.com/old\n" +
+			"10:00:01.000 [stdout] [download] Destination: " + filepath.Join(out, "a.f137.mp4") + "\n",
+		"job-20240102-100000-2.log": "10:00:00.000 [app] 下载地址: https://example.com/a\n" +
+			"10:00:00.000 [app] 下载方案: audio\n" +
+			"10:00:00.000 [app] 附加参数: --yes-playlist --download-archive '/data/my subs/1.txt' --password *** --video-password=***\n" +
+			"10:00:01.000 [stdout] [download] Destination: " + filepath.Join(out, "a.f137.mp4") + "\n" +
+			"10:00:02.000 [stdout] [download] Destination: " + filepath.Join(out, "a.f251.webm") + "\n",
+		"other.log": "10:00:00.000 [app] 下载地址: https://example.com/x\n",
+	}
+	for name, content := range logs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources := jobLogSources(dir)
+	want := Job{URL: "https://example.com/a", Profile: "audio", ExtraArgs: []string{"--yes-playlist", "--download-archive", "/data/my subs/1.txt"}}
+	if len(sources) != 1 || !reflect.DeepEqual(sources[filepath.Join(out, "a")], want) {
+		t.Errorf("sources = %+v, want %+v", sources, want)
+	}
+	if got := jobLogSources(filepath.Join(dir, "none")); len(got) != 0 {
+		t.Errorf("日志目录不存在时应返回空映射: %v", got)
+	}
+}
+
+func TestMarkActiveDownload(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "a")
+	done1 := markActiveDownload(base + ".f137.mp4")
+	done2 := markActiveDownload(base + ".f251.webm")
+	if !isActiveDownload(base) {
+		t.Fatal("应为正在下载")
+	}
+	done1()
+	done1()
+	if !isActiveDownload(base) {
+		t.Error("另一个文件仍在下载")
+	}
+	done2()
+	if isActiveDownload(base) {
+		t.Error("全部结束后不应为正在下载")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showCleanupDialog 显示下载目录中的残留文件，可删除，或将对应的下载重新加入队列继续下载。
+func showCleanupDialog(w fyne.Window, outputDir, logsDir string, onResume func(jobs []Job)) {
+	var all, shown []Leftover
+	selected := map[string]bool{}
+
+	staleOnly := widget.NewCheck("只显示超过 1 天未修改的文件", nil)
+	summary := widget.NewLabel("")
+
+	var list *widget.List
+	apply := func() {
+		now := time.Now()
+		shown = shown[:0]
+		var size int64
+		for _, l := range all {
+			if staleOnly.Checked && !l.Stale(now) {
+				continue
+			}
+			shown = append(shown, l)
+			size += l.Size
+		}
+		var selectedSize int64
+		count := 0
+		for _, l := range shown {
+			if selected[l.Path] {
+				count++
+				selectedSize += l.Size
+			}
+		}
+		summary.SetText(fmt.Sprintf("共 %d 个文件（%s），已选择 %d 个（%s）",
+			len(shown), formatBytes(size), count, formatBytes(selectedSize)))
+		list.Refresh()
+	}
+	reload := func() {
+		leftovers, err := ScanLeftovers(outputDir, jobLogSources(logsDir), isActiveDownload)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		all = leftovers
+		apply()
+	}
+	staleOnly.OnChanged = func(bool) { apply() }
+
+	list = widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, widget.NewCheck("", nil), nil, label)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(shown) {
+				return
+			}
+			l := shown[id]
+			row := obj.(*fyne.Container)
+			label := row.Objects[0].(*widget.Label)
+			check := row.Objects[1].(*widget.Check)
+
+			rel, err := filepath.Rel(outputDir, l.Path)
+			if err != nil {
+				rel = l.Path
+			}
+			text := fmt.Sprintf("[%s] %s  %s  %s", l.Kind, rel, formatBytes(l.Size), l.ModTime.Format("2006-01-02 15:04"))
+			if l.Active {
+				text += "  （正在下载）"
+				label.Importance = widget.LowImportance
+			} else {
+				label.Importance = widget.MediumImportance
+			}
+			label.SetText(text)
+
+			check.OnChanged = nil
+			check.SetChecked(selected[l.Path])
+			if l.Active {
+				check.Disable()
+			} else {
+				check.Enable()
+			}
+			check.OnChanged = func(b bool) {
+				if b {
+					selected[l.Path] = true
+				} else {
+					delete(selected, l.Path)
+				}
+				apply()
+			}
+		},
+	)
+
+	selectedItems := func() []Leftover {
+		var items []Leftover
+		for _, l := range shown {
+			if selected[l.Path] && !l.Active {
+				items = append(items, l)
+			}
+		}
+		return items
+	}
+
+	selectAllBtn := widget.NewButton("全选", func() {
+		for _, l := range shown {
+			if !l.Active {
+				selected[l.Path] = true
+			}
+		}
+		apply()
+	})
+	deleteBtn := widget.NewButton("删除所选", func() {
+		items := selectedItems()
+		if len(items) == 0 {
+			return
+		}
+		dialog.ShowConfirm("删除残留文件", fmt.Sprintf("确定删除 %d 个文件？删除后无法继续这些下载。", len(items)), func(ok bool) {
+			if !ok {
+				return
+			}
+			removed, freed, err := RemoveLeftovers(items)
+			for _, l := range items {
+				delete(selected, l.Path)
+			}
+			reload()
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			dialog.ShowInformation("清理完成", fmt.Sprintf("已删除 %d 个文件，释放 %s", removed, formatBytes(freed)), w)
+		}, w)
+	})
+	resumeBtn := widget.NewButton("继续下载所选", func() {
+		var jobs []Job
+		seen := map[string]bool{}
+		missing := 0
+		for _, l := range selectedItems() {
+			if l.Source.URL == "" {
+				missing++
+				continue
+			}
+			key := strings.Join(append([]string{l.Source.URL, l.Source.Profile}, l.Source.ExtraArgs...), "\x00")
+			if !seen[key] {
+				seen[key] = true
+				jobs = append(jobs, l.Source)
+			}
+		}
+		if len(jobs) > 0 {
+			onResume(jobs)
+		}
+		msg := fmt.Sprintf("已将 %d 个下载重新加入队列", len(jobs))
+		if missing > 0 {
+			msg += fmt.Sprintf("，%d 个文件在任务日志中找不到来源地址", missing)
+		}
+		dialog.ShowInformation("继续下载", msg, w)
+	})
+	reloadBtn := widget.NewButton("刷新", reload)
+
+	top := container.NewVBox(
+		widget.NewLabel("目录: "+filepath.Clean(outputDir)),
+		container.NewBorder(nil, nil, nil, reloadBtn, staleOnly),
+	)
+	bottom := container.NewVBox(
+		summary,
+		container.NewHBox(selectAllBtn, layout.NewSpacer(), resumeBtn, deleteBtn),
+	)
+	content := container.NewBorder(top, bottom, nil, nil, list)
+
+	dlg := dialog.NewCustom("清理未完成的下载", "关闭", content, w)
+	dlg.Resize(fyne.NewSize(760, 540))
+	reload()
+	dlg.Show()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showClipDialog 选择只下载视频中的部分片段，可手动输入时间段或勾选章节，结果通过 onChosen 返回。
+// 未选择任何片段时返回空的 ClipChoice，表示下载完整视频。
+func showClipDialog(w fyne.Window, info *VideoInfo, initial ClipChoice, onChosen func(ClipChoice)) {
+	chapterLabels := make([]string, len(info.Chapters))
+	for i, ch := range info.Chapters {
+		chapterLabels[i] = ch.Label()
+	}
+
+	// 与章节完全一致的时间段显示为勾选的章节，其余放入输入框
+	var checked, manual []string
+	for _, r := range initial.Ranges {
+		isChapter := false
+		for i, ch := range info.Chapters {
+			if ch.Range() == r {
+				checked = append(checked, chapterLabels[i])
+				isChapter = true
+				break
+			}
+		}
+		if !isChapter {
+			manual = append(manual, r.String())
+		}
+	}
+
+	rangesEntry := widget.NewMultiLineEntry()
+	rangesEntry.SetPlaceHolder("每行一段，例如：\n1:30-5:00\n1:02:00-结尾")
+	rangesEntry.SetText(strings.Join(manual, "\n"))
+	rangesEntry.SetMinRowsVisible(4)
+
+	chapterGroup := widget.NewCheckGroup(chapterLabels, nil)
+	chapterGroup.Selected = checked
+
+	keyframesCheck := widget.NewCheck("在切割处强制插入关键帧（切割更精确，但需要重新编码，速度较慢）", nil)
+	keyframesCheck.SetChecked(initial.ForceKeyframes)
+
+	items := []fyne.CanvasObject{widget.NewLabel("时间段（格式 开始-结束，时间可写作 90、1:30 或 1:02:03）：")}
+	if info.Duration > 0 {
+		items = append(items, widget.NewLabel("视频时长: "+formatDuration(info.Duration)))
+	}
+	items = append(items, rangesEntry)
+	if len(info.Chapters) > 0 {
+		chapterScroll := container.NewVScroll(chapterGroup)
+		chapterScroll.SetMinSize(fyne.NewSize(0, 200))
+		items = append(items, widget.NewLabel("章节："), chapterScroll)
+	}
+	items = append(items, keyframesCheck)
+
+	dlg := dialog.NewCustomWithoutButtons("下载片段", container.NewVBox(items...), w)
+	dlg.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("取消", func() { dlg.Hide() }),
+		widget.NewButton("完整下载", func() {
+			dlg.Hide()
+			onChosen(ClipChoice{})
+		}),
+		&widget.Button{Text: "确定", Importance: widget.HighImportance, OnTapped: func() {
+			ranges, err := ParseClipRanges(rangesEntry.Text, info.Duration)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			for i, label := range chapterLabels {
+				for _, c := range chapterGroup.Selected {
+					if c == label {
+						ranges = append(ranges, info.Chapters[i].Range())
+					}
+				}
+			}
+			dlg.Hide()
+			onChosen(ClipChoice{Ranges: ranges, ForceKeyframes: keyframesCheck.Checked})
+		}},
+	})
+	dlg.Resize(fyne.NewSize(560, 0))
+	dlg.Show()
+}
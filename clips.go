@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// clipOutputTemplate 为下载片段时使用的文件名模板，在默认文件名后附加片段的起止时间，
+// 避免同一视频的多个片段互相覆盖。
+const clipOutputTemplate = "%(title)s [%(id)s] %(section_start>%H-%M-%S)s-%(section_end>%H-%M-%S)s.%(ext)s"
+
+// VideoChapter 为视频元数据中的章节。
+type VideoChapter struct {
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Title     string  `json:"title"`
+}
+
+// Range 返回章节对应的时间段。
+func (c VideoChapter) Range() ClipRange {
+	return ClipRange{Start: c.StartTime, End: c.EndTime}
+}
+
+// Label 返回用于界面显示的章节说明。
+func (c VideoChapter) Label() string {
+	return c.Range().String() + "  " + c.Title
+}
+
+// CanClip 表示可以为该视频选择下载片段，播放列表和时长未知且没有章节的视频不支持。
+func (v *VideoInfo) CanClip() bool {
+	return !v.IsPlaylist() && (v.Duration > 0 || len(v.Chapters) > 0)
+}
+
+// ClipRange 为要下载的时间段，单位为秒。End 为 0 时表示到视频结尾。
+type ClipRange struct {
+	Start float64
+	End   float64
+}
+
+// String 返回 "1:00-2:30" 形式的说明。
+func (r ClipRange) String() string {
+	end := "结尾"
+	if r.End > 0 {
+		end = formatDuration(r.End)
+	}
+	return formatDuration(r.Start) + "-" + end
+}
+
+// sectionArg 返回 --download-sections 使用的时间段参数。
+func (r ClipRange) sectionArg() string {
+	end := "inf"
+	if r.End > 0 {
+		end = strconv.FormatFloat(r.End, 'f', -1, 64)
+	}
+	return "*" + strconv.FormatFloat(r.Start, 'f', -1, 64) + "-" + end
+}
+
+// parseTimestamp 解析 "90"、"1:30"、"1:02:03" 形式的时间，秒可以带小数。
+func parseTimestamp(s string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("时间格式错误: %s", s)
+	}
+	var total float64
+	for i, p := range parts {
+		var v float64
+		var err error
+		if i == len(parts)-1 {
+			v, err = strconv.ParseFloat(p, 64)
+		} else {
+			var n int
+			n, err = strconv.Atoi(p)
+			v = float64(n)
+		}
+		if err != nil || v < 0 || (i > 0 && v >= 60) {
+			return 0, fmt.Errorf("时间格式错误: %s", s)
+		}
+		total = total*60 + v
+	}
+	return total, nil
+}
+
+// ParseClipRanges 解析用户输入的时间段，每段形如 "1:00-2:30"，多段用换行或逗号分隔。
+// 结束时间留空或写作 "结尾"、"inf" 时表示到视频结尾。duration 大于 0 时检查时间段不超出视频时长。
+func ParseClipRanges(text string, duration float64) ([]ClipRange, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == '\n' || r == ',' || r == '，' || r == ';' || r == '；'
+	})
+	var ranges []ClipRange
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		r, err := parseClipRange(f, duration)
+		if err != nil {
+			return nil, fmt.Errorf("片段 %q 无效: %w", f, err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseClipRange(s string, duration float64) (ClipRange, error) {
+	startText, endText, ok := strings.Cut(strings.NewReplacer("~", "-", "–", "-", "—", "-").Replace(s), "-")
+	if !ok {
+		return ClipRange{}, errors.New("应为 开始-结束 格式")
+	}
+	start, err := parseTimestamp(startText)
+	if err != nil {
+		return ClipRange{}, err
+	}
+	var r ClipRange
+	r.Start = start
+	switch endText = strings.TrimSpace(endText); endText {
+	case "", "结尾", "inf":
+	default:
+		if r.End, err = parseTimestamp(endText); err != nil {
+			return ClipRange{}, err
+		}
+		if r.End <= r.Start {
+			return ClipRange{}, errors.New("结束时间必须晚于开始时间")
+		}
+	}
+	if duration > 0 {
+		if r.Start >= duration {
+			return ClipRange{}, fmt.Errorf("开始时间超出视频时长 %s", formatDuration(duration))
+		}
+		if r.End > duration {
+			return ClipRange{}, fmt.Errorf("结束时间超出视频时长 %s", formatDuration(duration))
+		}
+	}
+	return r, nil
+}
+
+// ClipChoice 为针对单个视频选择的下载片段。
+type ClipChoice struct {
+	Ranges []ClipRange
+	// ForceKeyframes 在切割处强制插入关键帧，切割更精确，但需要重新编码
+	ForceKeyframes bool
+}
+
+// Args 返回对应的 yt-dlp 参数，未选择片段时返回 nil。
+func (c ClipChoice) Args() []string {
+	if len(c.Ranges) == 0 {
+		return nil
+	}
+	var args []string
+	for _, r := range c.Ranges {
+		args = append(args, "--download-sections", r.sectionArg())
+	}
+	if c.ForceKeyframes {
+		args = append(args, "--force-keyframes-at-cuts")
+	}
+	return append(args, "-o", clipOutputTemplate)
+}
+
+// String 返回所有片段的说明。
+func (c ClipChoice) String() string {
+	parts := make([]string, len(c.Ranges))
+	for i, r := range c.Ranges {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ", ")
+}
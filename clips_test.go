@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"90", 90, false},
+		{"1:30", 90, false},
+		{" 1:02:03 ", 3723, false},
+		{"0:05.5", 5.5, false},
+		{"1:60", 0, true},
+		{"1:2:3:4", 0, true},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"-5", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTimestamp(%q) = %v, %v, want %v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseClipRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		duration float64
+		want     []ClipRange
+		wantErr  string
+	}{
+		{"单段", "1:30-5:00", 0, []ClipRange{{90, 300}}, ""},
+		{"多段混合分隔", "0-10\n1:00~2:00，3:00–结尾", 0, []ClipRange{{0, 10}, {60, 120}, {180, 0}}, ""},
+		{"结束留空", "1:02:00-", 3800, []ClipRange{{3720, 0}}, ""},
+		{"空输入", " \n ", 0, nil, ""},
+		{"缺少结束分隔", "1:30", 0, nil, "开始-结束"},
+		{"结束早于开始", "5:00-1:00", 0, nil, "晚于开始"},
+		{"开始超出时长", "10:00-结尾", 300, nil, "开始时间超出"},
+		{"结束超出时长", "1:00-6:00", 300, nil, "结束时间超出"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseClipRanges(tt.text, tt.duration)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseClipRanges(%q) err = %v, want 包含 %q", tt.text, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseClipRanges(%q) 出错: %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseClipRanges(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClipChoiceArgs(t *testing.T) {
+	if args := (ClipChoice{ForceKeyframes: true}).Args(); args != nil {
+		t.Errorf("未选择片段时 Args() = %q, want nil", args)
+	}
+
+	c := ClipChoice{Ranges: []ClipRange{{90, 300.5}, {3720, 0}}, ForceKeyframes: true}
+	want := []string{
+		"--download-sections", "*90-300.5",
+		"--download-sections", "*3720-inf",
+		"--force-keyframes-at-cuts",
+		"-o", clipOutputTemplate,
+	}
+	if got := c.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+	if got, want := c.String(), "1:30-5:01, 1:02:00-结尾"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestVideoInfoCanClip(t *testing.T) {
+	tests := []struct {
+		name string
+		info VideoInfo
+		want bool
+	}{
+		{"有时长", VideoInfo{Duration: 60}, true},
+		{"只有章节", VideoInfo{Chapters: []VideoChapter{{0, 30, "开头"}}}, true},
+		{"时长未知", VideoInfo{}, false},
+		{"播放列表", VideoInfo{Type: "playlist", Duration: 60}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.info.CanClip(); got != tt.want {
+			t.Errorf("%s: CanClip() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
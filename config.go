@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 
 	_ "embed"
@@ -23,9 +25,17 @@ const (
 
 // AppConfig holds program-level settings.
 type AppConfig struct {
-	OutputDir     string
-	DownloadProxy string
-	YtDlpURL      string
+	OutputDir    string
+	Proxy        ProxyConfig
+	YtDlpURL     string
+	PostDownload PostDownloadConfig
+	// ProfilePostDownload 为按下载方案单独设置的下载后操作，键为方案名称，未设置的方案使用 PostDownload
+	ProfilePostDownload map[string]PostDownloadConfig
+	Schedule            ScheduleConfig
+	DiskGuard           DiskGuardConfig
+	Organize            OrganizeConfig
+	Mirrors             MirrorConfig
+	Update              UpdateConfig
 }
 
 // YTDLPConfig holds yt-dlp command-line options.
@@ -35,13 +45,37 @@ type YTDLPConfig struct {
 	WriteSubs         bool
 	SubLangs          string
 	EmbedSubs         bool
+	WriteAutoSubs     bool
+	ConvertSubs       string // "", "srt", "ass", "vtt"
+	SubFavorites      []SubtitleFavorite
 	EmbedChapters     bool
 	SplitChapters     bool
 	EmbedMetadata     bool
 	MergeOutputFormat string // "mp4/mkv", "mp4", "mkv", "auto"
-	ExtraArgs         string
+	// 下载记录（--download-archive），相对路径基于程序目录
+	UseDownloadArchive bool
+	DownloadArchive    string
+	// 直播从头开始录制（--live-from-start）
+	LiveFromStart bool
+	// 等待预定的直播或首映开始（--wait-for-video），值为重试间隔秒数，可写作 "最小-最大"
+	UseWaitForVideo bool
+	WaitForVideo    string
+	// SponsorBlock 标记为章节和移除的片段类别，为空时不处理；API 为空时使用默认地址
+	SponsorBlockMark   []string
+	SponsorBlockRemove []string
+	SponsorBlockAPI    string
+	ExtraArgs          string
 }
 
+// DefaultDownloadArchive 为默认的下载记录文件名。
+const DefaultDownloadArchive = "archive.txt"
+
+// DefaultWaitForVideo 为等待直播开始时默认的重试间隔（秒）。
+const DefaultWaitForVideo = "60"
+
+// waitForVideoPattern 匹配 --wait-for-video 的取值，如 "60" 或 "30-600"。
+var waitForVideoPattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+
 // DefaultYTDLPConfig returns a YTDLPConfig with sensible defaults.
 func DefaultYTDLPConfig() *YTDLPConfig {
 	return &YTDLPConfig{
@@ -51,6 +85,8 @@ func DefaultYTDLPConfig() *YTDLPConfig {
 		EmbedChapters:     true,
 		EmbedMetadata:     true,
 		MergeOutputFormat: "mp4/mkv",
+		DownloadArchive:   DefaultDownloadArchive,
+		WaitForVideo:      DefaultWaitForVideo,
 	}
 }
 
@@ -65,6 +101,57 @@ var defaultIniConf string
 //go:embed res/icon.png
 var iconData []byte
 
+// postDownloadSectionPrefix 为按下载方案单独设置的下载后操作所在的节，如 [post_download.audio]。
+const postDownloadSectionPrefix = "post_download."
+
+// PostDownloadFor 返回使用指定下载方案的任务执行的下载后操作。
+func (c *AppConfig) PostDownloadFor(profile string) PostDownloadConfig {
+	if p, err := FindDownloadProfile(profile); err == nil {
+		if post, ok := c.ProfilePostDownload[p.Name]; ok {
+			return post
+		}
+	}
+	return c.PostDownload
+}
+
+func loadPostDownloadConfig(sec *ini.Section) PostDownloadConfig {
+	return PostDownloadConfig{
+		OpenFolder:   sec.Key("open_folder").MustBool(false),
+		TransferMode: sec.Key("transfer_mode").In(TransferNone, []string{TransferNone, TransferMove, TransferCopy}),
+		TransferDir:  sec.Key("transfer_dir").MustString(""),
+		Command:      sec.Key("command").MustString(""),
+		WebhookURL:   sec.Key("webhook_url").MustString(""),
+	}
+}
+
+// savePostDownloadConfig 写入下载后操作。用 NewKey 在 sec 中创建键：
+// 对 [post_download.audio] 这样的子节，Key 会返回父节中的同名键。
+func savePostDownloadConfig(sec *ini.Section, c PostDownloadConfig) {
+	for _, kv := range [][2]string{
+		{"open_folder", strconv.FormatBool(c.OpenFolder)},
+		{"transfer_mode", c.TransferMode},
+		{"transfer_dir", c.TransferDir},
+		{"command", c.Command},
+		{"webhook_url", c.WebhookURL},
+	} {
+		_, _ = sec.NewKey(kv[0], kv[1])
+	}
+}
+
+// loadProfilePostDownload 读取各下载方案单独设置的下载后操作，没有时返回 nil。
+func loadProfilePostDownload(cfg *ini.File) map[string]PostDownloadConfig {
+	var m map[string]PostDownloadConfig
+	for _, p := range downloadProfiles {
+		if sec, err := cfg.GetSection(postDownloadSectionPrefix + p.Name); err == nil {
+			if m == nil {
+				m = map[string]PostDownloadConfig{}
+			}
+			m[p.Name] = loadPostDownloadConfig(sec)
+		}
+	}
+	return m
+}
+
 // LoadAppConfig loads program settings from the ini file.
 func LoadAppConfig(path string) (*AppConfig, error) {
 	if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
@@ -75,10 +162,53 @@ func LoadAppConfig(path string) (*AppConfig, error) {
 		return nil, fmt.Errorf("无法加载配置文件: %w", err)
 	}
 	sec := cfg.Section("app")
+	post := cfg.Section("post_download")
+	sched := cfg.Section("schedule")
+	disk := cfg.Section("disk")
+	organize := cfg.Section("organize")
+	mirror := cfg.Section("mirror")
+	update := cfg.Section("update")
+	// 时间段格式错误时忽略，避免因手动编辑的配置导致程序无法启动
+	windows, _ := ParseScheduleWindows(sched.Key("windows").MustString(""))
 	return &AppConfig{
-		OutputDir:     sec.Key("output_dir").MustString(""),
-		DownloadProxy: sec.Key("download_proxy").MustString(""),
-		YtDlpURL:      sec.Key("yt_dlp_url").MustString(""),
+		OutputDir:           sec.Key("output_dir").MustString(""),
+		Proxy:               loadProxyConfig(cfg, proxyCredentialsPath(path)),
+		YtDlpURL:            sec.Key("yt_dlp_url").MustString(""),
+		PostDownload:        loadPostDownloadConfig(post),
+		ProfilePostDownload: loadProfilePostDownload(cfg),
+		Schedule: ScheduleConfig{
+			Enabled: sched.Key("enabled").MustBool(false),
+			Windows: windows,
+		},
+		DiskGuard: DiskGuardConfig{
+			Enabled:   disk.Key("enabled").MustBool(true),
+			MinFreeMB: disk.Key("min_free_mb").MustInt(DefaultMinFreeMB),
+		},
+		Organize: OrganizeConfig{
+			Enabled: organize.Key("enabled").MustBool(false),
+			Rules:   parseOrganizeRules(organize.Key("folders").MustString("")),
+		},
+		Mirrors: MirrorConfig{
+			API:       parseMirrorTemplates(mirror.Key("api").MustString("")),
+			Download:  parseMirrorTemplates(mirror.Key("download").MustString("")),
+			Preferred: mirror.Key("preferred").MustBool(false),
+		},
+		Update: UpdateConfig{
+			AllowUnsigned:      update.Key("allow_unsigned").MustBool(false),
+			CheckIntervalHours: update.Key("check_interval_hours").MustInt(DefaultUpdateCheckHours),
+			AutoUpdateYtDlp:    update.Key("auto_update_yt_dlp").MustBool(false),
+			Prerelease:         update.Key("prerelease").MustBool(false),
+			RemindDays:         update.Key("remind_days").MustInt(DefaultRemindDays),
+			LastCheck:          parseIniTime(update.Key("last_check").String()),
+			App: UpdatePrompt{
+				SkipVersion: update.Key("app_skip_version").String(),
+				RemindAfter: parseIniTime(update.Key("app_remind_after").String()),
+			},
+			YtDlp: UpdatePrompt{
+				SkipVersion: update.Key("yt_dlp_skip_version").String(),
+				RemindAfter: parseIniTime(update.Key("yt_dlp_remind_after").String()),
+			},
+		},
 	}, nil
 }
 
@@ -87,8 +217,40 @@ func SaveAppConfig(path string, cfg *AppConfig) error {
 	iniCfg := ini.Empty()
 	app := iniCfg.Section("app")
 	app.Key("output_dir").SetValue(cfg.OutputDir)
-	app.Key("download_proxy").SetValue(cfg.DownloadProxy)
 	app.Key("yt_dlp_url").SetValue(cfg.YtDlpURL)
+	savePostDownloadConfig(iniCfg.Section("post_download"), cfg.PostDownload)
+	for _, p := range downloadProfiles {
+		if c, ok := cfg.ProfilePostDownload[p.Name]; ok {
+			savePostDownloadConfig(iniCfg.Section(postDownloadSectionPrefix+p.Name), c)
+		}
+	}
+	sched := iniCfg.Section("schedule")
+	sched.Key("enabled").SetValue(strconv.FormatBool(cfg.Schedule.Enabled))
+	sched.Key("windows").SetValue(FormatScheduleWindows(cfg.Schedule.Windows))
+	disk := iniCfg.Section("disk")
+	disk.Key("enabled").SetValue(strconv.FormatBool(cfg.DiskGuard.Enabled))
+	disk.Key("min_free_mb").SetValue(strconv.Itoa(cfg.DiskGuard.MinFreeMB))
+	organize := iniCfg.Section("organize")
+	organize.Key("enabled").SetValue(strconv.FormatBool(cfg.Organize.Enabled))
+	organize.Key("folders").SetValue(strings.Join(cfg.Organize.Rules, ","))
+	mirror := iniCfg.Section("mirror")
+	mirror.Key("api").SetValue(strings.Join(cfg.Mirrors.API, " "))
+	mirror.Key("download").SetValue(strings.Join(cfg.Mirrors.Download, " "))
+	mirror.Key("preferred").SetValue(strconv.FormatBool(cfg.Mirrors.Preferred))
+	update := iniCfg.Section("update")
+	update.Key("allow_unsigned").SetValue(strconv.FormatBool(cfg.Update.AllowUnsigned))
+	update.Key("check_interval_hours").SetValue(strconv.Itoa(cfg.Update.CheckIntervalHours))
+	update.Key("auto_update_yt_dlp").SetValue(strconv.FormatBool(cfg.Update.AutoUpdateYtDlp))
+	update.Key("prerelease").SetValue(strconv.FormatBool(cfg.Update.Prerelease))
+	update.Key("remind_days").SetValue(strconv.Itoa(cfg.Update.RemindDays))
+	update.Key("last_check").SetValue(formatIniTime(cfg.Update.LastCheck))
+	update.Key("app_skip_version").SetValue(cfg.Update.App.SkipVersion)
+	update.Key("app_remind_after").SetValue(formatIniTime(cfg.Update.App.RemindAfter))
+	update.Key("yt_dlp_skip_version").SetValue(cfg.Update.YtDlp.SkipVersion)
+	update.Key("yt_dlp_remind_after").SetValue(formatIniTime(cfg.Update.YtDlp.RemindAfter))
+	if err := saveProxyConfig(iniCfg, proxyCredentialsPath(path), cfg.Proxy); err != nil {
+		return err
+	}
 	if err := iniCfg.SaveTo(path); err != nil {
 		return fmt.Errorf("无法保存配置文件: %w", err)
 	}
@@ -118,6 +280,11 @@ func LoadYTDLPConf(path string) (*YTDLPConfig, error) {
 			continue
 		}
 
+		if f, ok := parseSubFavoriteLine(line); ok {
+			cfg.SubFavorites = append(cfg.SubFavorites, f)
+			continue
+		}
+
 		// 处理注释形式的禁用选项（如 # --embed-chapters）
 		if strings.HasPrefix(line, "#") {
 			commentLine := strings.TrimSpace(strings.TrimPrefix(line, "#"))
@@ -128,6 +295,10 @@ func LoadYTDLPConf(path string) (*YTDLPConfig, error) {
 					cfg.WriteSubs = false
 				case "--embed-subs":
 					cfg.EmbedSubs = false
+				case "--write-auto-subs":
+					cfg.WriteAutoSubs = false
+				case "--convert-subs":
+					cfg.ConvertSubs = ""
 				case "--embed-chapters":
 					cfg.EmbedChapters = false
 				case "--split-chapters":
@@ -136,6 +307,24 @@ func LoadYTDLPConf(path string) (*YTDLPConfig, error) {
 					cfg.EmbedMetadata = false
 				case "--merge-output-format":
 					// 注释形式的格式选项不处理，保持默认
+				case "--download-archive":
+					cfg.UseDownloadArchive = false
+					if p := optionValue(commentLine); p != "" {
+						cfg.DownloadArchive = p
+					}
+				case "--live-from-start":
+					cfg.LiveFromStart = false
+				case "--wait-for-video":
+					cfg.UseWaitForVideo = false
+					if v := optionValue(commentLine); waitForVideoPattern.MatchString(v) {
+						cfg.WaitForVideo = v
+					}
+				case "--sponsorblock-mark":
+					cfg.SponsorBlockMark = nil
+				case "--sponsorblock-remove":
+					cfg.SponsorBlockRemove = nil
+				case "--sponsorblock-api":
+					cfg.SponsorBlockAPI = ""
 				default:
 					extraLines = append(extraLines, scanner.Text())
 				}
@@ -171,6 +360,14 @@ func LoadYTDLPConf(path string) (*YTDLPConfig, error) {
 			cfg.EmbedSubs = true
 		case "--no-embed-subs":
 			cfg.EmbedSubs = false
+		case "--write-auto-subs":
+			cfg.WriteAutoSubs = true
+		case "--no-write-auto-subs":
+			cfg.WriteAutoSubs = false
+		case "--convert-subs", "--convert-subtitles":
+			if len(parts) > 1 {
+				cfg.ConvertSubs = strings.Trim(parts[1], "\"'")
+			}
 		case "--embed-chapters":
 			cfg.EmbedChapters = true
 		case "--no-embed-chapters":
@@ -187,6 +384,33 @@ func LoadYTDLPConf(path string) (*YTDLPConfig, error) {
 			if len(parts) > 1 {
 				cfg.MergeOutputFormat = strings.Trim(parts[1], "\"'")
 			}
+		case "--download-archive":
+			if p := optionValue(line); p != "" {
+				cfg.UseDownloadArchive = true
+				cfg.DownloadArchive = p
+			}
+		case "--no-download-archive":
+			cfg.UseDownloadArchive = false
+		case "--live-from-start":
+			cfg.LiveFromStart = true
+		case "--no-live-from-start":
+			cfg.LiveFromStart = false
+		case "--wait-for-video":
+			if v := optionValue(line); v != "" {
+				cfg.UseWaitForVideo = true
+				cfg.WaitForVideo = v
+			}
+		case "--no-wait-for-video":
+			cfg.UseWaitForVideo = false
+		case "--sponsorblock-mark":
+			cfg.SponsorBlockMark = expandSponsorBlockCategories(optionValue(line), false)
+		case "--sponsorblock-remove":
+			cfg.SponsorBlockRemove = expandSponsorBlockCategories(optionValue(line), true)
+		case "--sponsorblock-api":
+			cfg.SponsorBlockAPI = optionValue(line)
+		case "--no-sponsorblock":
+			cfg.SponsorBlockMark = nil
+			cfg.SponsorBlockRemove = nil
 		default:
 			extraLines = append(extraLines, scanner.Text())
 		}
@@ -196,6 +420,16 @@ func LoadYTDLPConf(path string) (*YTDLPConfig, error) {
 	return cfg, nil
 }
 
+// optionValue 返回配置行中选项名之后的值，去掉两侧引号，值中可包含空格。
+func optionValue(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return ""
+	}
+	value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))
+	return strings.Trim(value, "\"'")
+}
+
 // SaveYTDLPConf saves yt-dlp configuration to file.
 func SaveYTDLPConf(path string, cfg *YTDLPConfig) error {
 	var lines []string
@@ -223,6 +457,24 @@ func SaveYTDLPConf(path string, cfg *YTDLPConfig) error {
 	} else {
 		lines = append(lines, "# --embed-subs")
 	}
+	lines = append(lines, "# 下载自动生成的字幕")
+	if cfg.WriteAutoSubs {
+		lines = append(lines, "--write-auto-subs")
+	} else {
+		lines = append(lines, "# --write-auto-subs")
+	}
+	lines = append(lines, "# 字幕格式转换")
+	if cfg.ConvertSubs != "" {
+		lines = append(lines, "--convert-subs "+cfg.ConvertSubs)
+	} else {
+		lines = append(lines, "# --convert-subs srt")
+	}
+	if len(cfg.SubFavorites) > 0 {
+		lines = append(lines, "# 常用字幕语言（供本程序使用，yt-dlp 会忽略）")
+		for _, f := range cfg.SubFavorites {
+			lines = append(lines, f.confLine())
+		}
+	}
 
 	lines = append(lines, "")
 	lines = append(lines, "# 同时下载章节")
@@ -259,6 +511,56 @@ func SaveYTDLPConf(path string, cfg *YTDLPConfig) error {
 		lines = append(lines, "# --merge-output-format \"mp4/mkv\"")
 	}
 
+	lines = append(lines, "")
+	lines = append(lines, "# 下载记录，跳过已下载的视频")
+	archive := cfg.DownloadArchive
+	if archive == "" {
+		archive = DefaultDownloadArchive
+	}
+	if cfg.UseDownloadArchive {
+		lines = append(lines, "--download-archive \""+archive+"\"")
+	} else {
+		lines = append(lines, "# --download-archive \""+archive+"\"")
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, "# 直播从头开始录制")
+	if cfg.LiveFromStart {
+		lines = append(lines, "--live-from-start")
+	} else {
+		lines = append(lines, "# --live-from-start")
+	}
+	lines = append(lines, "# 等待预定的直播开始，值为重试间隔（秒）")
+	wait := cfg.WaitForVideo
+	if wait == "" {
+		wait = DefaultWaitForVideo
+	}
+	if cfg.UseWaitForVideo {
+		lines = append(lines, "--wait-for-video "+wait)
+	} else {
+		lines = append(lines, "# --wait-for-video "+wait)
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, "# SponsorBlock 标记为章节的片段类别")
+	if len(cfg.SponsorBlockMark) > 0 {
+		lines = append(lines, "--sponsorblock-mark \""+strings.Join(cfg.SponsorBlockMark, ",")+"\"")
+	} else {
+		lines = append(lines, "# --sponsorblock-mark \"all\"")
+	}
+	lines = append(lines, "# SponsorBlock 移除的片段类别")
+	if len(cfg.SponsorBlockRemove) > 0 {
+		lines = append(lines, "--sponsorblock-remove \""+strings.Join(cfg.SponsorBlockRemove, ",")+"\"")
+	} else {
+		lines = append(lines, "# --sponsorblock-remove \"sponsor\"")
+	}
+	lines = append(lines, "# SponsorBlock API 地址")
+	if cfg.SponsorBlockAPI != "" {
+		lines = append(lines, "--sponsorblock-api \""+cfg.SponsorBlockAPI+"\"")
+	} else {
+		lines = append(lines, "# --sponsorblock-api \""+DefaultSponsorBlockAPI+"\"")
+	}
+
 	if cfg.ExtraArgs != "" {
 		lines = append(lines, "")
 		for _, line := range strings.Split(cfg.ExtraArgs, "\n") {
@@ -270,30 +572,35 @@ func SaveYTDLPConf(path string, cfg *YTDLPConfig) error {
 	return writeUTF8BOMFile(path, content, 0644)
 }
 
-// EnsureDefaults ensures yt-dlp.conf and the ini file exist. It will:
+// EnsureDefaults ensures yt-dlp.conf and the ini file exist in paths.ConfigDir. It will:
 //   - create yt-dlp.conf from embedded defaultYTDLPConf if the file is missing;
 //   - create the ini file from embedded defaultIniConf if missing, or write a minimal
-//     ini containing output_dir when no embedded ini is available.
+//     ini containing output_dir when no embedded ini is available;
+//   - use paths.DownloadDir as the output directory of a newly created ini.
 //
 // It returns the loaded AppConfig, YTDLPConfig and any error encountered.
-func EnsureDefaults(iniPath, defaultOutputDir string) (*AppConfig, *YTDLPConfig, error) {
+func EnsureDefaults(paths AppPaths) (*AppConfig, *YTDLPConfig, error) {
+	iniPath, ytdlpConfPath := paths.IniPath(), paths.YTDLPConfPath()
 	appCfg := &AppConfig{
-		OutputDir: defaultOutputDir,
+		OutputDir: paths.DownloadDir,
+		DiskGuard: DiskGuardConfig{Enabled: true, MinFreeMB: DefaultMinFreeMB},
+		Proxy:     ProxyConfig{App: ProxySystem, YtDlp: ProxySystem},
+		Update:    UpdateConfig{CheckIntervalHours: DefaultUpdateCheckHours, RemindDays: DefaultRemindDays},
 	}
 
 	ytdlpCfg := DefaultYTDLPConfig()
 
 	// If yt-dlp.conf doesn't exist, write embedded default
-	if _, st := os.Stat(YTDLPConfName); os.IsNotExist(st) {
+	if _, st := os.Stat(ytdlpConfPath); os.IsNotExist(st) {
 		if defaultYTDLPConf != "" {
-			if werr := writeUTF8BOMFile(YTDLPConfName, defaultYTDLPConf, 0644); werr != nil {
+			if werr := writeUTF8BOMFile(ytdlpConfPath, defaultYTDLPConf, 0644); werr != nil {
 				return nil, nil, fmt.Errorf("无法写入默认yt-dlp配置: %w", werr)
 			}
 		}
 	}
 
 	// Load yt-dlp.conf
-	if loadedCfg, err := LoadYTDLPConf(YTDLPConfName); err == nil {
+	if loadedCfg, err := LoadYTDLPConf(ytdlpConfPath); err == nil {
 		ytdlpCfg = loadedCfg
 	}
 
@@ -303,8 +610,15 @@ func EnsureDefaults(iniPath, defaultOutputDir string) (*AppConfig, *YTDLPConfig,
 			if loadedCfg.OutputDir != "" {
 				appCfg.OutputDir = loadedCfg.OutputDir
 			}
-			appCfg.DownloadProxy = loadedCfg.DownloadProxy
+			appCfg.Proxy = loadedCfg.Proxy
 			appCfg.YtDlpURL = loadedCfg.YtDlpURL
+			appCfg.PostDownload = loadedCfg.PostDownload
+			appCfg.ProfilePostDownload = loadedCfg.ProfilePostDownload
+			appCfg.Schedule = loadedCfg.Schedule
+			appCfg.DiskGuard = loadedCfg.DiskGuard
+			appCfg.Organize = loadedCfg.Organize
+			appCfg.Mirrors = loadedCfg.Mirrors
+			appCfg.Update = loadedCfg.Update
 		} else {
 			return nil, nil, fmt.Errorf("无法加载配置: %w", rerr)
 		}
@@ -316,11 +630,22 @@ func EnsureDefaults(iniPath, defaultOutputDir string) (*AppConfig, *YTDLPConfig,
 			}
 			// after writing embedded ini, try to read settings
 			if loadedCfg, rerr := LoadAppConfig(iniPath); rerr == nil {
-				if loadedCfg.OutputDir != "" {
-					appCfg.OutputDir = loadedCfg.OutputDir
+				// 模板中的下载目录为便携模式的默认值，改为按当前模式确定的目录
+				if loadedCfg.OutputDir != paths.DownloadDir {
+					loadedCfg.OutputDir = paths.DownloadDir
+					if werr := SaveAppConfig(iniPath, loadedCfg); werr != nil {
+						return nil, nil, fmt.Errorf("无法保存配置: %w", werr)
+					}
 				}
-				appCfg.DownloadProxy = loadedCfg.DownloadProxy
+				appCfg.Proxy = loadedCfg.Proxy
 				appCfg.YtDlpURL = loadedCfg.YtDlpURL
+				appCfg.PostDownload = loadedCfg.PostDownload
+				appCfg.ProfilePostDownload = loadedCfg.ProfilePostDownload
+				appCfg.Schedule = loadedCfg.Schedule
+				appCfg.DiskGuard = loadedCfg.DiskGuard
+				appCfg.Organize = loadedCfg.Organize
+				appCfg.Mirrors = loadedCfg.Mirrors
+				appCfg.Update = loadedCfg.Update
 			} else {
 				return nil, nil, fmt.Errorf("无法读取新创建的配置: %w", rerr)
 			}
@@ -331,8 +656,18 @@ func EnsureDefaults(iniPath, defaultOutputDir string) (*AppConfig, *YTDLPConfig,
 		}
 	}
 
+	// 旧版本写在 yt-dlp.conf 中的代理转为代理设置，由程序通过命令行传给 yt-dlp
+	if migrateYtDlpProxy(appCfg, ytdlpCfg) {
+		if werr := SaveAppConfig(iniPath, appCfg); werr != nil {
+			return nil, nil, fmt.Errorf("无法保存配置: %w", werr)
+		}
+		if werr := SaveYTDLPConf(ytdlpConfPath, ytdlpCfg); werr != nil {
+			return nil, nil, fmt.Errorf("无法保存yt-dlp配置: %w", werr)
+		}
+	}
+
 	// ensure output directory exists
-	if mkerr := os.MkdirAll(appCfg.OutputDir, 0755); mkerr != nil {
+	if mkerr := os.MkdirAll(paths.Resolve(appCfg.OutputDir), 0755); mkerr != nil {
 		return nil, nil, fmt.Errorf("无法创建输出目录 %s: %w", appCfg.OutputDir, mkerr)
 	}
 
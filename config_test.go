@@ -1,9 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestDefaultYTDLPConfig(t *testing.T) {
@@ -34,9 +37,47 @@ func TestSaveAndLoadAppConfig(t *testing.T) {
 	path := filepath.Join(dir, "test.ini")
 
 	cfg := &AppConfig{
-		OutputDir:     "./下载",
-		DownloadProxy: "http://127.0.0.1:7890",
-		YtDlpURL:      "https://example.com/yt-dlp.exe",
+		OutputDir: "./下载",
+		Proxy: ProxyConfig{
+			Profiles: []ProxyProfile{
+				{Name: "本地", Scheme: "http", Address: "127.0.0.1:7890"},
+				{Name: "公司", Scheme: "socks5h", Address: "10.0.0.1:1080", Username: "bob", Password: "p;w#d"},
+			},
+			App:   "本地",
+			YtDlp: "公司",
+		},
+		YtDlpURL: "https://example.com/yt-dlp.exe",
+		PostDownload: PostDownloadConfig{
+			OpenFolder:   true,
+			TransferMode: TransferCopy,
+			TransferDir:  "/mnt/nas/videos",
+			Command:      `notify-send "{title}"`,
+			WebhookURL:   "http://127.0.0.1:8080/hook",
+		},
+		ProfilePostDownload: map[string]PostDownloadConfig{
+			"audio": {TransferMode: TransferMove, TransferDir: "/mnt/nas/music"},
+		},
+		Schedule: ScheduleConfig{
+			Enabled: true,
+			Windows: []ScheduleWindow{{Start: 22 * 60, End: 7 * 60, RateLimit: "2M"}, {Start: 12 * 60, End: 13 * 60}},
+		},
+		DiskGuard: DiskGuardConfig{Enabled: false, MinFreeMB: 2048},
+		Organize:  OrganizeConfig{Enabled: true, Rules: []string{OrganizeByUploader, OrganizeByYearMonth}},
+		Mirrors: MirrorConfig{
+			API:       []string{"https://gh-api.example.com{path}"},
+			Download:  []string{"https://ghfast.top/{url}", "https://mirror.example.com{path}"},
+			Preferred: true,
+		},
+		Update: UpdateConfig{
+			AllowUnsigned:      true,
+			CheckIntervalHours: 7 * 24,
+			AutoUpdateYtDlp:    true,
+			Prerelease:         true,
+			RemindDays:         5,
+			LastCheck:          time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+			App:                UpdatePrompt{SkipVersion: "v1.2.0"},
+			YtDlp:              UpdatePrompt{RemindAfter: time.Date(2024, 5, 13, 12, 0, 0, 0, time.UTC)},
+		},
 	}
 
 	if err := SaveAppConfig(path, cfg); err != nil {
@@ -51,12 +92,41 @@ func TestSaveAndLoadAppConfig(t *testing.T) {
 	if loaded.OutputDir != cfg.OutputDir {
 		t.Errorf("OutputDir 不匹配: %s != %s", loaded.OutputDir, cfg.OutputDir)
 	}
-	if loaded.DownloadProxy != cfg.DownloadProxy {
-		t.Errorf("DownloadProxy 不匹配: %s != %s", loaded.DownloadProxy, cfg.DownloadProxy)
+	if fmt.Sprintf("%+v", loaded.Proxy) != fmt.Sprintf("%+v", cfg.Proxy) {
+		t.Errorf("Proxy 不匹配: %+v != %+v", loaded.Proxy, cfg.Proxy)
 	}
 	if loaded.YtDlpURL != cfg.YtDlpURL {
 		t.Errorf("YtDlpURL 不匹配: %s != %s", loaded.YtDlpURL, cfg.YtDlpURL)
 	}
+	if loaded.PostDownload != cfg.PostDownload {
+		t.Errorf("PostDownload 不匹配: %+v != %+v", loaded.PostDownload, cfg.PostDownload)
+	}
+	if fmt.Sprintf("%+v", loaded.ProfilePostDownload) != fmt.Sprintf("%+v", cfg.ProfilePostDownload) {
+		t.Errorf("ProfilePostDownload 不匹配: %+v != %+v", loaded.ProfilePostDownload, cfg.ProfilePostDownload)
+	}
+	if got := loaded.PostDownloadFor("Audio"); got != cfg.ProfilePostDownload["audio"] {
+		t.Errorf("PostDownloadFor(audio) = %+v", got)
+	}
+	for _, name := range []string{"", "720p", "unknown"} {
+		if got := loaded.PostDownloadFor(name); got != cfg.PostDownload {
+			t.Errorf("PostDownloadFor(%q) 应使用默认设置: %+v", name, got)
+		}
+	}
+	if fmt.Sprintf("%+v", loaded.Schedule) != fmt.Sprintf("%+v", cfg.Schedule) {
+		t.Errorf("Schedule 不匹配: %+v != %+v", loaded.Schedule, cfg.Schedule)
+	}
+	if loaded.DiskGuard != cfg.DiskGuard {
+		t.Errorf("DiskGuard 不匹配: %+v != %+v", loaded.DiskGuard, cfg.DiskGuard)
+	}
+	if fmt.Sprintf("%+v", loaded.Organize) != fmt.Sprintf("%+v", cfg.Organize) {
+		t.Errorf("Organize 不匹配: %+v != %+v", loaded.Organize, cfg.Organize)
+	}
+	if fmt.Sprintf("%+v", loaded.Mirrors) != fmt.Sprintf("%+v", cfg.Mirrors) {
+		t.Errorf("Mirrors 不匹配: %+v != %+v", loaded.Mirrors, cfg.Mirrors)
+	}
+	if fmt.Sprintf("%+v", loaded.Update) != fmt.Sprintf("%+v", cfg.Update) {
+		t.Errorf("Update 不匹配: %+v != %+v", loaded.Update, cfg.Update)
+	}
 }
 
 func TestLoadAppConfig_NotExists(t *testing.T) {
@@ -74,16 +144,27 @@ func TestSaveAndLoadYTDLPConf(t *testing.T) {
 	path := filepath.Join(dir, "yt-dlp.conf")
 
 	cfg := &YTDLPConfig{
-		UseProxy:          true,
-		ProxyAddress:      "127.0.0.1:7890",
-		WriteSubs:         true,
-		SubLangs:          "all",
-		EmbedSubs:         true,
-		EmbedChapters:     false,
-		SplitChapters:     true,
-		EmbedMetadata:     true,
-		MergeOutputFormat: "mkv",
-		ExtraArgs:         "--concurrent-fragments 4",
+		UseProxy:           true,
+		ProxyAddress:       "127.0.0.1:7890",
+		WriteSubs:          true,
+		SubLangs:           "all",
+		WriteAutoSubs:      true,
+		ConvertSubs:        "srt",
+		SubFavorites:       []SubtitleFavorite{{Name: "中英", Langs: "zh-Hans,en"}, {Name: "日语", Langs: "ja"}},
+		EmbedSubs:          true,
+		EmbedChapters:      false,
+		SplitChapters:      true,
+		EmbedMetadata:      true,
+		MergeOutputFormat:  "mkv",
+		UseDownloadArchive: true,
+		DownloadArchive:    "记录/archive list.txt",
+		LiveFromStart:      true,
+		UseWaitForVideo:    true,
+		WaitForVideo:       "30-600",
+		SponsorBlockMark:   []string{"intro", "chapter"},
+		SponsorBlockRemove: []string{"sponsor", "selfpromo"},
+		SponsorBlockAPI:    "http://127.0.0.1:8080",
+		ExtraArgs:          "--concurrent-fragments 4",
 	}
 
 	if err := SaveYTDLPConf(path, cfg); err != nil {
@@ -107,6 +188,12 @@ func TestSaveAndLoadYTDLPConf(t *testing.T) {
 	if loaded.SubLangs != cfg.SubLangs {
 		t.Errorf("SubLangs 不匹配: %s != %s", loaded.SubLangs, cfg.SubLangs)
 	}
+	if loaded.WriteAutoSubs != cfg.WriteAutoSubs || loaded.ConvertSubs != cfg.ConvertSubs {
+		t.Errorf("自动字幕/转换不匹配: %v %q != %v %q", loaded.WriteAutoSubs, loaded.ConvertSubs, cfg.WriteAutoSubs, cfg.ConvertSubs)
+	}
+	if !reflect.DeepEqual(loaded.SubFavorites, cfg.SubFavorites) {
+		t.Errorf("SubFavorites 不匹配: %v != %v", loaded.SubFavorites, cfg.SubFavorites)
+	}
 	if loaded.EmbedSubs != cfg.EmbedSubs {
 		t.Errorf("EmbedSubs 不匹配: %v != %v", loaded.EmbedSubs, cfg.EmbedSubs)
 	}
@@ -122,6 +209,83 @@ func TestSaveAndLoadYTDLPConf(t *testing.T) {
 	if loaded.MergeOutputFormat != cfg.MergeOutputFormat {
 		t.Errorf("MergeOutputFormat 不匹配: %s != %s", loaded.MergeOutputFormat, cfg.MergeOutputFormat)
 	}
+	if loaded.UseDownloadArchive != cfg.UseDownloadArchive || loaded.DownloadArchive != cfg.DownloadArchive {
+		t.Errorf("DownloadArchive 不匹配: %v %q != %v %q", loaded.UseDownloadArchive, loaded.DownloadArchive, cfg.UseDownloadArchive, cfg.DownloadArchive)
+	}
+	if loaded.LiveFromStart != cfg.LiveFromStart || loaded.UseWaitForVideo != cfg.UseWaitForVideo || loaded.WaitForVideo != cfg.WaitForVideo {
+		t.Errorf("直播选项不匹配: %v %v %q != %v %v %q", loaded.LiveFromStart, loaded.UseWaitForVideo, loaded.WaitForVideo,
+			cfg.LiveFromStart, cfg.UseWaitForVideo, cfg.WaitForVideo)
+	}
+	if !reflect.DeepEqual(loaded.SponsorBlockMark, cfg.SponsorBlockMark) || !reflect.DeepEqual(loaded.SponsorBlockRemove, cfg.SponsorBlockRemove) {
+		t.Errorf("SponsorBlock 类别不匹配: %q %q != %q %q", loaded.SponsorBlockMark, loaded.SponsorBlockRemove, cfg.SponsorBlockMark, cfg.SponsorBlockRemove)
+	}
+	if loaded.SponsorBlockAPI != cfg.SponsorBlockAPI {
+		t.Errorf("SponsorBlockAPI 不匹配: %s != %s", loaded.SponsorBlockAPI, cfg.SponsorBlockAPI)
+	}
+
+	// 禁用时保留文件路径和重试间隔
+	cfg.UseDownloadArchive = false
+	cfg.UseWaitForVideo = false
+	if err := SaveYTDLPConf(path, cfg); err != nil {
+		t.Fatalf("保存 yt-dlp 配置失败: %v", err)
+	}
+	loaded, err = LoadYTDLPConf(path)
+	if err != nil {
+		t.Fatalf("加载 yt-dlp 配置失败: %v", err)
+	}
+	if loaded.UseDownloadArchive || loaded.DownloadArchive != cfg.DownloadArchive {
+		t.Errorf("禁用后 DownloadArchive = %v %q, want false %q", loaded.UseDownloadArchive, loaded.DownloadArchive, cfg.DownloadArchive)
+	}
+	if loaded.UseWaitForVideo || loaded.WaitForVideo != cfg.WaitForVideo {
+		t.Errorf("禁用后 WaitForVideo = %v %q, want false %q", loaded.UseWaitForVideo, loaded.WaitForVideo, cfg.WaitForVideo)
+	}
+}
+
+func TestLoadYTDLPConf_SponsorBlock(t *testing.T) {
+	tests := []struct {
+		name       string
+		conf       string
+		wantMark   []string
+		wantRemove []string
+		wantAPI    string
+	}{
+		{
+			name:       "展开 default",
+			conf:       "--sponsorblock-mark \"chapter,intro\"\n--sponsorblock-remove default,-filler,-preview\n",
+			wantMark:   []string{"chapter", "intro"},
+			wantRemove: []string{"sponsor", "intro", "outro", "selfpromo", "interaction", "music_offtopic"},
+		},
+		{
+			name:    "注释形式为禁用",
+			conf:    "# --sponsorblock-mark \"all\"\n# --sponsorblock-remove \"sponsor\"\n--sponsorblock-api https://sb.example.com\n",
+			wantAPI: "https://sb.example.com",
+		},
+		{
+			name: "no-sponsorblock 清除前面的设置",
+			conf: "--sponsorblock-mark all\n--sponsorblock-remove sponsor\n--no-sponsorblock\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "yt-dlp.conf")
+			if err := os.WriteFile(path, []byte(tt.conf), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadYTDLPConf(path)
+			if err != nil {
+				t.Fatalf("加载 yt-dlp 配置失败: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.SponsorBlockMark, tt.wantMark) || !reflect.DeepEqual(cfg.SponsorBlockRemove, tt.wantRemove) {
+				t.Errorf("SponsorBlock = %q %q, want %q %q", cfg.SponsorBlockMark, cfg.SponsorBlockRemove, tt.wantMark, tt.wantRemove)
+			}
+			if cfg.SponsorBlockAPI != tt.wantAPI {
+				t.Errorf("SponsorBlockAPI = %q, want %q", cfg.SponsorBlockAPI, tt.wantAPI)
+			}
+			if cfg.ExtraArgs != "" {
+				t.Errorf("ExtraArgs = %q, want 空", cfg.ExtraArgs)
+			}
+		})
+	}
 }
 
 func TestLoadYTDLPConf_NotExists(t *testing.T) {
@@ -139,14 +303,10 @@ func TestLoadYTDLPConf_NotExists(t *testing.T) {
 
 func TestEnsureDefaults(t *testing.T) {
 	dir := t.TempDir()
-	iniPath := filepath.Join(dir, "test.ini")
+	paths := AppPaths{ExeDir: dir, ConfigDir: filepath.Join(dir, "config"), DataDir: filepath.Join(dir, "data"), DownloadDir: filepath.Join(dir, "Downloads")}
+	os.MkdirAll(paths.ConfigDir, 0755)
 
-	// 切换到临时目录以测试文件创建
-	origDir, _ := os.Getwd()
-	defer os.Chdir(origDir)
-	os.Chdir(dir)
-
-	appCfg, ytdlpCfg, err := EnsureDefaults(iniPath, filepath.Join(dir, "下载"))
+	appCfg, ytdlpCfg, err := EnsureDefaults(paths)
 	if err != nil {
 		t.Fatalf("EnsureDefaults 失败: %v", err)
 	}
@@ -162,4 +322,14 @@ func TestEnsureDefaults(t *testing.T) {
 	if _, err := os.Stat(appCfg.OutputDir); os.IsNotExist(err) {
 		t.Error("输出目录应被创建")
 	}
+	for _, p := range []string{paths.IniPath(), paths.YTDLPConfPath()} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("应在配置目录中创建 %s: %v", p, err)
+		}
+	}
+	// 新创建的配置使用按模式确定的下载目录，而不是模板中便携模式的相对目录
+	loaded, err := LoadAppConfig(paths.IniPath())
+	if err != nil || loaded.OutputDir != paths.DownloadDir || appCfg.OutputDir != paths.DownloadDir {
+		t.Errorf("下载目录 = %q, %q, want %q", loaded.OutputDir, appCfg.OutputDir, paths.DownloadDir)
+	}
 }
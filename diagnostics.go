@@ -0,0 +1,387 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	nativeDialog "github.com/sqweek/dialog"
+
+	"yinr.cc/yt-dlp-simpgo/utils"
+)
+
+// diagnosticsProbeURL 用于测试网络/代理连通性，返回 204 且不含正文。
+const diagnosticsProbeURL = "https://www.youtube.com/generate_204"
+
+// diagnosticsLogLines 为诊断报告中附带的最近日志行数。
+const diagnosticsLogLines = 200
+
+// DiagnosticsOptions 描述收集诊断信息所需的上下文。
+type DiagnosticsOptions struct {
+	Paths     AppPaths
+	AppCfg    *AppConfig
+	YtdlpCfg  *YTDLPConfig
+	LogLines  []string
+	ProbeURL  string // 为空时使用 diagnosticsProbeURL
+	ProbeWait time.Duration
+}
+
+// DiagnosticsFile 为诊断报告中附带的配置文件。
+type DiagnosticsFile struct {
+	Path    string
+	Content string
+	Err     error
+}
+
+// DiagnosticsProxyCheck 为一次代理连通性测试的结果。
+type DiagnosticsProxyCheck struct {
+	Name    string
+	Proxy   string
+	Latency time.Duration
+	Err     error
+}
+
+// DiagnosticsReport 汇总排查问题所需的环境信息。
+type DiagnosticsReport struct {
+	GeneratedAt  time.Time
+	AppVersion   string
+	Platform     string
+	Paths        AppPaths
+	YtDlpPath    string
+	YtDlpVersion string
+	YtDlpErr     error
+	FFmpegPath   string
+	ConfigFiles  []DiagnosticsFile
+	ProxyChecks  []DiagnosticsProxyCheck
+	OutputDir    string
+	OutputErr    error
+	FreeSpace    uint64
+	FreeSpaceErr error
+	LogLines     []string
+}
+
+// CollectDiagnostics 收集诊断信息。网络测试会阻塞，需在后台 goroutine 中调用。
+func CollectDiagnostics(opts DiagnosticsOptions) *DiagnosticsReport {
+	r := &DiagnosticsReport{
+		GeneratedAt: time.Now(),
+		AppVersion:  Version,
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		Paths:       opts.Paths,
+		LogLines:    opts.LogLines,
+	}
+
+	if p, found := findYtDlp(opts.Paths); found {
+		r.YtDlpPath = p
+		r.YtDlpVersion, r.YtDlpErr = GetYtDlpVersion(p)
+	} else {
+		r.YtDlpErr = fmt.Errorf("未找到 yt-dlp")
+	}
+	r.FFmpegPath, _ = findFFmpeg(opts.Paths)
+
+	for _, p := range []string{opts.Paths.IniPath(), opts.Paths.YTDLPConfPath()} {
+		data, err := os.ReadFile(p)
+		r.ConfigFiles = append(r.ConfigFiles, DiagnosticsFile{
+			Path:    p,
+			Content: strings.TrimPrefix(string(data), "\ufeff"),
+			Err:     err,
+		})
+	}
+
+	probeURL := opts.ProbeURL
+	if probeURL == "" {
+		probeURL = diagnosticsProbeURL
+	}
+	wait := opts.ProbeWait
+	if wait <= 0 {
+		wait = 10 * time.Second
+	}
+	r.ProxyChecks = append(r.ProxyChecks, probeProxy("直连", nil, probeURL, wait))
+	if opts.AppCfg != nil {
+		if opts.AppCfg.Proxy.App == ProxySystem || opts.AppCfg.Proxy.YtDlp == ProxySystem {
+			r.ProxyChecks = append(r.ProxyChecks, probeSystemProxy(probeURL, wait))
+		}
+		if p := opts.AppCfg.Proxy.Profile(opts.AppCfg.Proxy.App); p != nil {
+			r.ProxyChecks = append(r.ProxyChecks, probeProxy("程序代理 "+p.Name, p, probeURL, wait))
+		}
+		if p := opts.AppCfg.Proxy.Profile(opts.AppCfg.Proxy.YtDlp); p != nil && p.Name != opts.AppCfg.Proxy.App {
+			r.ProxyChecks = append(r.ProxyChecks, probeProxy("yt-dlp 代理 "+p.Name, p, probeURL, wait))
+		}
+	}
+	// yt-dlp.conf 中手动设置的代理
+	if opts.YtdlpCfg != nil && opts.YtdlpCfg.UseProxy && opts.YtdlpCfg.ProxyAddress != "" {
+		if p, err := parseProxyURL(opts.YtdlpCfg.ProxyAddress); err == nil {
+			r.ProxyChecks = append(r.ProxyChecks, probeProxy("yt-dlp 配置文件中的代理", &p, probeURL, wait))
+		} else {
+			r.ProxyChecks = append(r.ProxyChecks, DiagnosticsProxyCheck{Name: "yt-dlp 配置文件中的代理", Proxy: opts.YtdlpCfg.ProxyAddress, Err: err})
+		}
+	}
+
+	if opts.AppCfg != nil {
+		r.OutputDir = opts.Paths.Resolve(opts.AppCfg.OutputDir)
+		r.OutputErr = checkDirWritable(r.OutputDir)
+		r.FreeSpace, r.FreeSpaceErr = utils.DiskFree(r.OutputDir)
+	}
+
+	return r
+}
+
+// findFFmpeg 在数据目录、程序目录和 PATH 中查找 ffmpeg。
+func findFFmpeg(paths AppPaths) (string, bool) {
+	return findExecutable("ffmpeg", paths.DataDir, paths.ExeDir)
+}
+
+// probeProxy 经由代理访问 probeURL，测试连通性和延迟。proxy 为 nil 时测试直连。
+func probeProxy(name string, proxy *ProxyProfile, probeURL string, timeout time.Duration) DiagnosticsProxyCheck {
+	check := DiagnosticsProxyCheck{Name: name}
+	var route ProxyRoute
+	if proxy != nil {
+		check.Proxy = proxy.Display()
+		route.URL = proxy.URL()
+	}
+	return probeRoute(check, route, probeURL, timeout)
+}
+
+// probeSystemProxy 按系统代理设置访问 probeURL，报告中列出系统代理和例外列表。
+func probeSystemProxy(probeURL string, timeout time.Duration) DiagnosticsProxyCheck {
+	check := DiagnosticsProxyCheck{Name: "系统代理"}
+	cfg := systemProxyConfig()
+	switch {
+	case cfg.HTTPSProxy == "" && cfg.HTTPProxy == "":
+		check.Proxy = "未设置"
+	case cfg.HTTPSProxy == cfg.HTTPProxy || cfg.HTTPProxy == "":
+		check.Proxy = cfg.HTTPSProxy
+	default:
+		check.Proxy = "http: " + cfg.HTTPProxy + ", https: " + cfg.HTTPSProxy
+	}
+	if cfg.NoProxy != "" {
+		check.Proxy += ", 例外: " + cfg.NoProxy
+	}
+	return probeRoute(check, ProxyRoute{System: true}, probeURL, timeout)
+}
+
+func probeRoute(check DiagnosticsProxyCheck, route ProxyRoute, probeURL string, timeout time.Duration) DiagnosticsProxyCheck {
+	client := appHTTPClient(route, timeout)
+	start := time.Now()
+	resp, err := client.Get(probeURL)
+	check.Latency = time.Since(start)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		check.Err = fmt.Errorf("服务器返回 %s", resp.Status)
+	}
+	return check
+}
+
+func checkDirWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".yt-dlp-simpgo-write-test-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+var (
+	urlCredentialPattern = regexp.MustCompile(`(?i)(^|\s|"|[a-z][a-z0-9+.-]*://)[^/@\s:"]+(?::[^/@\s"]*)?@`)
+	// -p 区分大小写，避免隐去 -P（输出目录）
+	secretOptions = `(?:-p|(?i:--password|--video-password|--ap-password|--twofactor|--add-headers?))`
+	// secretLinePattern 匹配配置文件中的一行，值可能包含空格，隐去到行尾
+	secretLinePattern = regexp.MustCompile(`^(\s*#?\s*` + secretOptions + `(?:\s+|=)).+$`)
+	// secretArgPattern 匹配命令行中的参数（如任务日志中的命令），只隐去紧随其后的一个值
+	secretArgPattern = regexp.MustCompile(`(^|\s)(` + secretOptions + `(?:\s+|=))("[^"]*"|'[^']*'|\S+)`)
+)
+
+// redactSecrets 隐去文本中的代理账号密码、yt-dlp 登录口令等敏感信息。
+func redactSecrets(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = urlCredentialPattern.ReplaceAllString(line, "${1}***@")
+		line = secretLinePattern.ReplaceAllString(line, "${1}***")
+		line = secretArgPattern.ReplaceAllString(line, "${1}${2}***")
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Text 以纯文本形式输出诊断报告，敏感信息已隐去。
+func (r *DiagnosticsReport) Text() string {
+	var b strings.Builder
+	errText := func(err error) string {
+		if err == nil {
+			return "正常"
+		}
+		return "失败: " + err.Error()
+	}
+
+	fmt.Fprintln(&b, logMarker("yt-dlp-simpgo 诊断信息"))
+	fmt.Fprintf(&b, "生成时间: %s\n", r.GeneratedAt.Format("2006-01-02 15:04:05 -0700"))
+	fmt.Fprintf(&b, "程序版本: %s\n", r.AppVersion)
+	fmt.Fprintf(&b, "运行平台: %s\n", r.Platform)
+	fmt.Fprintf(&b, "程序目录: %s\n", r.Paths.ExeDir)
+	if r.Paths.Portable {
+		fmt.Fprintln(&b, "运行模式: 便携")
+	} else {
+		fmt.Fprintf(&b, "配置目录: %s\n", r.Paths.ConfigDir)
+		fmt.Fprintf(&b, "数据目录: %s\n", r.Paths.DataDir)
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, logMarker("依赖"))
+	if r.YtDlpPath != "" {
+		fmt.Fprintf(&b, "yt-dlp: %s\n", r.YtDlpPath)
+	}
+	if r.YtDlpErr != nil {
+		fmt.Fprintf(&b, "yt-dlp 版本: %s\n", errText(r.YtDlpErr))
+	} else {
+		fmt.Fprintf(&b, "yt-dlp 版本: %s\n", r.YtDlpVersion)
+	}
+	if r.FFmpegPath != "" {
+		fmt.Fprintf(&b, "ffmpeg: %s\n", r.FFmpegPath)
+	} else {
+		fmt.Fprintln(&b, "ffmpeg: 未找到（合并音视频、内嵌字幕等功能不可用）")
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, logMarker("网络"))
+	for _, c := range r.ProxyChecks {
+		target := c.Name
+		if c.Proxy != "" {
+			target += " (" + c.Proxy + ")"
+		}
+		if c.Err != nil {
+			fmt.Fprintf(&b, "%s: %s\n", target, errText(c.Err))
+		} else {
+			fmt.Fprintf(&b, "%s: 正常，耗时 %d ms\n", target, c.Latency.Milliseconds())
+		}
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, logMarker("下载目录"))
+	fmt.Fprintf(&b, "路径: %s\n", r.OutputDir)
+	fmt.Fprintf(&b, "可写: %s\n", errText(r.OutputErr))
+	if r.FreeSpaceErr != nil {
+		fmt.Fprintf(&b, "剩余空间: %s\n", errText(r.FreeSpaceErr))
+	} else {
+		fmt.Fprintf(&b, "剩余空间: %s\n", formatBytes(int64(r.FreeSpace)))
+	}
+
+	for _, f := range r.ConfigFiles {
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, logMarker("配置文件 "+f.Path))
+		if f.Err != nil {
+			fmt.Fprintln(&b, errText(f.Err))
+			continue
+		}
+		fmt.Fprintln(&b, strings.TrimRight(f.Content, "\r\n"))
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, logMarker(fmt.Sprintf("最近日志（%d 行）", len(r.LogLines))))
+	for _, line := range r.LogLines {
+		fmt.Fprintln(&b, line)
+	}
+
+	return redactSecrets(b.String())
+}
+
+// WriteDiagnosticsBundle 将诊断报告写入 path。扩展名为 .zip 时打包为压缩文件，
+// 否则写为纯文本。
+func WriteDiagnosticsBundle(path string, r *DiagnosticsReport) error {
+	text := r.Text()
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			return fmt.Errorf("无法写入诊断报告: %w", err)
+		}
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("无法创建诊断压缩包: %w", err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "diagnostics.txt",
+		Method:   zip.Deflate,
+		Modified: r.GeneratedAt,
+	})
+	if err == nil {
+		_, err = w.Write([]byte(text))
+	}
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("无法写入诊断压缩包: %w", err)
+	}
+	return nil
+}
+
+// showDiagnosticsDialog 在后台收集诊断信息并展示，支持导出。
+func showDiagnosticsDialog(w fyne.Window, opts DiagnosticsOptions) {
+	content := widget.NewMultiLineEntry()
+	content.SetText("正在收集诊断信息...")
+	content.Wrapping = fyne.TextWrapOff
+	content.Disable()
+
+	var report *DiagnosticsReport
+	exportBtn := widget.NewButton("导出...", func() {
+		if report == nil {
+			return
+		}
+		name := "yt-dlp-simpgo-诊断-" + report.GeneratedAt.Format("20060102-150405") + ".zip"
+		p, err := nativeDialog.File().Title("导出诊断信息").Filter("ZIP 压缩包", "zip").Filter("文本文件", "txt").SetStartFile(name).Save()
+		if err != nil || p == "" {
+			return
+		}
+		if err := WriteDiagnosticsBundle(p, report); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "已导出", Content: p})
+	})
+	exportBtn.Disable()
+	copyBtn := widget.NewButton("复制", func() {
+		if report != nil {
+			fyne.CurrentApp().Clipboard().SetContent(report.Text())
+		}
+	})
+	copyBtn.Disable()
+
+	body := container.NewBorder(nil, container.NewHBox(copyBtn, exportBtn), nil, nil, content)
+	dlg := dialog.NewCustom("诊断", "关闭", body, w)
+	dlg.Resize(fyne.NewSize(760, 560))
+	dlg.Show()
+
+	go func() {
+		r := CollectDiagnostics(opts)
+		text := r.Text()
+		fyne.Do(func() {
+			report = r
+			content.SetText(text)
+			exportBtn.Enable()
+			copyBtn.Enable()
+		})
+	}()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultMinFreeMB 为默认的最低剩余空间（MB）。
+const DefaultMinFreeMB = 1024
+
+// DiskGuardConfig 为磁盘空间保护设置：下载目录所在分区的剩余空间低于阈值时暂停下载队列。
+type DiskGuardConfig struct {
+	Enabled   bool
+	MinFreeMB int
+}
+
+// minFree 返回最低剩余空间（字节）。
+func (c DiskGuardConfig) minFree() uint64 {
+	if c.MinFreeMB <= 0 {
+		return 0
+	}
+	return uint64(c.MinFreeMB) << 20
+}
+
+// LowDiskSpaceError 表示剩余空间不足。
+type LowDiskSpaceError struct {
+	Dir  string
+	Free uint64
+	Need uint64 // 阈值加上预计的文件大小
+}
+
+func (e *LowDiskSpaceError) Error() string {
+	return fmt.Sprintf("磁盘空间不足: 剩余 %s，至少需要 %s", formatBytes(int64(e.Free)), formatBytes(int64(e.Need)))
+}
+
+// checkDiskSpace 检查 dir 所在分区的剩余空间是否足够下载 size 字节（未知时为 0）并保留阈值，
+// 不足时返回 *LowDiskSpaceError。无法获取剩余空间时不阻止下载。
+func checkDiskSpace(free func(path string) (uint64, error), dir string, cfg DiskGuardConfig, size int64) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	avail, err := free(existingParent(dir))
+	if err != nil {
+		return nil
+	}
+	need := cfg.minFree()
+	if size > 0 {
+		need += uint64(size)
+	}
+	if avail < need {
+		return &LowDiskSpaceError{Dir: dir, Free: avail, Need: need}
+	}
+	return nil
+}
+
+// existingParent 返回 dir 本身或其最近的已存在的上级目录，下载目录尚未创建时用于查询所在分区。
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
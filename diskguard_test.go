@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	const mb = 1 << 20
+	free := func(avail uint64, err error) func(string) (uint64, error) {
+		return func(string) (uint64, error) { return avail, err }
+	}
+	cfg := DiskGuardConfig{Enabled: true, MinFreeMB: 100}
+
+	tests := []struct {
+		name     string
+		free     func(string) (uint64, error)
+		cfg      DiskGuardConfig
+		size     int64
+		wantNeed uint64 // 0 表示不应报错
+	}{
+		{"空间充足", free(500*mb, nil), cfg, 0, 0},
+		{"低于阈值", free(50*mb, nil), cfg, 0, 100 * mb},
+		{"加上预计大小后不足", free(500*mb, nil), cfg, 450 * mb, 550 * mb},
+		{"未启用", free(0, nil), DiskGuardConfig{MinFreeMB: 100}, 0, 0},
+		{"无法获取剩余空间", free(0, errors.New("unsupported")), cfg, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDiskSpace(tt.free, t.TempDir(), tt.cfg, tt.size)
+			if tt.wantNeed == 0 {
+				if err != nil {
+					t.Errorf("checkDiskSpace() = %v, want nil", err)
+				}
+				return
+			}
+			var low *LowDiskSpaceError
+			if !errors.As(err, &low) {
+				t.Fatalf("checkDiskSpace() = %v, want *LowDiskSpaceError", err)
+			}
+			if low.Need != tt.wantNeed {
+				t.Errorf("Need = %d, want %d", low.Need, tt.wantNeed)
+			}
+		})
+	}
+}
+
+func TestExistingParent(t *testing.T) {
+	dir := t.TempDir()
+	if got := existingParent(dir); got != dir {
+		t.Errorf("existingParent(%q) = %q", dir, got)
+	}
+	missing := filepath.Join(dir, "a", "b")
+	if got := existingParent(missing); got != dir {
+		t.Errorf("existingParent(%q) = %q, want %q", missing, got, dir)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := existingParent(missing); got != filepath.Join(dir, "a") {
+		t.Errorf("创建上级目录后 existingParent(%q) = %q", missing, got)
+	}
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/dialog"
@@ -14,31 +18,53 @@ import (
 	"yinr.cc/yt-dlp-simpgo/utils"
 )
 
-func findYtDlp(exeDir string) (string, bool) {
-	exeName := "yt-dlp"
-	if runtime.GOOS == "windows" {
-		exeName = "yt-dlp.exe"
+// finalizeLivePart 在强制结束录制后去掉未完成文件的 .part 后缀。
+// yt-dlp 录制直播时默认使用 mpegts 容器，强制结束后的文件仍可播放。
+// 没有未完成的文件时返回 nil。
+func finalizeLivePart(destination, url string) (*DownloadedFile, error) {
+	if destination == "" {
+		return nil, nil
 	}
-	p := filepath.Join(exeDir, exeName)
-	if _, err := os.Stat(p); err == nil {
-		return p, true
+	part := destination + ".part"
+	if _, err := os.Stat(part); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if _, err := os.Stat(destination); err == nil {
+		return nil, fmt.Errorf("文件已存在: %s", destination)
 	}
-	if pathP, err := exec.LookPath(exeName); err == nil {
-		return pathP, true
+	if err := os.Rename(part, destination); err != nil {
+		return nil, fmt.Errorf("重命名 %s 失败: %w", part, err)
 	}
-	return "", false
+	title := strings.TrimSuffix(filepath.Base(destination), filepath.Ext(destination))
+	return &DownloadedFile{Title: title, URL: url, FilePath: destination}, nil
+}
+
+// findYtDlp 依次在数据目录（程序下载的 yt-dlp）、程序目录和 PATH 中查找 yt-dlp。
+func findYtDlp(paths AppPaths) (string, bool) {
+	return findExecutable("yt-dlp", paths.DataDir, paths.ExeDir)
+}
+
+// lastJobID 为最近一次分配的下载任务编号，任务编号从 1 开始，0 保留给程序自身日志。
+var lastJobID atomic.Int64
+
+func newJobID() int {
+	return int(lastJobID.Add(1))
 }
 
-func wireUpdateBtn(btn *widget.Button, exePath string, downloadProxy string, reporter ProgressReporter) {
+// wireUpdateBtn 设置更新 yt-dlp 的按钮，dataDir 为程序下载 yt-dlp 的目录，见 UpdateYtDlp。
+func wireUpdateBtn(btn *widget.Button, exePath, dataDir string, src func() UpdateSource, reporter ProgressReporter) {
 	btn.Enable()
 	btn.OnTapped = func() {
 		reporter.appendLog("正在更新 yt-dlp: " + exePath)
 		reporter.setProgress("正在更新 yt-dlp", -1)
 		go func() {
-			out, err := UpdateYtDlp(exePath, downloadProxy)
+			out, err := UpdateYtDlp(exePath, dataDir, src())
 			if err != nil {
-				reporter.appendLog(logMarker("yt-dlp 更新失败"))
-				reporter.appendLog("更新失败: " + err.Error())
+				reporter.errorLog(logMarker("yt-dlp 更新失败"))
+				reporter.errorLog("更新失败: " + err.Error())
 				reporter.appendLog(out)
 				reporter.clear()
 				fyne.Do(func() {
@@ -55,85 +81,232 @@ func wireUpdateBtn(btn *widget.Button, exePath string, downloadProxy string, rep
 	}
 }
 
-func startDownload(exePath string, url string, outputDir string, exeDir string,
-	runningMu *sync.Mutex, running *bool, reporter ProgressReporter) {
+// DownloadEnv 为执行下载任务时使用的设置，每个任务开始时读取，设置修改后对之后的任务生效。
+type DownloadEnv struct {
+	YtDlpPath    string
+	Paths        AppPaths
+	OutputDir    string
+	Proxy        ProxyRoute // yt-dlp 使用的代理
+	AppProxy     ProxyRoute // 程序自身使用的代理，用于调用 Webhook
+	Organize     OrganizeConfig
+	PostDownload PostDownloadConfig // 该任务的下载方案对应的下载后操作
+}
 
-	runningMu.Lock()
-	if *running {
-		runningMu.Unlock()
-		dialog.ShowInformation("提示", "已有下载在进行中", fyne.CurrentApp().Driver().AllWindows()[0])
-		return
-	}
-	*running = true
-	runningMu.Unlock()
+// liveStopTimeout 为请求停止录制后等待 yt-dlp 整理文件并退出的最长时间，超时则结束进程。
+const liveStopTimeout = 30 * time.Second
+
+// runDownloadJob 运行 yt-dlp 下载单个任务，直到结束或 ctx 被取消。
+// 录制直播时可通过 ctl.Stop 停止录制，已录制的内容作为正常完成的文件处理。
+func runDownloadJob(ctx context.Context, job Job, rateLimit string, ctl JobControl, env DownloadEnv, reporter ProgressReporter) error {
+	reporter = reporter.forJob(job.ID)
 	reporter.appendLog(logMarker("开始下载"))
-	reporter.appendLog("下载地址: " + url)
+	reporter.appendLog("下载地址: " + job.URL)
 	reporter.setProgress("准备下载", 0)
 
-	go func() {
-		actualOut := outputDir
-		if !filepath.IsAbs(actualOut) {
-			actualOut = filepath.Join(exeDir, actualOut)
+	profile, err := FindDownloadProfile(job.Profile)
+	if err != nil {
+		reporter.errorLog("下载失败: " + err.Error())
+		reporter.clear()
+		return err
+	}
+	if job.Profile != "" {
+		reporter.appendLog("下载方案: " + profile.Label)
+	}
+
+	actualOut := env.Paths.Resolve(env.OutputDir)
+	outTemplate := filepath.Join(actualOut, "%(title)s.%(ext)s")
+	confPath := env.Paths.YTDLPConfPath()
+	reporter.appendLog("使用输出目录: " + actualOut)
+	reporter.appendLog("使用配置文件: " + confPath)
+	// -P 使任务附加的相对文件名模板（如下载片段）也保存到输出目录
+	args := []string{"--newline", "--no-colors", "--config-location", confPath, "-P", actualOut, "-o", outTemplate}
+	if rateLimit != "" {
+		reporter.appendLog("当前时间段限速: " + rateLimit)
+		args = append(args, "--limit-rate", rateLimit)
+	}
+	args = append(args, afterMovePrintArgs...)
+	args = append(args, liveStatusPrintArgs...)
+	args = append(args, ytDlpProxyArgs(env.Proxy)...)
+	args = append(args, profile.Args...)
+	args = append(args, job.ExtraArgs...)
+	args = append(args, job.URL)
+	cmd := utils.ExecCmd(env.YtDlpPath, args...)
+	cmd.Dir = env.Paths.DataDir
+	applyProxyEnv(cmd, env.Proxy)
+
+	jobLog, err := openJobLog(env.Paths.DataPath(LogsDirName), job.ID, time.Now())
+	if err != nil {
+		reporter.log(LogWarning, "无法记录任务日志: "+err.Error(), false)
+	} else {
+		defer jobLog.Close()
+		if ctl.SetLogFile != nil {
+			ctl.SetLogFile(filepath.Base(jobLog.Path()))
 		}
-		outTemplate := filepath.Join(actualOut, "%(title)s.%(ext)s")
-		confPath := filepath.Join(exeDir, YTDLPConfName)
-		reporter.appendLog("使用输出目录: " + actualOut)
-		reporter.appendLog("使用配置文件: " + confPath)
-		cmd := utils.ExecCmd(exePath, "--newline", "--no-colors", "--config-location", confPath, "-o", outTemplate, url)
-		cmd.Dir = exeDir
-
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "下载失败", Content: err.Error()})
-			reporter.appendLog("获取标准输出管道失败: " + err.Error())
-			reporter.clear()
-			runningMu.Lock()
-			*running = false
-			runningMu.Unlock()
-			return
+		// 清理残留文件时据此还原任务以继续下载，参数中的密码等已隐去
+		jobLog.WriteLine("app", "下载地址: "+job.URL)
+		if job.Profile != "" {
+			jobLog.WriteLine("app", "下载方案: "+job.Profile)
+		}
+		if len(job.ExtraArgs) > 0 {
+			jobLog.WriteLine("app", "附加参数: "+redactSecrets(quoteCommandLine(job.ExtraArgs)))
 		}
+		jobLog.WriteLine("app", "命令: "+redactSecrets(env.YtDlpPath+" "+strings.Join(args, " ")))
+	}
+
+	fail := func(msg string, err error) error {
+		fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "下载失败", Content: err.Error()})
+		reporter.errorLog(msg + err.Error())
+		reporter.clear()
+		return err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fail("获取标准输出管道失败: ", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fail("获取错误输出管道失败: ", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fail("启动失败: ", err)
+	}
 
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "下载失败", Content: err.Error()})
-			reporter.appendLog("获取错误输出管道失败: " + err.Error())
-			reporter.clear()
-			runningMu.Lock()
-			*running = false
-			runningMu.Unlock()
+	// 取消任务时结束 yt-dlp 进程，管道随之关闭。停止录制时先发送中断信号，
+	// yt-dlp 收到后会结束直播下载并正常整理文件；无法发送时结束进程，之后自行整理未完成的文件。
+	done := make(chan struct{})
+	defer close(done)
+	var stopped, killed atomic.Bool
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = cmd.Process.Kill()
+			return
+		case <-ctl.Stop:
+		case <-done:
 			return
 		}
-
-		if err := cmd.Start(); err != nil {
-			fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "下载失败", Content: err.Error()})
-			reporter.appendLog("启动失败: " + err.Error())
-			reporter.clear()
-			runningMu.Lock()
-			*running = false
-			runningMu.Unlock()
+		stopped.Store(true)
+		reporter.appendLog("正在停止录制…")
+		if err := utils.Interrupt(cmd.Process); err != nil {
+			killed.Store(true)
+			_ = cmd.Process.Kill()
 			return
 		}
+		select {
+		case <-ctx.Done():
+			_ = cmd.Process.Kill()
+		case <-time.After(liveStopTimeout):
+			reporter.log(LogWarning, "yt-dlp 未能及时结束，强制停止录制", false)
+			killed.Store(true)
+			_ = cmd.Process.Kill()
+		case <-done:
+		}
+	}()
 
-		var wg sync.WaitGroup
-		wg.Add(2)
-		go func() { defer wg.Done(); readYtDlpPipe(stdout, reporter) }()
-		go func() { defer wg.Done(); readYtDlpPipe(stderr, reporter) }()
-		wg.Wait()
-
-		err = cmd.Wait()
-		if err != nil {
-			fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "下载失败", Content: err.Error()})
-			reporter.appendLog(logMarker("下载失败"))
-			reporter.appendLog("下载失败: " + err.Error())
-			reporter.clear()
-		} else {
-			fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "完成", Content: "下载完成"})
-			reporter.setProgress("下载完成", 1)
-			reporter.appendLog(logMarker("下载完成"))
-			reporter.appendLog("下载完成")
+	var filesMu sync.Mutex
+	var files []*DownloadedFile
+	var destination string // 最近一个正在写入的文件
+	var activeDone []func()
+	defer func() {
+		for _, done := range activeDone {
+			done()
 		}
-		runningMu.Lock()
-		*running = false
-		runningMu.Unlock()
 	}()
+	onFile := func(f *DownloadedFile) {
+		if env.Organize.Enabled && len(env.Organize.Rules) > 0 {
+			p, err := organizeFile(actualOut, env.Organize.Rules, *f)
+			if err != nil {
+				reporter.log(LogWarning, err.Error(), false)
+			}
+			if p != f.FilePath {
+				reporter.appendLog("文件已整理到: " + p)
+				f.FilePath = p
+			}
+		}
+		filesMu.Lock()
+		files = append(files, f)
+		filesMu.Unlock()
+		entry := HistoryEntry{Time: time.Now(), JobID: job.ID, DownloadedFile: *f}
+		if jobLog != nil {
+			entry.LogFile = filepath.Base(jobLog.Path())
+		}
+		if err := AppendHistory(env.Paths.DataPath(HistoryFileName), entry); err != nil {
+			reporter.log(LogWarning, "无法记录下载历史: "+err.Error(), false)
+		}
+	}
+	onEvent := func(ev YtDlpEvent) {
+		if ev.File != nil {
+			onFile(ev.File)
+		}
+		if ev.Destination != "" {
+			// 任务结束前清理工具不会删除正在写入的文件
+			done := markActiveDownload(ev.Destination)
+			filesMu.Lock()
+			destination = ev.Destination
+			activeDone = append(activeDone, done)
+			filesMu.Unlock()
+		}
+		if ev.Live && ctl.SetLive != nil {
+			ctl.SetLive()
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); readYtDlpPipe(stdout, "stdout", jobLog, reporter, onEvent) }()
+	go func() { defer wg.Done(); readYtDlpPipe(stderr, "stderr", jobLog, reporter, onEvent) }()
+	wg.Wait()
+
+	err = cmd.Wait()
+	var lowSpace *LowDiskSpaceError
+	if errors.As(context.Cause(ctx), &lowSpace) {
+		jobLog.WriteLine("app", "退出: "+lowSpace.Error())
+		reporter.log(LogWarning, logMarker("下载已暂停"), false)
+		reporter.log(LogWarning, lowSpace.Error()+"，空间足够后将继续下载", false)
+		reporter.clear()
+		runJobPostDownload(env.PostDownload, env.AppProxy, files, reporter)
+		return ctx.Err()
+	}
+	if ctx.Err() != nil {
+		jobLog.WriteLine("app", "退出: 已取消")
+		reporter.appendLog(logMarker("下载已取消"))
+		reporter.clear()
+		// 取消前已完成的文件仍执行后续操作
+		runJobPostDownload(env.PostDownload, env.AppProxy, files, reporter)
+		return ctx.Err()
+	}
+	if stopped.Load() {
+		// 中断后 yt-dlp 可能以非零状态退出，录制的内容已保存，视为正常完成
+		jobLog.WriteLine("app", "退出: 已停止录制")
+		if killed.Load() {
+			if f, ferr := finalizeLivePart(destination, job.URL); ferr != nil {
+				reporter.log(LogWarning, "无法整理录制的文件: "+ferr.Error(), false)
+			} else if f != nil {
+				reporter.appendLog("已保存: " + f.FilePath)
+				onFile(f)
+			}
+		}
+		fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "完成", Content: "录制已停止"})
+		reporter.setProgress("录制已停止", 1)
+		reporter.appendLog(logMarker("录制已停止"))
+		runJobPostDownload(env.PostDownload, env.AppProxy, files, reporter)
+		return nil
+	}
+	if err != nil {
+		jobLog.WriteLine("app", "退出: "+err.Error())
+		fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "下载失败", Content: err.Error()})
+		reporter.errorLog(logMarker("下载失败"))
+		reporter.errorLog("下载失败: " + err.Error())
+		reporter.clear()
+	} else {
+		jobLog.WriteLine("app", "退出: 成功")
+		fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "完成", Content: "下载完成"})
+		reporter.setProgress("下载完成", 1)
+		reporter.appendLog(logMarker("下载完成"))
+		reporter.appendLog("下载完成")
+	}
+	// 已完成的文件即使整体下载失败也执行后续操作，失败单独报告
+	runJobPostDownload(env.PostDownload, env.AppProxy, files, reporter)
+	return err
 }
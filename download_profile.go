@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DownloadProfile 为内置的下载方案。加入队列时将 Args 附加到任务的 yt-dlp 参数中，
+// 位于 yt-dlp.conf 之后，因此会覆盖其中的对应设置。
+type DownloadProfile struct {
+	Name  string // 在链接和命令行中使用的名称
+	Label string
+	Args  []string
+}
+
+// downloadProfiles 为内置的下载方案，第一个为默认方案。
+var downloadProfiles = []DownloadProfile{
+	{Name: "video", Label: "视频（按设置）"},
+	{Name: "audio", Label: "仅音频（MP3）", Args: []string{"-f", "bestaudio/best", "-x", "--audio-format", "mp3", "--audio-quality", "0"}},
+	{Name: "1080p", Label: "视频（最高 1080p）", Args: []string{"-f", "bv*[height<=1080]+ba/b[height<=1080]"}},
+	{Name: "720p", Label: "视频（最高 720p）", Args: []string{"-f", "bv*[height<=720]+ba/b[height<=720]"}},
+}
+
+// DefaultDownloadProfile 返回默认的下载方案，即按设置下载视频。
+func DefaultDownloadProfile() DownloadProfile {
+	return downloadProfiles[0]
+}
+
+// profileNames 返回各下载方案的名称。
+func profileNames() []string {
+	var names []string
+	for _, p := range downloadProfiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// profileLabels 返回各下载方案的显示名称，顺序与 downloadProfiles 相同。
+func profileLabels() []string {
+	var labels []string
+	for _, p := range downloadProfiles {
+		labels = append(labels, p.Label)
+	}
+	return labels
+}
+
+// downloadProfileNames 返回以逗号分隔的下载方案名称。
+func downloadProfileNames() string {
+	return strings.Join(profileNames(), ", ")
+}
+
+// FindDownloadProfile 按名称查找下载方案，不区分大小写，名称为空时返回默认方案。
+func FindDownloadProfile(name string) (DownloadProfile, error) {
+	if name == "" {
+		return DefaultDownloadProfile(), nil
+	}
+	for _, p := range downloadProfiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	return DownloadProfile{}, fmt.Errorf("未知的下载方案 %q，可用: %s", name, downloadProfileNames())
+}
@@ -1,6 +1,10 @@
 package main
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestFriendlyYtDlpLine(t *testing.T) {
 	tests := []struct {
@@ -25,12 +29,61 @@ func TestFriendlyYtDlpLine(t *testing.T) {
 			want: "保存文件: video.mp4",
 			ok:   true,
 		},
+		{
+			name:        "recorded in archive",
+			line:        "[download] abc123: has already been recorded in the archive",
+			want:        "已在下载记录中，跳过下载",
+			hasProgress: true,
+			ok:          true,
+		},
 		{
 			name: "warning",
 			line: "WARNING: something happened",
 			want: "WARNING: something happened",
 			ok:   true,
 		},
+		{
+			name: "after move",
+			line: `[simpgo:after_move] {"id": "abc", "title": "\u6d4b\u8bd5", "filepath": "/tmp/a.mp4"}`,
+			want: "已保存: /tmp/a.mp4",
+			ok:   true,
+		},
+		{
+			name:        "live progress",
+			line:        "[download]   12.34MiB at  1.23MiB/s (00:01:23) (frag 42)",
+			want:        "录制中: 已录制 12.34MiB，时长 00:01:23，速度 1.23MiB/s",
+			rewriteLast: true,
+			hasProgress: true,
+			ok:          true,
+		},
+		{
+			name:        "ffmpeg live progress",
+			line:        "frame= 2500 fps= 30 q=-1.0 size=   10240kB time=00:01:23.45 bitrate=1000.0kbits/s speed=1x",
+			want:        "录制中: 已录制 10240kB，时长 00:01:23",
+			rewriteLast: true,
+			hasProgress: true,
+			ok:          true,
+		},
+		{
+			name:        "wait for video",
+			line:        "[wait] Remaining time until next attempt: 00:04:59",
+			want:        "等待直播开始，剩余 00:04:59",
+			rewriteLast: true,
+			hasProgress: true,
+			ok:          true,
+		},
+		{
+			name:        "live status",
+			line:        liveStatusPrefix + "is_live",
+			want:        "检测到直播，开始录制",
+			hasProgress: true,
+			ok:          true,
+		},
+		{
+			name: "not live",
+			line: liveStatusPrefix + "not_live",
+			ok:   false,
+		},
 		{
 			name: "ignored noise",
 			line: "Deleting original file video.f137.mp4 (pass -k to keep)",
@@ -56,3 +109,37 @@ func TestFriendlyYtDlpLine(t *testing.T) {
 		})
 	}
 }
+
+func TestFinalizeLivePart(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "直播.mp4")
+
+	if f, err := finalizeLivePart("", "u"); f != nil || err != nil {
+		t.Errorf("无目标文件时 = %v, %v, want nil, nil", f, err)
+	}
+	if f, err := finalizeLivePart(dest, "u"); f != nil || err != nil {
+		t.Errorf("没有 .part 文件时 = %v, %v, want nil, nil", f, err)
+	}
+
+	if err := os.WriteFile(dest+".part", []byte("ts"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := finalizeLivePart(dest, "https://example.com/live")
+	if err != nil {
+		t.Fatalf("finalizeLivePart 出错: %v", err)
+	}
+	if f == nil || f.FilePath != dest || f.Title != "直播" || f.URL != "https://example.com/live" {
+		t.Errorf("finalizeLivePart() = %+v", f)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Errorf("重命名后的文件不存在: %v", err)
+	}
+
+	// 目标已存在时不覆盖
+	if err := os.WriteFile(dest+".part", []byte("ts"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finalizeLivePart(dest, "u"); err == nil {
+		t.Error("目标文件已存在时应返回错误")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// HistoryFileName 为下载历史文件名，每行一条 JSON 记录。
+const HistoryFileName = "history.jsonl"
+
+// HistoryEntry 为下载历史中的一条记录，对应一个已完成的文件。
+type HistoryEntry struct {
+	Time  time.Time `json:"time"`
+	JobID int       `json:"job_id"`
+	// LogFile 为下载该文件的任务日志的文件名（位于日志目录中），旧记录或无法记录日志时为空
+	LogFile string `json:"log_file,omitempty"`
+	DownloadedFile
+}
+
+// ArchiveKey 返回该记录在 yt-dlp 下载记录文件中的行。
+func (e HistoryEntry) ArchiveKey() string {
+	return archiveKey(e.Extractor, e.ID)
+}
+
+// historyLogFiles 按下载记录中的行（见 ArchiveKey）返回最近一次下载的任务日志文件名。
+func historyLogFiles(entries []HistoryEntry) map[string]string {
+	files := map[string]string{}
+	for _, e := range entries {
+		if e.LogFile != "" && e.Extractor != "" && e.ID != "" {
+			files[e.ArchiveKey()] = e.LogFile
+		}
+	}
+	return files
+}
+
+var historyMu sync.Mutex
+
+// AppendHistory 将记录追加到历史文件末尾。
+func AppendHistory(path string, entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("无法生成历史记录: %w", err)
+	}
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("无法打开历史文件: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("无法写入历史记录: %w", err)
+	}
+	return f.Close()
+}
+
+// LoadHistory 读取历史文件，文件不存在时返回空列表，无法解析的行被忽略。
+func LoadHistory(path string) ([]HistoryEntry, error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	return readHistory(path)
+}
+
+func readHistory(path string) ([]HistoryEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取历史文件: %w", err)
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("无法读取历史文件: %w", err)
+	}
+	return entries, nil
+}
+
+// UpdateHistory 读取历史文件，由 update 修改记录后写回。期间不会写入新的记录。
+func UpdateHistory(path string, update func([]HistoryEntry) error) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	entries, err := readHistory(path)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	updateErr := update(entries)
+	if err := writeHistory(path, entries); err != nil {
+		return err
+	}
+	return updateErr
+}
+
+// writeHistory 重写历史文件，先写临时文件再替换，避免中途失败损坏原文件。
+func writeHistory(path string, entries []HistoryEntry) error {
+	var b bytes.Buffer
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("无法生成历史记录: %w", err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("无法写入历史文件: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("无法写入历史文件: %w", err)
+	}
+	return nil
+}
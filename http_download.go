@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// httpDownload 描述一次文件下载。
+type httpDownload struct {
+	URLs       []string // 按顺序尝试的下载地址，前一个失败时换下一个
+	Dest       string   // 完成后的文件路径。下载中写入 Dest+".tmp"，中断后再次下载时从断点继续
+	Digest     string   // 形如 sha256:<hex>，非空时校验下载的文件
+	UserAgent  string
+	OnProgress func(received, total int64) // total 为 -1 时表示未知
+}
+
+// httpDownloader 下载程序自身的更新和 yt-dlp：断点续传、失败重试、按顺序换用镜像地址。
+type httpDownloader struct {
+	client           *http.Client
+	attempts         int           // 每个地址的最多尝试次数
+	backoff          time.Duration // 第一次重试前的等待时间，之后每次加倍
+	stallTimeout     time.Duration // 超过该时间没有收到数据则断开重试
+	progressInterval time.Duration // 两次进度回调的最小间隔
+}
+
+// newHTTPDownloader 返回经由 proxy 下载的 httpDownloader。
+func newHTTPDownloader(proxy ProxyRoute) *httpDownloader {
+	client := appHTTPClient(proxy, 0)
+	client.Transport.(*http.Transport).ResponseHeaderTimeout = 30 * time.Second
+	return &httpDownloader{
+		client:           client,
+		attempts:         3,
+		backoff:          2 * time.Second,
+		stallTimeout:     30 * time.Second,
+		progressInterval: 100 * time.Millisecond,
+	}
+}
+
+// errStalled 表示长时间没有收到数据。
+var errStalled = errors.New("长时间没有收到数据")
+
+// Download 依次尝试 dl.URLs 中的地址，直到下载完成或 ctx 被取消。
+// 网络错误和服务器错误在同一地址重试，地址不存在等错误直接换下一个地址。
+func (d *httpDownloader) Download(ctx context.Context, dl httpDownload) error {
+	if len(dl.URLs) == 0 {
+		return fmt.Errorf("没有可用的下载地址")
+	}
+	var errs []error
+	for _, u := range dl.URLs {
+		err := d.downloadFrom(ctx, dl, u)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", u, err))
+	}
+	return fmt.Errorf("下载失败: %w", errors.Join(errs...))
+}
+
+func (d *httpDownloader) downloadFrom(ctx context.Context, dl httpDownload, u string) error {
+	var err error
+	wait := d.backoff
+	for attempt := 0; attempt < max(d.attempts, 1); attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+		var retry bool
+		if retry, err = d.try(ctx, dl, u); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// try 下载一次，返回的 retry 表示失败后是否值得在同一地址重试。
+func (d *httpDownloader) try(ctx context.Context, dl httpDownload, u string) (retry bool, err error) {
+	tmp := dl.Dest + ".tmp"
+	offset, validator := partialDownload(tmp)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, fmt.Errorf("创建下载请求失败: %w", err)
+	}
+	if dl.UserAgent != "" {
+		req.Header.Set("User-Agent", dl.UserAgent)
+	}
+	if offset > 0 {
+		// 文件在服务器上已改变时 If-Range 不成立，服务器返回完整内容
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	var f *os.File
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && contentRangeStart(resp.Header.Get("Content-Range")) == offset:
+		f, err = os.OpenFile(tmp, os.O_WRONLY|os.O_APPEND, 0644)
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+		if f, err = os.Create(tmp); err == nil {
+			err = savePartialValidator(tmp, resp.Header)
+		}
+	default:
+		// 续传的位置不对时删除已下载的部分，重试时从头下载
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable || resp.StatusCode == http.StatusPartialContent {
+			removePartialDownload(tmp)
+			return true, fmt.Errorf("服务器返回 %s", resp.Status)
+		}
+		return retryableStatus(resp.StatusCode), fmt.Errorf("服务器返回 %s", resp.Status)
+	}
+	if err != nil {
+		if f != nil {
+			_ = f.Close()
+		}
+		return false, fmt.Errorf("无法写入临时文件: %w", err)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	var stalled atomic.Bool
+	watchdog := time.AfterFunc(d.stallTimeout, func() {
+		stalled.Store(true)
+		cancel()
+	})
+	var last time.Time
+	err = copyWithProgress(f, resp.Body, total, func(received, total int64) {
+		watchdog.Reset(d.stallTimeout)
+		if dl.OnProgress != nil && time.Since(last) >= d.progressInterval {
+			last = time.Now()
+			dl.OnProgress(offset+received, total)
+		}
+	})
+	watchdog.Stop()
+	closeErr := f.Close()
+	if err != nil {
+		if stalled.Load() {
+			err = errStalled
+		}
+		return ctx.Err() == nil || stalled.Load(), fmt.Errorf("下载中断: %w", err)
+	}
+	if closeErr != nil {
+		return false, fmt.Errorf("关闭文件失败: %w", closeErr)
+	}
+	if dl.OnProgress != nil {
+		if st, err := os.Stat(tmp); err == nil {
+			dl.OnProgress(st.Size(), total)
+		}
+	}
+
+	if err := verifyFileDigest(tmp, dl.Digest); err != nil {
+		// 续传的内容可能与之前的不一致，删除后重新下载
+		removePartialDownload(tmp)
+		return true, err
+	}
+	if err := replaceFile(tmp, dl.Dest); err != nil {
+		return false, err
+	}
+	_ = os.Remove(tmp + ".validator")
+	return false, nil
+}
+
+// partialDownload 返回上次中断时已下载的大小和用于 If-Range 的 ETag 或 Last-Modified。
+// 没有记录时无法确认服务器上的文件未变，返回 0 重新下载。
+func partialDownload(tmp string) (int64, string) {
+	st, err := os.Stat(tmp)
+	if err != nil || st.Size() == 0 {
+		return 0, ""
+	}
+	data, err := os.ReadFile(tmp + ".validator")
+	if err != nil || len(data) == 0 {
+		return 0, ""
+	}
+	return st.Size(), string(data)
+}
+
+// savePartialValidator 记录响应的 ETag 或 Last-Modified，供续传时使用。弱 ETag 不能用于 If-Range。
+func savePartialValidator(tmp string, h http.Header) error {
+	validator := h.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = h.Get("Last-Modified")
+	}
+	if validator == "" {
+		if err := os.Remove(tmp + ".validator"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(tmp+".validator", []byte(validator), 0644)
+}
+
+func removePartialDownload(tmp string) {
+	_ = os.Remove(tmp)
+	_ = os.Remove(tmp + ".validator")
+}
+
+// contentRangeStart 返回 Content-Range（如 bytes 100-199/200）的起始位置，无法解析时返回 -1。
+func contentRangeStart(h string) int64 {
+	rest, ok := strings.CutPrefix(h, "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// retryableStatus 判断服务器错误是否可能是暂时的。
+func retryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
+
+// verifyFileDigest 按 digest 校验文件，digest 为空时不校验。
+func verifyFileDigest(path, digest string) error {
+	if digest == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("无法读取下载的文件: %w", err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return fmt.Errorf("无法读取下载的文件: %w", err)
+	}
+	return verifyAssetDigest(digest, hash.Sum(nil))
+}
+
+// replaceFile 将 src 重命名为 dst。Windows 上目标文件可能被其他进程锁定（杀毒扫描等），多次重试。
+func replaceFile(src, dst string) error {
+	var err error
+	for i := 0; i < 6; i++ {
+		if err = os.Rename(src, dst); err == nil {
+			return nil
+		}
+		if _, statErr := os.Stat(dst); statErr == nil {
+			_ = os.Remove(dst)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("重命名文件失败: %w", err)
+}
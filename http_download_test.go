@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestDownloader() *httpDownloader {
+	return &httpDownloader{
+		client:       &http.Client{},
+		attempts:     3,
+		backoff:      time.Millisecond,
+		stallTimeout: time.Second,
+	}
+}
+
+func testContent() ([]byte, string) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	sum := sha256.Sum256(content)
+	return content, "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// serveFile 支持 Range 和 If-Range，和 GitHub 下载地址一样带 ETag。
+func serveFile(w http.ResponseWriter, r *http.Request, content []byte, etag string) {
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+}
+
+// dropAfter 发送完整的响应头和 n 字节内容后断开连接。
+func dropAfter(t *testing.T, w http.ResponseWriter, content []byte, etag string, n int) {
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\nETag: %s\r\n\r\n", len(content), etag)
+	buf.Write(content[:n])
+	buf.Flush()
+}
+
+func TestHTTPDownloaderResume(t *testing.T) {
+	content, digest := testContent()
+	var requests atomic.Int32
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if requests.Add(1) == 1 {
+			dropAfter(t, w, content, `"v1"`, 1000)
+			return
+		}
+		serveFile(w, r, content, `"v1"`)
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "yt-dlp")
+	var lastReceived, lastTotal int64
+	err := newTestDownloader().Download(context.Background(), httpDownload{
+		URLs:   []string{srv.URL},
+		Dest:   dest,
+		Digest: digest,
+		OnProgress: func(received, total int64) {
+			lastReceived, lastTotal = received, total
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Fatal("下载的内容不一致")
+	}
+	if len(ranges) != 2 || ranges[1] != "bytes=1000-" {
+		t.Errorf("第二次请求应从断点续传: %q", ranges)
+	}
+	if lastReceived != int64(len(content)) || lastTotal != int64(len(content)) {
+		t.Errorf("最后的进度 = %d/%d, want %d", lastReceived, lastTotal, len(content))
+	}
+	for _, p := range []string{dest + ".tmp", dest + ".tmp.validator"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s 应被删除", p)
+		}
+	}
+}
+
+func TestHTTPDownloaderChangedFile(t *testing.T) {
+	content, digest := testContent()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveFile(w, r, content, `"v2"`)
+	}))
+	defer srv.Close()
+
+	// 上次中断时服务器上还是旧版本，续传时 If-Range 不成立，应重新下载完整内容
+	dest := filepath.Join(t.TempDir(), "yt-dlp")
+	writeTestFile(t, dest+".tmp", "old partial content")
+	writeTestFile(t, dest+".tmp.validator", `"v1"`)
+	d := newTestDownloader()
+	d.progressInterval = time.Hour
+	var calls int
+	onProgress := func(received, total int64) { calls++ }
+	if err := d.Download(context.Background(), httpDownload{URLs: []string{srv.URL}, Dest: dest, Digest: digest, OnProgress: onProgress}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Fatal("应重新下载完整内容")
+	}
+	// 间隔内只在开始和完成时回调
+	if calls != 2 {
+		t.Errorf("进度回调 %d 次, want 2", calls)
+	}
+}
+
+func TestHTTPDownloaderMirrors(t *testing.T) {
+	content, _ := testContent()
+	var notFound, broken, ok atomic.Int32
+	srvNotFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notFound.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer srvNotFound.Close()
+	srvBroken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		broken.Add(1)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer srvBroken.Close()
+	srvOK := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok.Add(1)
+		serveFile(w, r, content, `"v1"`)
+	}))
+	defer srvOK.Close()
+
+	dest := filepath.Join(t.TempDir(), "yt-dlp")
+	err := newTestDownloader().Download(context.Background(), httpDownload{
+		URLs: []string{srvNotFound.URL, srvBroken.URL, srvOK.URL},
+		Dest: dest,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 404 不重试，服务器错误在同一地址重试
+	if notFound.Load() != 1 || broken.Load() != 3 || ok.Load() != 1 {
+		t.Errorf("请求次数 = %d, %d, %d, want 1, 3, 1", notFound.Load(), broken.Load(), ok.Load())
+	}
+
+	err = newTestDownloader().Download(context.Background(), httpDownload{URLs: []string{srvNotFound.URL, srvBroken.URL}, Dest: dest + "2"})
+	if err == nil || !strings.Contains(err.Error(), srvNotFound.URL) || !strings.Contains(err.Error(), "502") {
+		t.Errorf("所有地址都失败时应列出每个地址的错误: %v", err)
+	}
+}
+
+func TestHTTPDownloaderStall(t *testing.T) {
+	content, digest := testContent()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			w.Header().Set("ETag", `"v1"`)
+			w.Write(content[:100])
+			w.(http.Flusher).Flush()
+			// 不再发送数据，直到客户端断开
+			<-r.Context().Done()
+			return
+		}
+		serveFile(w, r, content, `"v1"`)
+	}))
+	defer srv.Close()
+
+	d := newTestDownloader()
+	d.stallTimeout = 100 * time.Millisecond
+	dest := filepath.Join(t.TempDir(), "yt-dlp")
+	if err := d.Download(context.Background(), httpDownload{URLs: []string{srv.URL}, Dest: dest, Digest: digest}); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("卡住后应断开重试，请求次数 = %d", requests.Load())
+	}
+}
+
+func TestHTTPDownloaderDigestMismatch(t *testing.T) {
+	content, _ := testContent()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		serveFile(w, r, content, `"v1"`)
+	}))
+	defer srv.Close()
+
+	d := newTestDownloader()
+	d.attempts = 2
+	dest := filepath.Join(t.TempDir(), "update.exe")
+	err := d.Download(context.Background(), httpDownload{URLs: []string{srv.URL}, Dest: dest, Digest: "sha256:" + strings.Repeat("0", 64)})
+	if err == nil || !strings.Contains(err.Error(), "sha256") {
+		t.Fatalf("校验失败时应返回错误: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("校验失败后应重新下载，请求次数 = %d", requests.Load())
+	}
+	for _, p := range []string{dest, dest + ".tmp", dest + ".tmp.validator"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s 不应存在", p)
+		}
+	}
+}
+
+func TestHTTPDownloaderCanceled(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := newTestDownloader()
+	d.backoff = time.Hour
+	go func() {
+		for requests.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	if err := d.Download(ctx, httpDownload{URLs: []string{srv.URL, srv.URL}, Dest: filepath.Join(t.TempDir(), "f")}); err != context.Canceled {
+		t.Errorf("Download = %v, want context.Canceled", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("取消后不应继续请求，请求次数 = %d", requests.Load())
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{in: "bytes 100-199/200", want: 100},
+		{in: "bytes 0-0/*", want: 0},
+		{in: "bytes */200", want: -1},
+		{in: "", want: -1},
+	}
+	for _, tt := range tests {
+		if got := contentRangeStart(tt.in); got != tt.want {
+			t.Errorf("contentRangeStart(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
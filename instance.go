@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InstanceLockName 为单实例锁文件，保存正在运行的程序监听的本地端口和口令。
+const InstanceLockName = "instance.lock"
+
+// ErrAnotherInstance 表示程序已在运行，命令行参数已转发给该程序。
+var ErrAnotherInstance = errors.New("程序已在运行")
+
+// errInstanceDenied 表示正在运行的程序因口令不符拒绝了转发的参数。
+var errInstanceDenied = errors.New("正在运行的程序拒绝了请求")
+
+// instanceReplyTimeout 为等待正在运行的程序处理参数的超时，测试中可缩短。
+var instanceReplyTimeout = 10 * time.Second
+
+const (
+	// instanceDialTimeout 为连接正在运行的程序的超时
+	instanceDialTimeout = 2 * time.Second
+	// 锁文件刚创建、尚未写入内容时等待重试，仍无效时视为残留
+	instanceLockWaits = 10
+	instanceLockWait  = 100 * time.Millisecond
+	// 删除残留的锁文件后重新获取的次数
+	instanceLockAttempts = instanceLockWaits + 5
+)
+
+// instanceMessage 为后启动的程序转发给正在运行的程序的内容，每个连接一行 JSON。
+type instanceMessage struct {
+	Token string   `json:"token"`
+	Args  []string `json:"args"`
+}
+
+// InstanceServer 为正在运行的程序接收其他实例转发的命令行参数。
+// 只监听 127.0.0.1，并用锁文件中的随机口令验证，锁文件只允许当前用户读写。
+type InstanceServer struct {
+	ln       net.Listener
+	lockPath string
+	lockData []byte
+	token    string
+
+	closeOnce sync.Once
+}
+
+// AcquireSingleInstance 在 dir 中获取单实例锁。已有程序在运行时将 args 转发给它并返回 ErrAnotherInstance；
+// 锁文件残留（程序异常退出，或端口上的程序关闭连接、应答无效）时接管。
+func AcquireSingleInstance(dir string, args []string) (*InstanceServer, error) {
+	lockPath := filepath.Join(dir, InstanceLockName)
+	var lastErr error
+	waits := 0
+	for attempt := 0; attempt < instanceLockAttempts; attempt++ {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			return startInstanceServer(f, lockPath)
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("无法创建单实例锁: %w", err)
+		}
+
+		data, port, token, err := readInstanceLock(lockPath)
+		if err != nil {
+			// 另一个程序刚创建锁文件，稍后再读
+			lastErr = err
+			if waits < instanceLockWaits {
+				waits++
+				time.Sleep(instanceLockWait)
+				continue
+			}
+		} else {
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), instanceDialTimeout)
+			if err == nil {
+				// 收到确认或拒绝说明是正在运行的程序；等待应答超时时它可能仍在启动，也不能接管。
+				// 连接在应答前被关闭或应答无效时视为残留
+				err = forwardInstanceArgs(conn, token, args)
+				if err == nil {
+					return nil, ErrAnotherInstance
+				}
+				var netErr net.Error
+				if errors.Is(err, errInstanceDenied) || errors.As(err, &netErr) && netErr.Timeout() {
+					return nil, fmt.Errorf("%w，但无法转发参数: %v", ErrAnotherInstance, err)
+				}
+			}
+			lastErr = err
+		}
+
+		// 锁文件无效或程序已退出，内容未被其他程序更新时删除后重试
+		if current, err := os.ReadFile(lockPath); err == nil && !bytes.Equal(current, data) {
+			continue
+		}
+		if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("无法删除残留的单实例锁: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("无法获取单实例锁: %w", lastErr)
+}
+
+// startInstanceServer 监听本地端口，并将端口和口令写入已创建的锁文件 f。
+func startInstanceServer(f *os.File, lockPath string) (*InstanceServer, error) {
+	fail := func(err error) (*InstanceServer, error) {
+		f.Close()
+		os.Remove(lockPath)
+		return nil, err
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fail(fmt.Errorf("无法生成口令: %w", err))
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fail(fmt.Errorf("无法监听本地端口: %w", err))
+	}
+	s := &InstanceServer{ln: ln, lockPath: lockPath, token: hex.EncodeToString(buf)}
+	s.lockData = []byte(fmt.Sprintf("%d %s\n", ln.Addr().(*net.TCPAddr).Port, s.token))
+	if _, err := f.Write(s.lockData); err != nil {
+		ln.Close()
+		return fail(fmt.Errorf("无法写入单实例锁: %w", err))
+	}
+	if err := f.Close(); err != nil {
+		ln.Close()
+		os.Remove(lockPath)
+		return nil, fmt.Errorf("无法写入单实例锁: %w", err)
+	}
+	return s, nil
+}
+
+// readInstanceLock 读取锁文件，返回文件内容、端口和口令。
+func readInstanceLock(lockPath string) (data []byte, port int, token string, err error) {
+	data, err = os.ReadFile(lockPath)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	portStr, token, ok := strings.Cut(strings.TrimSpace(string(data)), " ")
+	if !ok || token == "" {
+		return data, 0, "", fmt.Errorf("单实例锁内容无效")
+	}
+	port, err = strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return data, 0, "", fmt.Errorf("单实例锁中的端口无效: %q", portStr)
+	}
+	return data, port, token, nil
+}
+
+// forwardInstanceArgs 发送参数并等待正在运行的程序确认，被拒绝时返回 errInstanceDenied。
+func forwardInstanceArgs(conn net.Conn, token string, args []string) error {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(instanceReplyTimeout))
+	msg, err := json.Marshal(instanceMessage{Token: token, Args: args})
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(append(msg, '\n')); err != nil {
+		return err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	switch reply = strings.TrimSpace(reply); reply {
+	case "ok":
+		return nil
+	case "denied":
+		return errInstanceDenied
+	}
+	return fmt.Errorf("正在运行的程序应答无效: %q", reply)
+}
+
+// Serve 在后台接收其他实例转发的参数，每次转发调用一次 handler（参数可能为空，此时只需将窗口置于前台）。
+// handler 在后台协程中调用。
+func (s *InstanceServer) Serve(handler func(args []string)) {
+	if s == nil {
+		return
+	}
+	go func() {
+		for {
+			conn, err := s.ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn, handler)
+		}
+	}()
+}
+
+func (s *InstanceServer) handle(conn net.Conn, handler func(args []string)) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(instanceReplyTimeout))
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var msg instanceMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		fmt.Fprintln(conn, "invalid")
+		return
+	}
+	if msg.Token != s.token {
+		fmt.Fprintln(conn, "denied")
+		return
+	}
+	handler(msg.Args)
+	fmt.Fprintln(conn, "ok")
+}
+
+// Close 停止接收并删除锁文件，之后启动的程序成为新的实例。可多次调用。
+func (s *InstanceServer) Close() {
+	if s == nil {
+		return
+	}
+	s.closeOnce.Do(func() {
+		s.ln.Close()
+		// 只删除自己写入的锁文件
+		if data, err := os.ReadFile(s.lockPath); err == nil && bytes.Equal(data, s.lockData) {
+			os.Remove(s.lockPath)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSingleInstanceForwardsArgs(t *testing.T) {
+	dir := t.TempDir()
+	s, err := AcquireSingleInstance(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	got := make(chan []string, 1)
+	s.Serve(func(args []string) { got <- args })
+
+	args := []string{"https://example.com/watch?v=1", "--foo"}
+	if _, err := AcquireSingleInstance(dir, args); err != ErrAnotherInstance {
+		t.Fatalf("第二个实例应返回 ErrAnotherInstance，实际 %v", err)
+	}
+	select {
+	case a := <-got:
+		if !reflect.DeepEqual(a, args) {
+			t.Errorf("收到 %q，应为 %q", a, args)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到转发的参数")
+	}
+
+	// 关闭后锁文件被删除，可重新获取
+	s.Close()
+	if _, err := os.Stat(filepath.Join(dir, InstanceLockName)); !os.IsNotExist(err) {
+		t.Errorf("关闭后锁文件应被删除: %v", err)
+	}
+	s2, err := AcquireSingleInstance(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2.Close()
+}
+
+func TestSingleInstanceStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	// 程序异常退出后残留的锁文件：端口上已没有程序监听
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	lockPath := filepath.Join(dir, InstanceLockName)
+	writeTestFile(t, lockPath, fmt.Sprintf("%d deadbeef\n", port))
+
+	s, err := AcquireSingleInstance(dir, nil)
+	if err != nil {
+		t.Fatalf("应接管残留的锁: %v", err)
+	}
+	defer s.Close()
+	if data, _ := os.ReadFile(lockPath); string(data) == fmt.Sprintf("%d deadbeef\n", port) {
+		t.Error("锁文件应被重写")
+	}
+
+	// 内容无效的锁文件在重试后也会被接管
+	s.Close()
+	writeTestFile(t, lockPath, "garbage")
+	s, err = AcquireSingleInstance(dir, nil)
+	if err != nil {
+		t.Fatalf("应接管无效的锁: %v", err)
+	}
+	s.Close()
+}
+
+func TestSingleInstanceUnexpectedReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string // 为空时不应答直接关闭连接
+	}{
+		{name: "closed", reply: ""},
+		{name: "invalid", reply: "invalid\n"},
+		{name: "other service", reply: "HTTP/1.1 400 Bad Request\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// 锁文件中的端口已被不相关的程序占用
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			go func() {
+				for {
+					conn, err := ln.Accept()
+					if err != nil {
+						return
+					}
+					conn.Write([]byte(tt.reply))
+					conn.Close()
+				}
+			}()
+			writeTestFile(t, filepath.Join(dir, InstanceLockName), fmt.Sprintf("%d deadbeef\n", ln.Addr().(*net.TCPAddr).Port))
+
+			s, err := AcquireSingleInstance(dir, []string{"https://example.com"})
+			if err != nil {
+				t.Fatalf("未按约定应答时应接管锁: %v", err)
+			}
+			s.Close()
+		})
+	}
+}
+
+func TestSingleInstanceReplyTimeout(t *testing.T) {
+	old := instanceReplyTimeout
+	instanceReplyTimeout = 200 * time.Millisecond
+	defer func() { instanceReplyTimeout = old }()
+
+	dir := t.TempDir()
+	// 正在启动的程序已监听端口，但尚未开始处理转发的参数
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lockPath := filepath.Join(dir, InstanceLockName)
+	lock := fmt.Sprintf("%d deadbeef\n", ln.Addr().(*net.TCPAddr).Port)
+	writeTestFile(t, lockPath, lock)
+
+	if _, err := AcquireSingleInstance(dir, []string{"https://example.com"}); !errors.Is(err, ErrAnotherInstance) {
+		t.Fatalf("等待应答超时时应返回 ErrAnotherInstance，实际 %v", err)
+	}
+	if data, _ := os.ReadFile(lockPath); string(data) != lock {
+		t.Errorf("等待应答超时时不应接管锁文件: %q", data)
+	}
+}
+
+func TestSingleInstanceRejectsWrongToken(t *testing.T) {
+	dir := t.TempDir()
+	s, err := AcquireSingleInstance(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	called := make(chan struct{}, 1)
+	s.Serve(func([]string) { called <- struct{}{} })
+
+	_, port, _, err := readInstanceLock(filepath.Join(dir, InstanceLockName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := forwardInstanceArgs(conn, "wrong", []string{"https://example.com"}); !errors.Is(err, errInstanceDenied) {
+		t.Errorf("口令错误时应被拒绝，实际 %v", err)
+	}
+	select {
+	case <-called:
+		t.Error("口令错误时不应处理参数")
+	default:
+	}
+
+	// 锁文件中的口令与正在运行的程序不符时，不应接管
+	other := t.TempDir()
+	writeTestFile(t, filepath.Join(other, InstanceLockName), fmt.Sprintf("%d deadbeef\n", port))
+	if _, err := AcquireSingleInstance(other, nil); !errors.Is(err, ErrAnotherInstance) || err == ErrAnotherInstance {
+		t.Errorf("被拒绝时应返回包含原因的 ErrAnotherInstance，实际 %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogsDirName 为保存任务原始输出的目录名。
+const LogsDirName = "logs"
+
+// jobLogKeep 为日志目录中最多保留的任务日志文件数，超出时删除最旧的。
+const jobLogKeep = 100
+
+const jobLogPrefix = "job-"
+
+// jobLogTimeLayout 为任务日志文件名中的时间格式，按名称排序即按时间排序。
+const jobLogTimeLayout = "20060102-150405"
+
+// JobLog 将单个任务的 yt-dlp 完整输出写入日志文件，可并发写入。
+type JobLog struct {
+	mu   sync.Mutex
+	f    *os.File
+	path string
+}
+
+// openJobLog 在 dir 下为任务创建日志文件，并清理超出数量的旧日志。
+func openJobLog(dir string, jobID int, now time.Time) (*JobLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("无法创建日志目录: %w", err)
+	}
+	name := fmt.Sprintf("%s%s-%d.log", jobLogPrefix, now.Format(jobLogTimeLayout), jobID)
+	p := filepath.Join(dir, name)
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("无法创建任务日志: %w", err)
+	}
+	rotateJobLogs(dir, jobLogKeep)
+	return &JobLog{f: f, path: p}, nil
+}
+
+// parseJobLogName 解析任务日志文件名 job-<时间>-<编号>.log，返回创建时间（本地时间）和任务编号。
+func parseJobLogName(name string) (time.Time, int, bool) {
+	rest, ok := strings.CutPrefix(name, jobLogPrefix)
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	rest, ok = strings.CutSuffix(rest, ".log")
+	if !ok || len(rest) < len(jobLogTimeLayout)+2 || rest[len(jobLogTimeLayout)] != '-' {
+		return time.Time{}, 0, false
+	}
+	t, err := time.ParseInLocation(jobLogTimeLayout, rest[:len(jobLogTimeLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	id, err := strconv.Atoi(rest[len(jobLogTimeLayout)+1:])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return t, id, true
+}
+
+// findJobLog 在 dir 中查找 since 之后创建的、指定任务的最新日志文件。
+// 任务编号每次运行都从 1 开始，since 应为本次运行的开始时间。
+func findJobLog(dir string, jobID int, since time.Time) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	since = since.Truncate(time.Second)
+	var found string
+	var foundAt time.Time
+	for _, e := range entries {
+		t, id, ok := parseJobLogName(e.Name())
+		if !ok || e.IsDir() || id != jobID || t.Before(since) || (found != "" && !t.After(foundAt)) {
+			continue
+		}
+		found, foundAt = filepath.Join(dir, e.Name()), t
+	}
+	return found, found != ""
+}
+
+// existingJobLog 返回日志目录中名为 name 的任务日志路径，name 为空或文件已被清理时返回 false。
+func existingJobLog(dir, name string) (string, bool) {
+	if name == "" || filepath.Base(name) != name {
+		return "", false
+	}
+	p := filepath.Join(dir, name)
+	if _, err := os.Stat(p); err != nil {
+		return "", false
+	}
+	return p, true
+}
+
+// rotateJobLogs 只保留 dir 中最新的 keep 个任务日志。
+func rotateJobLogs(dir string, keep int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), jobLogPrefix) && strings.HasSuffix(e.Name(), ".log") {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= keep {
+		return
+	}
+	// 文件名以时间开头，按名称排序即按时间排序
+	sort.Strings(names)
+	for _, name := range names[:len(names)-keep] {
+		_ = os.Remove(filepath.Join(dir, name))
+	}
+}
+
+// Path 返回日志文件路径。
+func (l *JobLog) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// WriteLine 写入一行带时间戳和来源的输出。l 为 nil 时忽略。
+func (l *JobLog) WriteLine(stream, line string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return
+	}
+	fmt.Fprintf(l.f, "%s [%s] %s\n", time.Now().Format("15:04:05.000"), stream, line)
+}
+
+// Close 关闭日志文件。
+func (l *JobLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// exportJobLog 将任务日志复制到 dest。
+func exportJobLog(src, dest string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("无法读取任务日志: %w", err)
+	}
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return fmt.Errorf("无法导出任务日志: %w", err)
+	}
+	return nil
+}
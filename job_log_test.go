@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobLog(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), LogsDirName)
+	l, err := openJobLog(dir, 42, time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local))
+	if err != nil {
+		t.Fatalf("创建任务日志失败: %v", err)
+	}
+	l.WriteLine("stdout", "[youtube] abc: Downloading webpage")
+	l.WriteLine("stderr", "ERROR: boom")
+	if err := l.Close(); err != nil {
+		t.Fatalf("关闭任务日志失败: %v", err)
+	}
+	l.WriteLine("stdout", "关闭后写入应被忽略")
+
+	if filepath.Base(l.Path()) != "job-20260102-030405-42.log" {
+		t.Fatalf("日志文件名 = %s", filepath.Base(l.Path()))
+	}
+	if p, ok := findJobLog(dir, 42, time.Date(2026, 1, 2, 3, 4, 5, 500, time.Local)); !ok || p != l.Path() {
+		t.Fatalf("findJobLog(42) = %s, %v", p, ok)
+	}
+	if p, ok := existingJobLog(dir, filepath.Base(l.Path())); !ok || p != l.Path() {
+		t.Fatalf("existingJobLog = %s, %v", p, ok)
+	}
+
+	data, err := os.ReadFile(l.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("日志行数 = %d, want 2:\n%s", len(lines), data)
+	}
+	if !strings.HasSuffix(lines[0], "[stdout] [youtube] abc: Downloading webpage") || !strings.HasSuffix(lines[1], "[stderr] ERROR: boom") {
+		t.Fatalf("日志内容不正确:\n%s", data)
+	}
+
+	dest := filepath.Join(t.TempDir(), "export.log")
+	if err := exportJobLog(l.Path(), dest); err != nil {
+		t.Fatalf("导出任务日志失败: %v", err)
+	}
+	if exported, _ := os.ReadFile(dest); string(exported) != string(data) {
+		t.Fatal("导出的日志内容应与原日志一致")
+	}
+}
+
+func TestNilJobLog(t *testing.T) {
+	var l *JobLog
+	l.WriteLine("stdout", "ignored")
+	if err := l.Close(); err != nil {
+		t.Fatalf("nil JobLog 关闭不应报错: %v", err)
+	}
+}
+
+func TestRotateJobLogs(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 5; i++ {
+		name := fmt.Sprintf("job-20260101-00000%d-1.log", i)
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rotateJobLogs(dir, 2)
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"job-20260101-000004-1.log", "job-20260101-000005-1.log", "other.txt"}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Fatalf("保留的文件 = %v, want %v", names, want)
+	}
+}
+
+func TestFindJobLog(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"job-20260101-080000-1.log", // 上次运行
+		"job-20260102-090000-1.log",
+		"job-20260102-093000-1.log", // 同一任务暂停后重新开始
+		"job-20260102-090000-2.log",
+		"job-20260102-100000-12.log",
+		"job-bad-1.log",
+	} {
+		writeTestFile(t, filepath.Join(dir, name), "")
+	}
+	since := time.Date(2026, 1, 2, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		id   int
+		want string
+	}{
+		{1, "job-20260102-093000-1.log"},
+		{2, "job-20260102-090000-2.log"},
+		{12, "job-20260102-100000-12.log"},
+		{3, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if p, ok := findJobLog(dir, tt.id, since); ok {
+			got = filepath.Base(p)
+		}
+		if got != tt.want {
+			t.Errorf("findJobLog(%d) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+	if p, ok := findJobLog(dir, 1, time.Date(2026, 1, 3, 0, 0, 0, 0, time.Local)); ok {
+		t.Errorf("不应返回本次运行之前的日志: %s", p)
+	}
+
+	for _, name := range []string{"", "../job-20260102-090000-1.log", "job-20260103-000000-1.log"} {
+		if p, ok := existingJobLog(dir, name); ok {
+			t.Errorf("existingJobLog(%q) = %s，应不存在", name, p)
+		}
+	}
+}
+
+func TestHistoryLogFiles(t *testing.T) {
+	entries := []HistoryEntry{
+		{LogFile: "job-20260101-080000-1.log", DownloadedFile: DownloadedFile{Extractor: "Youtube", ID: "abc"}},
+		{LogFile: "job-20260102-080000-3.log", DownloadedFile: DownloadedFile{Extractor: "youtube", ID: "abc"}},
+		{DownloadedFile: DownloadedFile{Extractor: "youtube", ID: "old"}},
+	}
+	files := historyLogFiles(entries)
+	if len(files) != 1 || files[archiveKey("youtube", "abc")] != "job-20260102-080000-3.log" {
+		t.Errorf("historyLogFiles = %v", files)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// logStoreCapacity 为内存中保留的日志条数上限，超出后丢弃最旧的条目。
+const logStoreCapacity = 5000
+
+// logRewriteLookback 为改写日志时向前查找同一任务条目的最大距离。
+const logRewriteLookback = 64
+
+// LogLevel 表示日志条目的级别，数值越大越重要。零值为 LogInfo。
+type LogLevel int
+
+const (
+	LogRaw LogLevel = iota - 1
+	LogInfo
+	LogWarning
+	LogError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogRaw:
+		return "原始"
+	case LogInfo:
+		return "信息"
+	case LogWarning:
+		return "警告"
+	case LogError:
+		return "错误"
+	}
+	return "未知"
+}
+
+// LogEntry 为一条结构化日志。JobID 为 0 表示程序自身的日志。
+// Rewritable 的条目会被同一任务的下一条 Rewritable 条目原地替换，用于进度行；
+// 原始输出与整理后的日志分别改写，互不覆盖。
+type LogEntry struct {
+	Seq        uint64
+	Time       time.Time
+	JobID      int
+	Level      LogLevel
+	Text       string
+	Rewritable bool
+}
+
+// String 返回用于展示和导出的单行文本。
+func (e LogEntry) String() string {
+	prefix := e.Time.Format("15:04:05")
+	if e.JobID > 0 {
+		prefix += fmt.Sprintf(" [#%d]", e.JobID)
+	}
+	return prefix + " " + e.Text
+}
+
+// LogFilter 描述日志视图的筛选条件。JobID 为负数时不按任务筛选。
+// ShowRaw 为 true 时额外包含 yt-dlp 原始输出。
+type LogFilter struct {
+	JobID    int
+	MinLevel LogLevel
+	ShowRaw  bool
+}
+
+func (f LogFilter) match(e LogEntry) bool {
+	if f.JobID >= 0 && e.JobID != f.JobID {
+		return false
+	}
+	if e.Level == LogRaw {
+		return f.ShowRaw || f.MinLevel <= LogRaw
+	}
+	return e.Level >= f.MinLevel
+}
+
+type logOp struct {
+	entry LogEntry
+	done  chan struct{}
+}
+
+// LogStore 是有容量上限的环形日志存储。
+// 所有写入经由同一个 channel 交给唯一的消费 goroutine 处理，保证顺序。
+type LogStore struct {
+	ops chan logOp
+
+	mu        sync.RWMutex
+	entries   []LogEntry
+	head      int
+	count     int
+	seq       uint64
+	jobs      []int
+	listeners []func()
+}
+
+// NewLogStore 创建日志存储并启动消费 goroutine。
+func NewLogStore(capacity int) *LogStore {
+	if capacity <= 0 {
+		capacity = logStoreCapacity
+	}
+	s := &LogStore{
+		ops:     make(chan logOp, 1024),
+		entries: make([]LogEntry, capacity),
+	}
+	go s.consume()
+	return s
+}
+
+// Add 追加一条日志。可在任意 goroutine 中调用，同一调用方的日志按调用顺序写入。
+func (s *LogStore) Add(e LogEntry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	s.ops <- logOp{entry: e}
+}
+
+// Sync 等待此前提交的日志全部写入并完成变更通知。
+func (s *LogStore) Sync() {
+	done := make(chan struct{})
+	s.ops <- logOp{done: done}
+	<-done
+}
+
+// OnChange 注册日志变更回调。回调在消费 goroutine 中调用，连续写入会合并为一次通知。
+func (s *LogStore) OnChange(fn func()) {
+	s.mu.Lock()
+	s.listeners = append(s.listeners, fn)
+	s.mu.Unlock()
+}
+
+func (s *LogStore) consume() {
+	for op := range s.ops {
+		changed := false
+		var waiters []chan struct{}
+		apply := func(op logOp) {
+			if op.done != nil {
+				waiters = append(waiters, op.done)
+			} else if s.apply(op.entry) {
+				changed = true
+			}
+		}
+		apply(op)
+		// 尽量合并当前已排队的写入，减少界面刷新次数
+		for drained := false; !drained; {
+			select {
+			case next := <-s.ops:
+				apply(next)
+			default:
+				drained = true
+			}
+		}
+		if changed {
+			s.mu.RLock()
+			listeners := append([]func(){}, s.listeners...)
+			s.mu.RUnlock()
+			for _, fn := range listeners {
+				fn()
+			}
+		}
+		for _, done := range waiters {
+			close(done)
+		}
+	}
+}
+
+func (s *LogStore) apply(e LogEntry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.Rewritable {
+		if idx, ok := s.lastOf(e.JobID, e.Level == LogRaw); ok && s.entries[idx].Rewritable {
+			e.Seq = s.entries[idx].Seq
+			s.entries[idx] = e
+			return true
+		}
+	}
+
+	s.seq++
+	e.Seq = s.seq
+	capacity := len(s.entries)
+	if s.count < capacity {
+		s.entries[(s.head+s.count)%capacity] = e
+		s.count++
+	} else {
+		s.entries[s.head] = e
+		s.head = (s.head + 1) % capacity
+	}
+	if e.JobID > 0 && !slices.Contains(s.jobs, e.JobID) {
+		s.jobs = append(s.jobs, e.JobID)
+	}
+	return true
+}
+
+// lastOf 返回指定任务最近一条原始输出（raw 为 true）或整理后日志在环形缓冲区中的下标。
+func (s *LogStore) lastOf(jobID int, raw bool) (int, bool) {
+	capacity := len(s.entries)
+	for i := 0; i < s.count && i < logRewriteLookback; i++ {
+		idx := (s.head + s.count - 1 - i) % capacity
+		if s.entries[idx].JobID == jobID && (s.entries[idx].Level == LogRaw) == raw {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// Snapshot 返回符合筛选条件的日志副本，按写入顺序排列。
+func (s *LogStore) Snapshot(f LogFilter) []LogEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]LogEntry, 0, s.count)
+	capacity := len(s.entries)
+	for i := 0; i < s.count; i++ {
+		e := s.entries[(s.head+i)%capacity]
+		if f.match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Tail 返回最近 n 条符合筛选条件的日志文本。
+func (s *LogStore) Tail(f LogFilter, n int) []string {
+	entries := s.Snapshot(f)
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = e.String()
+	}
+	return lines
+}
+
+// Jobs 返回出现过日志的任务编号，按首次出现顺序排列。
+func (s *LogStore) Jobs() []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]int(nil), s.jobs...)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func entryTexts(entries []LogEntry) []string {
+	texts := make([]string, len(entries))
+	for i, e := range entries {
+		texts[i] = e.Text
+	}
+	return texts
+}
+
+func TestLogStoreKeepsOrder(t *testing.T) {
+	s := NewLogStore(1000)
+	for i := 0; i < 500; i++ {
+		s.Add(LogEntry{Text: fmt.Sprint(i)})
+	}
+	s.Sync()
+
+	entries := s.Snapshot(LogFilter{JobID: -1})
+	if len(entries) != 500 {
+		t.Fatalf("len = %d, want 500", len(entries))
+	}
+	for i, e := range entries {
+		if e.Text != fmt.Sprint(i) {
+			t.Fatalf("entries[%d] = %q, 日志顺序错乱", i, e.Text)
+		}
+		if e.Seq != uint64(i+1) {
+			t.Fatalf("entries[%d].Seq = %d, want %d", i, e.Seq, i+1)
+		}
+	}
+}
+
+func TestLogStoreCapacity(t *testing.T) {
+	s := NewLogStore(3)
+	for i := 1; i <= 5; i++ {
+		s.Add(LogEntry{Text: fmt.Sprint(i)})
+	}
+	s.Sync()
+
+	got := entryTexts(s.Snapshot(LogFilter{JobID: -1}))
+	want := []string{"3", "4", "5"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestLogStoreRewrite(t *testing.T) {
+	s := NewLogStore(100)
+	s.Add(LogEntry{JobID: 1, Text: "保存文件: a.mp4"})
+	s.Add(LogEntry{JobID: 1, Text: "下载中: 10%", Rewritable: true})
+	s.Add(LogEntry{JobID: 2, Text: "下载中: 1%", Rewritable: true})
+	s.Add(LogEntry{JobID: 1, Text: "下载中: 50%", Rewritable: true})
+	s.Add(LogEntry{JobID: 2, Text: "下载中: 2%", Rewritable: true})
+	s.Add(LogEntry{JobID: 1, Text: "下载完成"})
+	s.Sync()
+
+	got := entryTexts(s.Snapshot(LogFilter{JobID: -1}))
+	want := []string{"保存文件: a.mp4", "下载中: 50%", "下载中: 2%", "下载完成"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestLogStoreRewriteRawSeparately(t *testing.T) {
+	s := NewLogStore(100)
+	s.Add(LogEntry{JobID: 1, Level: LogRaw, Text: "[download]  10.0% of 1MiB", Rewritable: true})
+	s.Add(LogEntry{JobID: 1, Text: "下载中: 10.0%", Rewritable: true})
+	s.Add(LogEntry{JobID: 1, Level: LogRaw, Text: "[download]  20.0% of 1MiB", Rewritable: true})
+	s.Add(LogEntry{JobID: 1, Text: "下载中: 20.0%", Rewritable: true})
+	s.Sync()
+
+	got := entryTexts(s.Snapshot(LogFilter{JobID: 1, ShowRaw: true}))
+	want := []string{"[download]  20.0% of 1MiB", "下载中: 20.0%"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestLogStoreFilter(t *testing.T) {
+	s := NewLogStore(100)
+	s.Add(LogEntry{Text: "程序启动"})
+	s.Add(LogEntry{JobID: 1, Level: LogRaw, Text: "[youtube] abc: Downloading webpage"})
+	s.Add(LogEntry{JobID: 1, Level: LogWarning, Text: "WARNING: x"})
+	s.Add(LogEntry{JobID: 2, Level: LogError, Text: "ERROR: y"})
+	s.Sync()
+
+	tests := []struct {
+		name   string
+		filter LogFilter
+		want   []string
+	}{
+		{"default", LogFilter{JobID: -1, MinLevel: LogInfo}, []string{"程序启动", "WARNING: x", "ERROR: y"}},
+		{"raw", LogFilter{JobID: -1, MinLevel: LogInfo, ShowRaw: true}, []string{"程序启动", "[youtube] abc: Downloading webpage", "WARNING: x", "ERROR: y"}},
+		{"errors with raw", LogFilter{JobID: -1, MinLevel: LogError, ShowRaw: true}, []string{"[youtube] abc: Downloading webpage", "ERROR: y"}},
+		{"errors", LogFilter{JobID: -1, MinLevel: LogError}, []string{"ERROR: y"}},
+		{"job", LogFilter{JobID: 1, MinLevel: LogRaw}, []string{"[youtube] abc: Downloading webpage", "WARNING: x"}},
+		{"app", LogFilter{JobID: 0, MinLevel: LogInfo}, []string{"程序启动"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := entryTexts(s.Snapshot(tt.filter))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if jobs := s.Jobs(); fmt.Sprint(jobs) != "[1 2]" {
+		t.Fatalf("jobs = %v, want [1 2]", jobs)
+	}
+	if tail := s.Tail(LogFilter{JobID: -1}, 1); len(tail) != 1 || tail[0][len(tail[0])-8:] != "ERROR: y" {
+		t.Fatalf("tail = %v", tail)
+	}
+}
+
+func TestLogStoreOnChange(t *testing.T) {
+	s := NewLogStore(10)
+	var mu sync.Mutex
+	calls := 0
+	s.OnChange(func() {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+	})
+	s.Add(LogEntry{Text: "a"})
+	s.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls == 0 {
+		t.Fatal("写入日志后应触发变更通知")
+	}
+}
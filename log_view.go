@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	nativeDialog "github.com/sqweek/dialog"
+)
+
+// logViewRefreshInterval 为日志列表的最短刷新间隔，避免进度行刷屏拖慢界面。
+const logViewRefreshInterval = 100 * time.Millisecond
+
+var logLevelOptions = []struct {
+	label string
+	level LogLevel
+}{
+	{"信息", LogInfo},
+	{"警告", LogWarning},
+	{"错误", LogError},
+}
+
+// newLogView 创建基于 LogStore 的虚拟化日志列表，带任务和级别筛选，
+// 选中单个任务时可打开或导出该任务的原始日志文件。startedAt 为本次运行的开始时间，用于区分各次运行中编号相同的任务。
+func newLogView(w fyne.Window, store *LogStore, logsDir string, startedAt time.Time) fyne.CanvasObject {
+	filter := LogFilter{JobID: -1, MinLevel: LogInfo}
+	var entries []LogEntry
+	var jobs []int
+
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			if id >= len(entries) {
+				return
+			}
+			e := entries[id]
+			switch e.Level {
+			case LogError:
+				label.Importance = widget.DangerImportance
+			case LogWarning:
+				label.Importance = widget.WarningImportance
+			case LogRaw:
+				label.Importance = widget.LowImportance
+			default:
+				label.Importance = widget.MediumImportance
+			}
+			label.SetText(e.String())
+		},
+	)
+
+	// 列表行不换行，选中后在下方显示完整内容
+	detail := widget.NewLabel("")
+	detail.Wrapping = fyne.TextWrapWord
+	detail.Hide()
+	list.OnSelected = func(id widget.ListItemID) {
+		if id < len(entries) {
+			detail.SetText(entries[id].String())
+			detail.Show()
+		}
+	}
+	list.OnUnselected = func(widget.ListItemID) { detail.Hide() }
+
+	followCheck := widget.NewCheck("自动滚动", nil)
+	followCheck.SetChecked(true)
+
+	jobSelect := widget.NewSelect([]string{"全部任务", "程序"}, nil)
+	jobOptions := func() []string {
+		opts := []string{"全部任务", "程序"}
+		for _, id := range jobs {
+			opts = append(opts, fmt.Sprintf("任务 #%d", id))
+		}
+		return opts
+	}
+
+	refresh := func() {
+		if newJobs := store.Jobs(); !slices.Equal(newJobs, jobs) {
+			jobs = newJobs
+			jobSelect.Options = jobOptions()
+			jobSelect.Refresh()
+		}
+		entries = store.Snapshot(filter)
+		list.Refresh()
+		if followCheck.Checked && len(entries) > 0 {
+			list.ScrollToBottom()
+		}
+	}
+
+	openJobLogBtn := widget.NewButton("打开任务日志", func() {
+		if p, ok := findJobLog(logsDir, filter.JobID, startedAt); ok {
+			if err := openLocalPath(p); err != nil {
+				dialog.ShowError(fmt.Errorf("无法打开任务日志: %v", err), w)
+			}
+		}
+	})
+	exportJobLogBtn := widget.NewButton("导出...", func() {
+		src, ok := findJobLog(logsDir, filter.JobID, startedAt)
+		if !ok {
+			return
+		}
+		dest, err := nativeDialog.File().Title("导出任务日志").Filter("日志文件", "log", "txt").SetStartFile(filepath.Base(src)).Save()
+		if err != nil || dest == "" {
+			return
+		}
+		if err := exportJobLog(src, dest); err != nil {
+			dialog.ShowError(err, w)
+		}
+	})
+	logsDirBtn := widget.NewButton("日志目录", func() {
+		err := os.MkdirAll(logsDir, 0755)
+		if err == nil {
+			err = openLocalPath(logsDir)
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("无法打开日志目录: %v", err), w)
+		}
+	})
+	updateJobLogBtns := func() {
+		if _, ok := findJobLog(logsDir, filter.JobID, startedAt); ok {
+			openJobLogBtn.Enable()
+			exportJobLogBtn.Enable()
+		} else {
+			openJobLogBtn.Disable()
+			exportJobLogBtn.Disable()
+		}
+	}
+
+	jobSelect.OnChanged = func(selected string) {
+		switch selected {
+		case "全部任务":
+			filter.JobID = -1
+		case "程序":
+			filter.JobID = 0
+		default:
+			var id int
+			if _, err := fmt.Sscanf(selected, "任务 #%d", &id); err == nil {
+				filter.JobID = id
+			}
+		}
+		updateJobLogBtns()
+		refresh()
+	}
+	jobSelect.SetSelected("全部任务")
+
+	levelLabels := make([]string, len(logLevelOptions))
+	for i, opt := range logLevelOptions {
+		levelLabels[i] = opt.label
+	}
+	levelSelect := widget.NewSelect(levelLabels, func(selected string) {
+		for _, opt := range logLevelOptions {
+			if opt.label == selected {
+				filter.MinLevel = opt.level
+			}
+		}
+		refresh()
+	})
+	levelSelect.SetSelected("信息")
+
+	rawCheck := widget.NewCheck("显示原始输出", func(b bool) {
+		filter.ShowRaw = b
+		refresh()
+	})
+
+	var pending atomic.Bool
+	store.OnChange(func() {
+		if pending.Swap(true) {
+			return
+		}
+		time.AfterFunc(logViewRefreshInterval, func() {
+			pending.Store(false)
+			fyne.Do(refresh)
+		})
+	})
+
+	toolbar := container.NewVBox(
+		container.NewHBox(widget.NewLabel("任务:"), jobSelect, widget.NewLabel("级别:"), levelSelect),
+		container.NewHBox(rawCheck, followCheck, layout.NewSpacer(), openJobLogBtn, exportJobLogBtn, logsDirBtn),
+	)
+	return container.NewBorder(toolbar, detail, nil, nil, list)
+}
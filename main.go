@@ -1,14 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/url"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -18,15 +17,36 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 	nativeDialog "github.com/sqweek/dialog"
+
+	"yinr.cc/yt-dlp-simpgo/utils"
 )
 
 func main() {
-	// 切换工作目录到可执行文件所在目录，使相对路径和文件对话框行为可预测
-	if exePath, err := os.Executable(); err == nil {
-		if exeDir := filepath.Dir(exePath); exeDir != "" {
-			_ = os.Chdir(exeDir)
+	// 便携模式下配置和数据保存在程序目录，否则保存在用户目录
+	startedAt := time.Now()
+	paths, pathsErr := LocateAppPaths()
+	// 只运行一个程序，避免多个窗口同时写入配置和更新 yt-dlp；已在运行时将网址转发给它
+	// instanceMu 保护 instance，安装程序更新失败时会在后台协程中重新获取单实例锁
+	var instanceMu sync.Mutex
+	var instance *InstanceServer
+	var instanceErr error
+	if pathsErr == nil {
+		instance, instanceErr = AcquireSingleInstance(paths.DataDir, os.Args[1:])
+		if errors.Is(instanceErr, ErrAnotherInstance) {
+			if instanceErr != ErrAnotherInstance {
+				nativeDialog.Message("%s", instanceErr.Error()).Title("视频下载工具").Error()
+			}
+			os.Exit(0)
 		}
 	}
+	defer func() {
+		instanceMu.Lock()
+		instance.Close()
+		instanceMu.Unlock()
+	}()
+	migrated, migrateErr := MigrateLegacyFiles(paths)
+	// 切换工作目录到数据目录，使相对路径和文件对话框行为可预测
+	_ = os.Chdir(paths.DataDir)
 
 	a := app.NewWithID("yinr.cc.yt-dlp-simpgo")
 	a.SetIcon(&fyne.StaticResource{
@@ -39,37 +59,34 @@ func main() {
 	entry := widget.NewEntry()
 	entry.SetPlaceHolder("在此输入视频网址，例如：https://...")
 
-	iniPath := IniFileName
-	defaultOut := filepath.Join(".", "下载")
-	appCfg, ytdlpCfg, err := EnsureDefaults(iniPath, defaultOut)
+	if pathsErr != nil {
+		dialog.ShowError(fmt.Errorf("初始化目录失败: %v", pathsErr), nil)
+		os.Exit(1)
+	}
+	iniPath := paths.IniPath()
+	appCfg, ytdlpCfg, err := EnsureDefaults(paths)
 	if err != nil {
 		dialog.ShowError(fmt.Errorf("初始化配置失败: %v", err), nil)
 		os.Exit(1)
 	}
-	exeDir, _ := os.Getwd()
+	ytDlpPath, found := findYtDlp(paths)
+
+	// cfgMu 保护设置和 yt-dlp 路径，下载队列在后台协程中读取
+	var cfgMu sync.Mutex
+	var queue *DownloadQueue
 
 	outputBinding := binding.NewString()
 	_ = outputBinding.Set(appCfg.OutputDir)
 	linkContainer := container.NewHBox()
 
 	setOutputBtn := widget.NewButton("设置下载目录", func() {
-		startDir := appCfg.OutputDir
-		if !filepath.IsAbs(startDir) {
-			startDir = filepath.Join(exeDir, startDir)
-		}
+		startDir := paths.Resolve(appCfg.OutputDir)
 		p, err := nativeDialog.Directory().Title("选择下载目录").SetStartDir(startDir).Browse()
 		if err != nil || p == "" {
 			return
 		}
-		if rel, rerr := filepath.Rel(exeDir, p); rerr == nil && !strings.HasPrefix(rel, "..") {
-			appCfg.OutputDir = rel
-		} else {
-			appCfg.OutputDir = p
-		}
-		actualPath := appCfg.OutputDir
-		if !filepath.IsAbs(actualPath) {
-			actualPath = filepath.Join(exeDir, actualPath)
-		}
+		appCfg.OutputDir = paths.Rel(p)
+		actualPath := paths.Resolve(appCfg.OutputDir)
 		if err := os.MkdirAll(actualPath, 0755); err != nil {
 			dialog.ShowError(fmt.Errorf("无法创建目录: %v", err), w)
 			return
@@ -84,35 +101,16 @@ func main() {
 		}
 	})
 
-	// 打开文件夹辅助函数
-	openFolder := func(path string, fileURL *url.URL) {
-		if runtime.GOOS == "windows" {
-			if err := exec.Command("explorer", path).Start(); err != nil {
+	// 创建可点击的目录链接按钮
+	makeLink := func(path string) fyne.CanvasObject {
+		return widget.NewButton(path, func() {
+			if err := openLocalPath(path); err != nil {
 				fyne.CurrentApp().SendNotification(&fyne.Notification{
 					Title:   "错误",
 					Content: fmt.Sprintf("无法打开文件夹: %v", err),
 				})
 			}
-		} else if fileURL != nil {
-			if err := fyne.CurrentApp().OpenURL(fileURL); err != nil {
-				fyne.CurrentApp().SendNotification(&fyne.Notification{
-					Title:   "错误",
-					Content: fmt.Sprintf("无法打开URL: %v", err),
-				})
-			}
-		}
-	}
-
-	// 创建可点击的目录链接按钮
-	makeLink := func(path string) fyne.CanvasObject {
-		var fileURL string
-		if runtime.GOOS == "windows" {
-			fileURL = "file:///" + filepath.ToSlash(path)
-		} else {
-			fileURL = "file://" + filepath.ToSlash(path)
-		}
-		u, _ := url.Parse(fileURL)
-		return widget.NewButton(path, func() { openFolder(path, u) })
+		})
 	}
 
 	linkContainer.Add(makeLink(appCfg.OutputDir))
@@ -124,10 +122,8 @@ func main() {
 	}))
 
 	// 日志区域
-	logBinding := binding.NewString()
-	logLabel := widget.NewLabelWithData(logBinding)
-	logLabel.Wrapping = fyne.TextWrapWord
-	logScroll := container.NewVScroll(logLabel)
+	logStore := NewLogStore(logStoreCapacity)
+	logView := newLogView(w, logStore, paths.DataPath(LogsDirName), startedAt)
 	progressLabel := widget.NewLabel("就绪")
 	progressBar := widget.NewProgressBar()
 
@@ -135,11 +131,21 @@ func main() {
 		entry.SetText("")
 	})
 
+	// 下载方案，决定附加的 yt-dlp 参数和下载后操作
+	profileSelect := widget.NewSelect(profileLabels(), nil)
+	profileSelect.SetSelectedIndex(0)
+	selectedProfile := func() DownloadProfile {
+		if i := profileSelect.SelectedIndex(); i >= 0 {
+			return downloadProfiles[i]
+		}
+		return DefaultDownloadProfile()
+	}
+
 	updateBtn := widget.NewButton("更新 yt-dlp", nil)
 	downloadBtn := widget.NewButton("开始下载", nil)
 
-	var runningMu sync.Mutex
-	running := false
+	scheduleBtn := widget.NewButton("定时下载", nil)
+	scheduleBtn.Disable()
 
 	aboutBtn := widget.NewButton("关于", func() {
 		content := widget.NewLabel(fmt.Sprintf("yt-dlp-simpgo %s\n\n"+
@@ -151,10 +157,22 @@ func main() {
 		dialog.ShowCustom("关于", "关闭", scroll, w)
 	})
 
+	diagnosticsBtn := widget.NewButton("诊断", func() {
+		showDiagnosticsDialog(w, DiagnosticsOptions{
+			Paths:    paths,
+			AppCfg:   appCfg,
+			YtdlpCfg: ytdlpCfg,
+			LogLines: logStore.Tail(LogFilter{JobID: -1, MinLevel: LogRaw}, diagnosticsLogLines),
+		})
+	})
+
 	settingsBtn := widget.NewButton("设置", func() {
-		showSettingsDialog(w, appCfg, ytdlpCfg, exeDir, func(newAppCfg *AppConfig, newYtdlpCfg *YTDLPConfig) {
+		showSettingsDialog(w, appCfg, ytdlpCfg, paths, func(newAppCfg *AppConfig, newYtdlpCfg *YTDLPConfig) {
+			cfgMu.Lock()
 			appCfg = newAppCfg
 			ytdlpCfg = newYtdlpCfg
+			cfgMu.Unlock()
+			queue.Wake()
 			if err := outputBinding.Set(appCfg.OutputDir); err != nil {
 				dialog.ShowError(fmt.Errorf("无法更新输出目录: %v", err), w)
 			}
@@ -163,32 +181,18 @@ func main() {
 		})
 	})
 
-	// 日志绑定自动滚动
-	logBinding.AddListener(binding.NewDataListener(func() {
-		logScroll.ScrollToBottom()
-	}))
-
-	addLog := func(text string, rewriteLast bool) {
-		go func() {
-			cur, err := logBinding.Get()
-			if err != nil {
-				return
-			}
-			if cur == "" {
-				_ = logBinding.Set(text)
-			} else if rewriteLast {
-				idx := strings.LastIndex(cur, "\n")
-				if idx == -1 {
-					idx = 0
-				}
-				_ = logBinding.Set(cur[:idx] + "\n" + text)
-			} else {
-				_ = logBinding.Set(cur + "\n" + text)
-			}
-		}()
+	appendLog := func(text string) { logStore.Add(LogEntry{Level: LogInfo, Text: text}) }
+	rewriteLog := func(text string) { logStore.Add(LogEntry{Level: LogInfo, Text: text, Rewritable: true}) }
+	errorLog := func(text string) { logStore.Add(LogEntry{Level: LogError, Text: text}) }
+	if len(migrated) > 0 {
+		appendLog(fmt.Sprintf("已将程序目录中的 %d 个配置和数据文件移到: %s", len(migrated), paths.ConfigDir))
+	}
+	if migrateErr != nil {
+		errorLog(migrateErr.Error())
+	}
+	if instanceErr != nil {
+		errorLog(instanceErr.Error())
 	}
-	appendLog := func(text string) { addLog(text, false) }
-	rewriteLog := func(text string) { addLog(text, true) }
 	setProgress := func(status string, value float64) {
 		fyne.Do(func() {
 			progressLabel.SetText(status)
@@ -209,115 +213,402 @@ func main() {
 		})
 	}
 	reporter := ProgressReporter{
-		AppendLog:   appendLog,
-		RewriteLog:  rewriteLog,
+		Log:         logStore.Add,
 		SetProgress: setProgress,
 		Clear:       clearProgress,
 	}
 
-	go func() {
-		info, err := CheckAppUpdate(Version, appCfg.DownloadProxy)
-		if err != nil || info == nil {
-			return
+	// updateSource 返回检查更新、下载 yt-dlp 时使用的代理和镜像
+	updateSource := func() UpdateSource {
+		cfgMu.Lock()
+		defer cfgMu.Unlock()
+		return UpdateSource{Proxy: appCfg.Proxy.AppRoute(), Mirrors: appCfg.Mirrors}
+	}
+
+	// updatePrefs 返回更新设置；saveUpdatePrefs 修改更新设置中的检查记录和对更新提示的选择并保存
+	updatePrefs := func() UpdateConfig {
+		cfgMu.Lock()
+		defer cfgMu.Unlock()
+		return appCfg.Update
+	}
+	saveUpdatePrefs := func(change func(*UpdateConfig)) {
+		cfgMu.Lock()
+		updated := *appCfg
+		change(&updated.Update)
+		appCfg = &updated
+		cfgMu.Unlock()
+		if err := SaveAppConfig(iniPath, &updated); err != nil {
+			errorLog("无法保存更新设置: " + err.Error())
 		}
+	}
 
-		fyne.Do(func() {
-			appendLog(logMarker("程序更新可用"))
-			appendLog(fmt.Sprintf("程序有新版本: %s -> %s", info.CurrentVersion, info.LatestVersion))
-			dialog.ShowConfirm("程序更新可用",
-				fmt.Sprintf("检测到 yt-dlp-simpgo 新版本 %s（当前 %s），是否立即更新并重启？", info.LatestVersion, info.CurrentVersion),
-				func(ok bool) {
-					if !ok {
-						return
-					}
-					go func() {
-						appendLog(logMarker("开始下载程序更新"))
-						appendLog("正在下载程序更新: " + info.AssetName)
-						setProgress("正在下载程序更新", 0)
-						var lastProgress string
-						var lastPct = -1
-						onProgress := func(received, total int64) {
-							progress, pct := formatProgress("下载程序更新", received, total)
-							if pct != lastPct || progress != lastProgress {
-								lastPct = pct
-								lastProgress = progress
-								setProgress(progress, float64(pct)/100)
-								fyne.Do(func() { rewriteLog(progress) })
-							}
-						}
+	// spaceCheck 检查下载目录所在分区的剩余空间，size 为预计的下载大小
+	spaceCheck := func(size int64) error {
+		cfgMu.Lock()
+		outDir, guard := appCfg.OutputDir, appCfg.DiskGuard
+		cfgMu.Unlock()
+		return checkDiskSpace(utils.DiskFree, paths.Resolve(outDir), guard, size)
+	}
 
-						updatePath, err := DownloadAppUpdate(info, appCfg.DownloadProxy, onProgress)
-						if err != nil {
-							fyne.Do(func() {
-								appendLog(logMarker("程序更新下载失败"))
-								appendLog("程序更新下载失败: " + err.Error())
-								clearProgress()
-								dialog.ShowError(err, w)
-							})
-							return
-						}
+	// 下载队列在后台逐个执行任务，每个任务开始时读取当时的设置
+	queue = NewDownloadQueue(realClock{},
+		func() ScheduleConfig {
+			cfgMu.Lock()
+			defer cfgMu.Unlock()
+			return appCfg.Schedule
+		},
+		func(ctx context.Context, job Job, rateLimit string, ctl JobControl) error {
+			cfgMu.Lock()
+			env := DownloadEnv{
+				YtDlpPath:    ytDlpPath,
+				Paths:        paths,
+				OutputDir:    appCfg.OutputDir,
+				Proxy:        appCfg.Proxy.YtDlpRoute(),
+				AppProxy:     appCfg.Proxy.AppRoute(),
+				Organize:     appCfg.Organize,
+				PostDownload: appCfg.PostDownloadFor(job.Profile),
+			}
+			cfgMu.Unlock()
+			return runDownloadJob(ctx, job, rateLimit, ctl, env, reporter)
+		}, spaceCheck)
+	queueView := newQueueView(w, queue, paths.DataPath(LogsDirName), func() {
+		cfgMu.Lock()
+		outDir := appCfg.OutputDir
+		cfgMu.Unlock()
+		showCleanupDialog(w, paths.Resolve(outDir), paths.DataPath(LogsDirName), func(jobs []Job) {
+			for _, job := range jobs {
+				job = queue.AddJob(job)
+				appendLog(fmt.Sprintf("任务 #%d 已加入队列（继续下载）: %s", job.ID, job.URL))
+			}
+		})
+	})
+	queue.Start()
 
-						if err := InstallAppUpdateAndRestart(updatePath); err != nil {
-							fyne.Do(func() {
-								appendLog(logMarker("程序更新安装失败"))
-								appendLog("程序更新安装失败: " + err.Error())
-								clearProgress()
-								dialog.ShowError(err, w)
-							})
-							return
-						}
+	// 输入网址后获取视频信息和缩略图，缩略图经由 yt-dlp 使用的代理下载
+	previewLoader := NewPreviewLoader(
+		func(ctx context.Context, url string) (*VideoInfo, error) {
+			cfgMu.Lock()
+			p, proxy := ytDlpPath, appCfg.Proxy.YtDlpRoute()
+			cfgMu.Unlock()
+			if p == "" {
+				return nil, fmt.Errorf("未找到 yt-dlp，请先下载 yt-dlp")
+			}
+			return fetchVideoInfo(ctx, p, paths, url, proxy)
+		},
+		func(ctx context.Context, thumbURL string) ([]byte, error) {
+			cfgMu.Lock()
+			proxy := appCfg.Proxy.YtDlpRoute()
+			cfgMu.Unlock()
+			return fetchThumbnail(ctx, appHTTPClient(proxy, 20*time.Second), thumbURL)
+		})
+	// 在预览中为单个视频选择的字幕，加入队列时附加到该任务的参数中
+	subtitleChoices := map[string]SubtitleChoice{}
+	subtitleAction := previewAction{Label: "选择字幕...", Available: (*VideoInfo).HasSubtitles, OnTapped: func(url string, info *VideoInfo) {
+		choice, ok := subtitleChoices[url]
+		if !ok {
+			choice = defaultSubtitleChoice(ytdlpCfg, info)
+		}
+		showSubtitleDialog(w, info, choice, ytdlpCfg.SubFavorites,
+			func(f SubtitleFavorite) ([]SubtitleFavorite, error) {
+				cfgMu.Lock()
+				defer cfgMu.Unlock()
+				updated := *ytdlpCfg
+				updated.SubFavorites = putSubtitleFavorite(ytdlpCfg.SubFavorites, f)
+				if err := SaveYTDLPConf(paths.YTDLPConfPath(), &updated); err != nil {
+					return nil, err
+				}
+				ytdlpCfg = &updated
+				return updated.SubFavorites, nil
+			},
+			func(c SubtitleChoice) {
+				subtitleChoices[url] = c
+				if len(c.Langs) == 0 {
+					appendLog("该视频将不下载字幕")
+				} else {
+					appendLog("该视频将下载字幕: " + strings.Join(c.Langs, ","))
+				}
+			})
+	}}
+	// 在预览中为单个视频选择的片段
+	clipChoices := map[string]ClipChoice{}
+	clipAction := previewAction{Label: "下载片段...", Available: (*VideoInfo).CanClip, OnTapped: func(url string, info *VideoInfo) {
+		showClipDialog(w, info, clipChoices[url], func(c ClipChoice) {
+			if len(c.Ranges) == 0 {
+				delete(clipChoices, url)
+				appendLog("该视频将完整下载")
+				return
+			}
+			clipChoices[url] = c
+			appendLog("该视频将只下载片段: " + c.String())
+		})
+	}}
+	previewCard, onURLChanged := newPreviewCard(previewLoader, subtitleAction, clipAction)
+	entry.OnChanged = onURLChanged
 
-						fyne.Do(func() {
-							appendLog(logMarker("程序更新已安装"))
-							appendLog("正在重启")
-							a.Quit()
-						})
-					}()
-				}, w)
+	// 订阅在后台定期检查，新内容作为普通任务加入下载队列
+	var subscriptionsView fyne.CanvasObject
+	subMgr, err := NewSubscriptionManager(paths.DataPath(SubscriptionsFileName), paths.DataPath(SubscriptionsDirName), realClock{},
+		func(url string) (*FlatPlaylist, error) {
+			cfgMu.Lock()
+			p, proxy := ytDlpPath, appCfg.Proxy.YtDlpRoute()
+			cfgMu.Unlock()
+			if p == "" {
+				return nil, fmt.Errorf("未找到 yt-dlp")
+			}
+			return fetchFlatPlaylist(p, paths, url, proxy)
+		},
+		func(job Job) {
+			job = queue.AddJob(job)
+			appendLog(fmt.Sprintf("任务 #%d 已加入队列（订阅）: %s", job.ID, job.URL))
 		})
-	}()
+	if err != nil {
+		errorLog("无法加载订阅: " + err.Error())
+		subscriptionsView = widget.NewLabel("无法加载订阅: " + err.Error())
+	} else {
+		subscriptionsView = newSubscriptionsView(w, subMgr)
+		subMgr.Start()
+	}
 
-	// 根据 yt-dlp 是否存在设置按钮行为
-	ytDlpPath, found := findYtDlp(exeDir)
-	if found {
-		downloadBtn.SetText("开始下载")
-		downloadBtn.OnTapped = func() {
-			urlStr := strings.TrimSpace(entry.Text)
-			if urlStr == "" {
-				dialog.ShowInformation("提示", "请输入一个有效的网址", w)
-				return
+	// enqueue 将输入框中的网址加入队列，notBefore 为零值时尽快开始
+	enqueue := func(notBefore time.Time) {
+		urlStr := strings.TrimSpace(entry.Text)
+		if urlStr == "" {
+			dialog.ShowInformation("提示", "请输入一个有效的网址", w)
+			return
+		}
+		newJob := Job{URL: urlStr, NotBefore: notBefore, Profile: selectedProfile().Name}
+		if choice, ok := subtitleChoices[urlStr]; ok {
+			newJob.ExtraArgs = choice.Args()
+		}
+		if choice, ok := clipChoices[urlStr]; ok {
+			newJob.ExtraArgs = append(newJob.ExtraArgs, choice.Args()...)
+		} else if p := previewLoader.Cached(urlStr); p != nil {
+			// 只下载片段时无法估算大小
+			newJob.EstimatedSize = p.Info.EstimatedSize()
+		}
+		add := func() {
+			job := queue.AddJob(newJob)
+			if notBefore.IsZero() {
+				appendLog(fmt.Sprintf("任务 #%d 已加入队列: %s", job.ID, urlStr))
+			} else {
+				appendLog(fmt.Sprintf("任务 #%d 将于 %s 开始: %s", job.ID, notBefore.Format("01-02 15:04"), urlStr))
 			}
-			startDownload(ytDlpPath, urlStr, appCfg.OutputDir, exeDir, &runningMu, &running, reporter)
 		}
-		wireUpdateBtn(updateBtn, ytDlpPath, appCfg.DownloadProxy, reporter)
+		if err := spaceCheck(newJob.EstimatedSize); err != nil {
+			dialog.ShowConfirm("磁盘空间不足", err.Error()+"\n任务将在空间足够后才开始，仍要加入队列吗？", func(ok bool) {
+				if ok {
+					add()
+				}
+			}, w)
+			return
+		}
+		add()
+	}
+	scheduleBtn.OnTapped = func() {
+		timeEntry := widget.NewEntry()
+		timeEntry.SetPlaceHolder("HH:MM，例如：02:30")
+		dialog.ShowForm("定时下载", "加入队列", "取消",
+			[]*widget.FormItem{widget.NewFormItem("开始时间", timeEntry)},
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				at, err := nextClockTime(time.Now(), timeEntry.Text)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				enqueue(at)
+			}, w)
+	}
 
-		// 后台检查 yt-dlp 版本
+	// openArgs 处理命令行参数和链接（包括后启动的程序转发的参数）：网址按指定的下载方案加入下载队列，并将窗口置于前台
+	openArgs := func(args []string) {
+		w.Show()
+		w.RequestFocus()
+		reqs, err := parseLaunchArgs(args)
+		if err != nil {
+			errorLog("无法处理启动参数: " + err.Error())
+			dialog.ShowError(err, w)
+		}
+		cfgMu.Lock()
+		ready := ytDlpPath != ""
+		cfgMu.Unlock()
+		if !ready {
+			if len(reqs) > 0 {
+				entry.SetText(reqs[0].URL)
+				appendLog("请先下载 yt-dlp，再开始下载: " + reqs[0].URL)
+			}
+			return
+		}
+		for _, r := range reqs {
+			job := queue.AddJob(Job{URL: r.URL, Profile: r.Profile.Name})
+			appendLog(fmt.Sprintf("任务 #%d 已加入队列（%s）: %s", job.ID, r.Profile.Label, r.URL))
+		}
+	}
+	serveInstance := func() {
+		instanceMu.Lock()
+		s := instance
+		instanceMu.Unlock()
+		s.Serve(func(args []string) { fyne.Do(func() { openArgs(args) }) })
+	}
+
+	// installAppUpdate 下载程序更新，安装后重启
+	installAppUpdate := func(info *AppUpdateInfo) {
 		go func() {
-			current, latest, needUpdate, err := CheckYtDlpUpdate(ytDlpPath, appCfg.DownloadProxy)
+			appendLog(logMarker("开始下载程序更新"))
+			appendLog("正在下载程序更新: " + info.AssetName)
+			setProgress("正在下载程序更新", 0)
+			var lastProgress string
+			var lastPct = -1
+			onProgress := func(received, total int64) {
+				progress, pct := formatProgress("下载程序更新", received, total)
+				if pct != lastPct || progress != lastProgress {
+					lastPct = pct
+					lastProgress = progress
+					setProgress(progress, float64(pct)/100)
+					fyne.Do(func() { rewriteLog(progress) })
+				}
+			}
+
+			updatePath, err := DownloadAppUpdate(info, updateSource(), updatePrefs().AllowUnsigned, onProgress)
 			if err != nil {
-				// 版本检查失败不影响正常使用
+				fyne.Do(func() {
+					errorLog(logMarker("程序更新下载失败"))
+					errorLog("程序更新下载失败: " + err.Error())
+					clearProgress()
+					dialog.ShowError(err, w)
+				})
 				return
 			}
-			if needUpdate {
+
+			appendLog("正在启动新版本")
+			setProgress("正在启动新版本", -1)
+			// 新版本启动时需要获取单实例锁，失败恢复后重新获取
+			instanceMu.Lock()
+			instance.Close()
+			instanceMu.Unlock()
+			if err := InstallAppUpdateAndRestart(updatePath); err != nil {
+				instanceMu.Lock()
+				s, acquireErr := AcquireSingleInstance(paths.DataDir, nil)
+				instance = s
+				instanceMu.Unlock()
 				fyne.Do(func() {
-					appendLog(fmt.Sprintf("yt-dlp 有新版本: %s -> %s", current, latest))
-					dialog.ShowConfirm("更新可用",
-						fmt.Sprintf("检测到 yt-dlp 新版本 %s（当前 %s），是否更新？", latest, current),
-						func(ok bool) {
-							if ok {
-								updateBtn.OnTapped()
-							}
-						}, w)
+					if acquireErr != nil {
+						errorLog(acquireErr.Error())
+					} else {
+						serveInstance()
+					}
+					errorLog(logMarker("程序更新安装失败"))
+					errorLog("程序更新安装失败: " + err.Error())
+					clearProgress()
+					dialog.ShowError(err, w)
 				})
+				return
+			}
+
+			fyne.Do(func() {
+				appendLog(logMarker("程序更新已安装"))
+				appendLog("正在重启")
+				a.Quit()
+			})
+		}()
+	}
+
+	// 按检查间隔在启动时检查程序和 yt-dlp 的更新
+	checkUpdates := updatePrefs().CheckDue(time.Now())
+	recordUpdateCheck := func() {
+		saveUpdatePrefs(func(c *UpdateConfig) { c.LastCheck = time.Now() })
+	}
+
+	if checkUpdates {
+		go func() {
+			prefs := updatePrefs()
+			info, err := CheckAppUpdate(Version, updateSource(), prefs.Prerelease)
+			if err != nil {
+				return
+			}
+			recordUpdateCheck()
+			if info == nil || !prefs.App.ShouldPrompt(info.LatestVersion, time.Now()) {
+				return
 			}
+
+			fyne.Do(func() {
+				appendLog(logMarker("程序更新可用"))
+				appendLog(fmt.Sprintf("程序有新版本: %s -> %s", info.CurrentVersion, info.LatestVersion))
+				message := fmt.Sprintf("检测到 yt-dlp-simpgo 新版本 %s（当前 %s），是否立即更新并重启？", info.LatestVersion, info.CurrentVersion)
+				if info.Prerelease {
+					message = fmt.Sprintf("检测到 yt-dlp-simpgo 预发布版本 %s（当前 %s），是否立即更新并重启？", info.LatestVersion, info.CurrentVersion)
+				}
+				showUpdateDialog(w, "程序更新可用", message, info.ReleaseNotes, prefs.remindDays(), func(c updateChoice) {
+					switch c {
+					case updateChoiceInstall:
+						installAppUpdate(info)
+					case updateChoiceRemind:
+						saveUpdatePrefs(func(u *UpdateConfig) { u.App.RemindAfter = u.RemindAt(time.Now()) })
+					case updateChoiceSkip:
+						saveUpdatePrefs(func(u *UpdateConfig) { u.App.SkipVersion = info.LatestVersion })
+						appendLog("已跳过程序版本 " + info.LatestVersion)
+					}
+				})
+			})
 		}()
+	}
+
+	// 根据 yt-dlp 是否存在设置按钮行为
+	if found {
+		downloadBtn.SetText("开始下载")
+		downloadBtn.OnTapped = func() { enqueue(time.Time{}) }
+		scheduleBtn.Enable()
+		wireUpdateBtn(updateBtn, ytDlpPath, paths.DataDir, updateSource, reporter)
+
+		// 后台检查 yt-dlp 版本
+		if checkUpdates {
+			go func() {
+				prefs := updatePrefs()
+				current, latest, needUpdate, err := CheckYtDlpUpdate(ytDlpPath, updateSource())
+				if err != nil {
+					// 版本检查失败不影响正常使用
+					return
+				}
+				recordUpdateCheck()
+				if !needUpdate {
+					return
+				}
+				if prefs.AutoUpdateYtDlp {
+					fyne.Do(func() {
+						appendLog(fmt.Sprintf("yt-dlp 有新版本: %s -> %s，自动更新", current, latest.TagName))
+						updateBtn.OnTapped()
+					})
+					return
+				}
+				if !prefs.YtDlp.ShouldPrompt(latest.TagName, time.Now()) {
+					return
+				}
+				fyne.Do(func() {
+					appendLog(fmt.Sprintf("yt-dlp 有新版本: %s -> %s", current, latest.TagName))
+					showUpdateDialog(w, "更新可用",
+						fmt.Sprintf("检测到 yt-dlp 新版本 %s（当前 %s），是否更新？", latest.TagName, current),
+						latest.Body, prefs.remindDays(), func(c updateChoice) {
+							switch c {
+							case updateChoiceInstall:
+								updateBtn.OnTapped()
+							case updateChoiceRemind:
+								saveUpdatePrefs(func(u *UpdateConfig) { u.YtDlp.RemindAfter = u.RemindAt(time.Now()) })
+							case updateChoiceSkip:
+								saveUpdatePrefs(func(u *UpdateConfig) { u.YtDlp.SkipVersion = latest.TagName })
+								appendLog("已跳过 yt-dlp 版本 " + latest.TagName)
+							}
+						})
+				})
+			}()
+		}
 	} else {
 		updateBtn.Disable()
 		downloadBtn.SetText("下载 yt-dlp")
 		downloadBtn.OnTapped = func() {
 			appendLog(logMarker("开始下载 yt-dlp"))
-			appendLog("正在下载 yt-dlp 到: " + exeDir)
-			appendLog("")
+			appendLog("正在下载 yt-dlp 到: " + paths.DataDir)
 			setProgress("正在下载 yt-dlp", 0)
 			go func() {
 				var lastProgress string
@@ -336,11 +627,11 @@ func main() {
 					}
 				}
 
-				p, derr := DownloadYtDlpWithProgress(exeDir, appCfg.DownloadProxy, appCfg.YtDlpURL, onProgress)
+				p, derr := DownloadYtDlpWithProgress(paths.DataDir, updateSource(), appCfg.YtDlpURL, onProgress)
 				if derr != nil {
 					fyne.Do(func() {
-						appendLog(logMarker("下载 yt-dlp 失败"))
-						appendLog("下载 yt-dlp 失败: " + derr.Error())
+						errorLog(logMarker("下载 yt-dlp 失败"))
+						errorLog("下载 yt-dlp 失败: " + derr.Error())
 						clearProgress()
 						dialog.ShowError(derr, w)
 					})
@@ -351,26 +642,36 @@ func main() {
 					appendLog(logMarker("yt-dlp 下载完成"))
 					appendLog("已下载: " + p)
 					fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "已完成", Content: "yt-dlp 已下载"})
+					cfgMu.Lock()
+					ytDlpPath = p
+					cfgMu.Unlock()
 					downloadBtn.SetText("开始下载")
-					downloadBtn.OnTapped = func() {
-						urlStr := strings.TrimSpace(entry.Text)
-						if urlStr == "" {
-							dialog.ShowInformation("提示", "请输入一个有效的网址", w)
-							return
-						}
-						startDownload(p, urlStr, appCfg.OutputDir, exeDir, &runningMu, &running, reporter)
-					}
-					wireUpdateBtn(updateBtn, p, appCfg.DownloadProxy, reporter)
+					downloadBtn.OnTapped = func() { enqueue(time.Time{}) }
+					scheduleBtn.Enable()
+					wireUpdateBtn(updateBtn, p, paths.DataDir, updateSource, reporter)
 				})
 			}()
 		}
 	}
 
-	entryRow := container.NewBorder(nil, nil, nil, clearBtn, entry)
-	buttons := container.NewHBox(setOutputBtn, widget.NewLabel("下载目录:"), linkContainer, layout.NewSpacer(), updateBtn, downloadBtn, settingsBtn, aboutBtn)
+	entryRow := container.NewBorder(nil, nil, nil, container.NewHBox(profileSelect, clearBtn), entry)
+	buttons := container.NewHBox(setOutputBtn, widget.NewLabel("下载目录:"), linkContainer, layout.NewSpacer(), updateBtn, downloadBtn, scheduleBtn, settingsBtn, diagnosticsBtn, aboutBtn)
 	progressBox := container.NewVBox(progressLabel, progressBar)
-	top := container.NewVBox(entryRow, buttons, progressBox)
-	content := container.NewBorder(top, nil, nil, nil, logScroll)
+	top := container.NewVBox(entryRow, buttons, previewCard, progressBox)
+	tabs := container.NewAppTabs(
+		container.NewTabItem("日志", logView),
+		container.NewTabItem("下载队列", queueView),
+		container.NewTabItem("订阅", subscriptionsView),
+	)
+	content := container.NewBorder(top, nil, nil, nil, tabs)
 	w.SetContent(container.NewPadded(content))
+	a.Lifecycle().SetOnStarted(func() {
+		// 由程序更新启动时，告知更新程序已正常启动
+		ConfirmAppUpdateStarted()
+		serveInstance()
+		if len(os.Args) > 1 {
+			openArgs(os.Args[1:])
+		}
+	})
 	w.ShowAndRun()
 }
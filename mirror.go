@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// 镜像地址模板中的占位符。{url} 替换为完整的原地址，适用于前缀代理，如 https://ghfast.top/{url}；
+// {path} 替换为原地址的路径和查询参数（以 / 开头），适用于替换域名的镜像，如 https://mirror.example.com{path}。
+const (
+	mirrorURLPlaceholder  = "{url}"
+	mirrorPathPlaceholder = "{path}"
+)
+
+// githubAPIHosts 为 GitHub 发布信息 API 的域名。
+var githubAPIHosts = []string{"api.github.com"}
+
+// githubDownloadHosts 为 GitHub 发布文件可能所在的域名，github.com 的下载地址会跳转到后两者。
+var githubDownloadHosts = []string{"github.com", "objects.githubusercontent.com", "release-assets.githubusercontent.com"}
+
+// MirrorConfig 为访问 GitHub 的镜像设置，用于检查更新、下载程序更新和 yt-dlp。
+type MirrorConfig struct {
+	API       []string // 发布信息 API 的地址模板
+	Download  []string // 发布文件下载地址的模板
+	Preferred bool     // 先尝试镜像，都失败后再直接访问 GitHub
+}
+
+// UpdateSource 为检查和下载更新时使用的代理和镜像。
+type UpdateSource struct {
+	Proxy   ProxyRoute
+	Mirrors MirrorConfig
+}
+
+// APIURLs 返回请求 GitHub API 地址 raw 时依次尝试的地址。
+func (c MirrorConfig) APIURLs(raw string) []string {
+	return c.urls(raw, c.API, githubAPIHosts)
+}
+
+// DownloadURLs 返回下载 GitHub 发布文件 raw 时依次尝试的地址。
+func (c MirrorConfig) DownloadURLs(raw string) []string {
+	return c.urls(raw, c.Download, githubDownloadHosts)
+}
+
+// urls 按模板改写 raw，不是 GitHub 的地址不改写。
+func (c MirrorConfig) urls(raw string, templates, hosts []string) []string {
+	u, err := url.Parse(raw)
+	if err != nil || !slices.Contains(hosts, strings.ToLower(u.Hostname())) {
+		return []string{raw}
+	}
+	var mirrors []string
+	for _, t := range templates {
+		if m := expandMirrorTemplate(t, u); m != raw && !slices.Contains(mirrors, m) {
+			mirrors = append(mirrors, m)
+		}
+	}
+	if c.Preferred {
+		return append(mirrors, raw)
+	}
+	return append([]string{raw}, mirrors...)
+}
+
+func expandMirrorTemplate(tmpl string, u *url.URL) string {
+	return strings.NewReplacer(mirrorURLPlaceholder, u.String(), mirrorPathPlaceholder, u.RequestURI()).Replace(tmpl)
+}
+
+// validateMirrorTemplate 检查镜像地址模板是否包含占位符，以及展开后是否为 http(s) 地址。
+func validateMirrorTemplate(tmpl string) error {
+	if !strings.Contains(tmpl, mirrorURLPlaceholder) && !strings.Contains(tmpl, mirrorPathPlaceholder) {
+		return fmt.Errorf("镜像地址应包含 %s 或 %s: %s", mirrorURLPlaceholder, mirrorPathPlaceholder, tmpl)
+	}
+	u, err := url.Parse(expandMirrorTemplate(tmpl, &url.URL{Scheme: "https", Host: "github.com", Path: "/owner/repo"}))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("镜像地址应以 http:// 或 https:// 开头: %s", tmpl)
+	}
+	return nil
+}
+
+// Validate 检查所有镜像地址模板。
+func (c MirrorConfig) Validate() error {
+	for _, t := range append(slices.Clone(c.API), c.Download...) {
+		if err := validateMirrorTemplate(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseMirrorTemplates 解析以空白或换行分隔的镜像地址模板。
+func parseMirrorTemplates(s string) []string {
+	return strings.Fields(s)
+}
+
+// getWithFallback 依次请求 urls，返回第一个状态为 200 的响应，由调用方关闭。
+func getWithFallback(client *http.Client, urls []string, header http.Header) (*http.Response, error) {
+	var errs []error
+	for _, u := range urls {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u, err))
+			continue
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u, err))
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			errs = append(errs, fmt.Errorf("%s: 服务器返回 %s", u, resp.Status))
+			continue
+		}
+		return resp, nil
+	}
+	return nil, errors.Join(errs...)
+}
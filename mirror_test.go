@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMirrorConfigURLs(t *testing.T) {
+	asset := "https://github.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp.exe"
+	api := "https://api.github.com/repos/yt-dlp/yt-dlp/releases/latest"
+	c := MirrorConfig{
+		API:      []string{"https://gh-api.example.com{path}"},
+		Download: []string{"https://ghfast.top/{url}", "https://mirror.example.com{path}", "https://ghfast.top/{url}"},
+	}
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{
+			name: "download",
+			got:  c.DownloadURLs(asset),
+			want: []string{asset, "https://ghfast.top/" + asset, "https://mirror.example.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp.exe"},
+		},
+		{
+			name: "api",
+			got:  c.APIURLs(api),
+			want: []string{api, "https://gh-api.example.com/repos/yt-dlp/yt-dlp/releases/latest"},
+		},
+		// 下载镜像不用于 API，API 镜像不用于下载
+		{name: "api with download host", got: c.DownloadURLs(api), want: []string{api}},
+		{name: "custom url", got: c.DownloadURLs("https://example.com/yt-dlp"), want: []string{"https://example.com/yt-dlp"}},
+		{
+			name: "preferred",
+			got:  MirrorConfig{API: c.API, Preferred: true}.APIURLs(api + "?per_page=1"),
+			want: []string{"https://gh-api.example.com/repos/yt-dlp/yt-dlp/releases/latest?per_page=1", api + "?per_page=1"},
+		},
+	}
+	for _, tt := range tests {
+		if fmt.Sprint(tt.got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestValidateMirrorTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantErr bool
+	}{
+		{tmpl: "https://ghfast.top/{url}"},
+		{tmpl: "http://10.0.0.1:8080{path}"},
+		{tmpl: "https://ghfast.top/", wantErr: true},
+		{tmpl: "ftp://mirror/{path}", wantErr: true},
+		{tmpl: "{url}", wantErr: false},
+		{tmpl: "{path}", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateMirrorTemplate(tt.tmpl); (err != nil) != tt.wantErr {
+			t.Errorf("validateMirrorTemplate(%q) = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGetWithFallback(t *testing.T) {
+	var down atomic.Int32
+	srvDown := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		down.Add(1)
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer srvDown.Close()
+	srvOK := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("User-Agent"))
+	}))
+	defer srvOK.Close()
+
+	header := http.Header{}
+	header.Set("User-Agent", "yt-dlp-simpgo/test")
+	resp, err := getWithFallback(http.DefaultClient, []string{srvDown.URL, srvOK.URL}, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "yt-dlp-simpgo/test" || down.Load() != 1 {
+		t.Errorf("body = %q, 第一个地址请求 %d 次", body, down.Load())
+	}
+
+	if _, err := getWithFallback(http.DefaultClient, []string{srvDown.URL}, nil); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("全部失败时应返回错误: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"fyne.io/fyne/v2"
+)
+
+// fileURL 将本地路径转换为 file:// URL。
+func fileURL(path string) *url.URL {
+	if runtime.GOOS == "windows" {
+		u, _ := url.Parse("file:///" + filepath.ToSlash(path))
+		return u
+	}
+	u, _ := url.Parse("file://" + filepath.ToSlash(path))
+	return u
+}
+
+// openLocalPath 使用系统默认程序打开本地文件或目录。
+func openLocalPath(path string) error {
+	if runtime.GOOS == "windows" {
+		return exec.Command("explorer", path).Start()
+	}
+	return fyne.CurrentApp().OpenURL(fileURL(path))
+}
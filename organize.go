@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// 整理规则，每条规则对应一级子目录
+const (
+	OrganizeBySite      = "site"
+	OrganizeByUploader  = "uploader"
+	OrganizeByPlaylist  = "playlist"
+	OrganizeByYearMonth = "year_month"
+)
+
+// organizeRules 为可用的整理规则及其界面名称。
+var organizeRules = []struct {
+	Key   string
+	Label string
+}{
+	{OrganizeBySite, "网站"},
+	{OrganizeByUploader, "上传者"},
+	{OrganizeByPlaylist, "播放列表"},
+	{OrganizeByYearMonth, "上传年月"},
+}
+
+// OrganizeConfig 为下载文件的整理设置：按规则依次建立子目录，将完成的文件移入其中。
+type OrganizeConfig struct {
+	Enabled bool
+	Rules   []string
+}
+
+// parseOrganizeRules 解析以逗号分隔的规则列表，忽略未知和重复的规则。
+func parseOrganizeRules(s string) []string {
+	var rules []string
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		known := slices.ContainsFunc(organizeRules, func(o struct{ Key, Label string }) bool { return o.Key == r })
+		if known && !slices.Contains(rules, r) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// organizeFolders 返回文件按规则应放入的各级子目录名，元数据为空的规则被跳过。
+func organizeFolders(rules []string, f DownloadedFile) []string {
+	var folders []string
+	for _, rule := range rules {
+		var name string
+		switch rule {
+		case OrganizeBySite:
+			name = f.Extractor
+		case OrganizeByUploader:
+			name = f.Uploader
+		case OrganizeByPlaylist:
+			name = f.Playlist
+		case OrganizeByYearMonth:
+			if d := f.UploadDate; len(d) >= 6 && isDigits(d[:6]) {
+				name = d[:4] + "-" + d[4:6]
+			}
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			folders = append(folders, sanitizeName(name, maxNameBytes))
+		}
+	}
+	return folders
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// OrganizedPath 返回文件在 root 下按规则整理后的路径。
+func OrganizedPath(root string, rules []string, f DownloadedFile) string {
+	parts := append([]string{root}, organizeFolders(rules, f)...)
+	parts = append(parts, sanitizeFileName(filepath.Base(f.FilePath)))
+	return filepath.Join(parts...)
+}
+
+// maxNameBytes 为单个文件名或目录名的最大字节数。多数文件系统限制为 255 字节，
+// 留出余量给重名时追加的 " (1)" 等后缀。
+const maxNameBytes = 240
+
+var windowsReservedName = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[0-9¹²³]|lpt[0-9¹²³])(\..*)?$`)
+
+// sanitizeName 将 name 处理为在各平台都可用的文件名或目录名：替换 Windows 不允许的字符，
+// 去掉控制字符和末尾的点与空格，避开 CON、NUL 等保留名称，并截断到 limit 字节以内。
+func sanitizeName(name string, limit int) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			return -1
+		case strings.ContainsRune(`<>:"/\|?*`, r):
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	for len(name) > limit {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "_"
+	}
+	if windowsReservedName.MatchString(name) {
+		name = "_" + name
+	}
+	return name
+}
+
+// sanitizeFileName 与 sanitizeName 相同，但截断时保留扩展名。
+func sanitizeFileName(name string) string {
+	ext := filepath.Ext(name)
+	if ext == name || len(ext) > 16 || strings.ContainsAny(ext, "<>:\"/\\|?* ") {
+		ext = ""
+	}
+	return sanitizeName(strings.TrimSuffix(name, ext), maxNameBytes-len(ext)) + ext
+}
+
+// sidecarInfixes 为字幕、缩略图以外与媒体文件同名的附属文件的中缀，如 "a.info.json"。
+var sidecarInfixes = []string{"info", "live_chat", "description"}
+
+// isSidecarOf 判断 name 是否为文件名主干为 stem 的媒体文件的附属文件（字幕、缩略图、信息文件等）。
+func isSidecarOf(name, stem string) bool {
+	if !strings.HasPrefix(name, stem+".") {
+		return false
+	}
+	rest := name[len(stem):]
+	ext := strings.ToLower(filepath.Ext(rest))
+	infix := strings.TrimPrefix(strings.TrimSuffix(rest, filepath.Ext(rest)), ".")
+	switch {
+	case ext == ".description":
+		return infix == ""
+	case ext == ".json":
+		return slices.Contains(sidecarInfixes, infix)
+	case slices.Contains(thumbnailExts, ext):
+		return infix == ""
+	case slices.Contains(subtitleExts, ext):
+		return infix != "" && subLangPattern.FindString("."+infix) == "."+infix
+	}
+	return false
+}
+
+// organizeFile 将 root 中的已完成文件及其附属文件按规则移入子目录，返回新的路径。
+// 不在 root 中的文件（如已被转移到其他目录）和已在目标位置的文件保持不变。
+func organizeFile(root string, rules []string, f DownloadedFile) (string, error) {
+	src := filepath.Clean(f.FilePath)
+	if rel, err := filepath.Rel(root, src); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return src, nil
+	}
+	dest := OrganizedPath(root, rules, f)
+	if dest == src {
+		return src, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return src, fmt.Errorf("无法创建整理目录: %w", err)
+	}
+	dest = uniquePath(dest)
+	if err := os.Rename(src, dest); err != nil {
+		return src, fmt.Errorf("无法整理文件: %w", err)
+	}
+
+	// 附属文件随媒体文件一起移动并改为相同的主干
+	srcStem := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+	destStem := strings.TrimSuffix(filepath.Base(dest), filepath.Ext(dest))
+	var errs []error
+	if entries, err := os.ReadDir(filepath.Dir(src)); err == nil {
+		for _, e := range entries {
+			if e.IsDir() || !isSidecarOf(e.Name(), srcStem) {
+				continue
+			}
+			target := filepath.Join(filepath.Dir(dest), destStem+e.Name()[len(srcStem):])
+			if _, err := os.Stat(target); err == nil {
+				continue
+			}
+			if err := os.Rename(filepath.Join(filepath.Dir(src), e.Name()), target); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	removeEmptyDirs(filepath.Dir(src), root)
+	if len(errs) > 0 {
+		return dest, fmt.Errorf("部分附属文件无法移动: %w", errors.Join(errs...))
+	}
+	return dest, nil
+}
+
+// removeEmptyDirs 删除 dir 及其上级中的空目录，直到 root 为止（不含 root）。
+func removeEmptyDirs(dir, root string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// ReorganizeHistory 按规则整理下载历史中仍位于 root 内的文件，并更新 entries 中的路径。
+// 返回移动的文件数，单个文件失败不影响其他文件。
+func ReorganizeHistory(root string, rules []string, entries []HistoryEntry) (moved int, err error) {
+	root = filepath.Clean(root)
+	renamed := map[string]string{}
+	var errs []error
+	for i, e := range entries {
+		old := filepath.Clean(e.FilePath)
+		if p, ok := renamed[old]; ok {
+			entries[i].FilePath = p
+			continue
+		}
+		if _, serr := os.Stat(old); serr != nil {
+			continue
+		}
+		p, oerr := organizeFile(root, rules, e.DownloadedFile)
+		if oerr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(old), oerr))
+		}
+		if p != old {
+			renamed[old] = p
+			entries[i].FilePath = p
+			moved++
+		}
+	}
+	if len(errs) > 0 {
+		return moved, errors.Join(errs...)
+	}
+	return moved, nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "普通名称", want: "普通名称"},
+		{name: `a/b\c:d*e?f"g<h>i|j`, want: "a_b_c_d_e_f_g_h_i_j"},
+		{name: "tab\there\n", want: "tabhere"},
+		{name: "  结尾的点...  ", want: "结尾的点"},
+		{name: "CON", want: "_CON"},
+		{name: "nul.txt", want: "_nul.txt"},
+		{name: "com1", want: "_com1"},
+		{name: "CONSOLE", want: "CONSOLE"},
+		{name: "...", want: "_"},
+		{name: "", want: "_"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeName(tt.name, maxNameBytes); got != tt.want {
+			t.Errorf("sanitizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	long := strings.Repeat("视频", 100)
+	if got := sanitizeName(long, maxNameBytes); len(got) > maxNameBytes || !strings.HasPrefix(long, got) {
+		t.Errorf("截断后长度 %d，应不超过 %d 且在字符边界截断", len(got), maxNameBytes)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "标题: 副标题.mp4", want: "标题_ 副标题.mp4"},
+		{name: "aux.mkv", want: "_aux.mkv"},
+		{name: "结尾的点..mp4", want: "结尾的点.mp4"},
+		{name: "没有扩展名", want: "没有扩展名"},
+		{name: "v1.0 final version", want: "v1.0 final version"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeFileName(tt.name); got != tt.want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	got := sanitizeFileName(strings.Repeat("长", 200) + ".webm")
+	if len(got) > maxNameBytes || !strings.HasSuffix(got, "长.webm") {
+		t.Errorf("截断时应保留扩展名: %q (%d)", got, len(got))
+	}
+}
+
+func TestParseOrganizeRules(t *testing.T) {
+	got := parseOrganizeRules(" uploader, unknown,site,uploader,,year_month")
+	want := []string{OrganizeByUploader, OrganizeBySite, OrganizeByYearMonth}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("parseOrganizeRules = %v, want %v", got, want)
+	}
+}
+
+func TestOrganizedPath(t *testing.T) {
+	f := DownloadedFile{
+		FilePath:   filepath.Join("out", "标题?.mp4"),
+		Extractor:  "Youtube",
+		Uploader:   "某人 / 频道.",
+		UploadDate: "20240131",
+	}
+	tests := []struct {
+		rules []string
+		want  string
+	}{
+		{rules: nil, want: filepath.Join("root", "标题_.mp4")},
+		{rules: []string{OrganizeBySite, OrganizeByUploader}, want: filepath.Join("root", "Youtube", "某人 _ 频道", "标题_.mp4")},
+		// 不属于播放列表时跳过该级
+		{rules: []string{OrganizeByPlaylist, OrganizeByYearMonth}, want: filepath.Join("root", "2024-01", "标题_.mp4")},
+	}
+	for _, tt := range tests {
+		if got := OrganizedPath("root", tt.rules, f); got != tt.want {
+			t.Errorf("OrganizedPath(%v) = %q, want %q", tt.rules, got, tt.want)
+		}
+	}
+
+	f.UploadDate = "NA"
+	if got := OrganizedPath("root", []string{OrganizeByYearMonth}, f); got != filepath.Join("root", "标题_.mp4") {
+		t.Errorf("上传日期无效时应跳过: %q", got)
+	}
+}
+
+func TestIsSidecarOf(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "a.en.vtt", want: true},
+		{name: "a.zh-Hans.srt", want: true},
+		{name: "a.webp", want: true},
+		{name: "a.info.json", want: true},
+		{name: "a.live_chat.json", want: true},
+		{name: "a.description", want: true},
+		{name: "a.mp4", want: false},
+		{name: "a.vtt", want: false},
+		{name: "a.part2.en.vtt", want: false},
+		{name: "ab.en.vtt", want: false},
+	}
+	for _, tt := range tests {
+		if got := isSidecarOf(tt.name, "a"); got != tt.want {
+			t.Errorf("isSidecarOf(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func writeTestFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOrganizeFile(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "a.mp4", "a.en.vtt", "a.jpg", "a.part2.mp4", "old/b.mp4")
+	rules := []string{OrganizeByUploader}
+
+	p, err := organizeFile(root, rules, DownloadedFile{FilePath: filepath.Join(root, "a.mp4"), Uploader: "up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "up", "a.mp4"); p != want {
+		t.Errorf("path = %q, want %q", p, want)
+	}
+	for _, name := range []string{"up/a.mp4", "up/a.en.vtt", "up/a.jpg", "a.part2.mp4"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("%s 应存在: %v", name, err)
+		}
+	}
+
+	// 目标已存在时改名，原目录为空时删除
+	writeTestFiles(t, root, "up/b.mp4")
+	p, err = organizeFile(root, rules, DownloadedFile{FilePath: filepath.Join(root, "old", "b.mp4"), Uploader: "up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "up", "b (1).mp4"); p != want {
+		t.Errorf("path = %q, want %q", p, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, "old")); !os.IsNotExist(err) {
+		t.Error("空目录应被删除")
+	}
+
+	// 不在下载目录中的文件不处理
+	outside := filepath.Join(t.TempDir(), "c.mp4")
+	writeTestFiles(t, filepath.Dir(outside), "c.mp4")
+	if p, err := organizeFile(root, rules, DownloadedFile{FilePath: outside, Uploader: "up"}); err != nil || p != outside {
+		t.Errorf("organizeFile(outside) = %q, %v", p, err)
+	}
+}
+
+func TestReorganizeHistory(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "a.mp4", "b.mp4")
+	historyPath := filepath.Join(t.TempDir(), HistoryFileName)
+	for _, f := range []DownloadedFile{
+		{FilePath: filepath.Join(root, "a.mp4"), Extractor: "Youtube"},
+		{FilePath: filepath.Join(root, "gone.mp4"), Extractor: "Youtube"},
+		{FilePath: filepath.Join(root, "a.mp4"), Extractor: "Youtube"},
+		{FilePath: filepath.Join(root, "b.mp4"), Extractor: "BiliBili"},
+	} {
+		if err := AppendHistory(historyPath, HistoryEntry{DownloadedFile: f}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var moved int
+	err := UpdateHistory(historyPath, func(entries []HistoryEntry) error {
+		var err error
+		moved, err = ReorganizeHistory(root, []string{OrganizeBySite}, entries)
+		return err
+	})
+	if err != nil || moved != 2 {
+		t.Fatalf("ReorganizeHistory = %d, %v, want 2, nil", moved, err)
+	}
+
+	entries, err := LoadHistory(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(root, "Youtube", "a.mp4"),
+		filepath.Join(root, "gone.mp4"),
+		filepath.Join(root, "Youtube", "a.mp4"),
+		filepath.Join(root, "BiliBili", "b.mp4"),
+	}
+	for i, e := range entries {
+		if e.FilePath != want[i] {
+			t.Errorf("entries[%d].FilePath = %q, want %q", i, e.FilePath, want[i])
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PortableMarkerName 为便携模式的标记文件。程序目录中有此文件时，配置和数据都保存在程序目录中。
+const PortableMarkerName = "portable.txt"
+
+// appDirName 为程序在用户配置和数据目录中使用的子目录名。
+const appDirName = "yt-dlp-simpgo"
+
+// portableDownloadDir 为便携模式下的默认下载目录，相对于程序目录。
+const portableDownloadDir = "下载"
+
+// AppPaths 为程序使用的目录。便携模式下都为程序所在目录；
+// 否则使用各系统的用户目录（Linux 为 XDG 目录，Windows 为 AppData，macOS 为 Application Support），
+// 程序目录可以是只读的（如 /usr/bin、Program Files、AppImage）。
+type AppPaths struct {
+	Portable    bool
+	ExeDir      string // 程序所在目录，也在此查找随程序附带的 yt-dlp 和 ffmpeg
+	ConfigDir   string // yt-dlp-simpgo.ini、yt-dlp.conf 和代理账号文件
+	DataDir     string // 下载的 yt-dlp、历史记录、订阅、日志和下载记录；配置中的相对路径相对于此目录
+	DownloadDir string // 默认下载目录
+}
+
+// IniPath 返回程序配置文件的路径。
+func (p AppPaths) IniPath() string {
+	return filepath.Join(p.ConfigDir, IniFileName)
+}
+
+// YTDLPConfPath 返回 yt-dlp 配置文件的路径。
+func (p AppPaths) YTDLPConfPath() string {
+	return filepath.Join(p.ConfigDir, YTDLPConfName)
+}
+
+// DataPath 返回数据目录中的文件路径。
+func (p AppPaths) DataPath(name string) string {
+	return filepath.Join(p.DataDir, name)
+}
+
+// Resolve 将配置中的相对路径转为绝对路径（相对于数据目录）。
+func (p AppPaths) Resolve(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.DataDir, path)
+}
+
+// Rel 返回保存到配置中的路径：在数据目录中时保存为相对路径，便携模式下整个目录移动后仍可用。
+func (p AppPaths) Rel(path string) string {
+	if rel, err := filepath.Rel(p.DataDir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel
+	}
+	return path
+}
+
+// LocateAppPaths 确定程序使用的目录，并创建配置和数据目录。
+func LocateAppPaths() (AppPaths, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return AppPaths{}, fmt.Errorf("无法定位当前程序: %w", err)
+	}
+	if p, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = p
+	}
+	paths := resolveAppPaths(filepath.Dir(exePath), runtime.GOOS, os.Getenv)
+	for _, dir := range []string{paths.ConfigDir, paths.DataDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return paths, fmt.Errorf("无法创建目录 %s: %w", dir, err)
+		}
+	}
+	return paths, nil
+}
+
+// resolveAppPaths 按便携标记和系统确定程序使用的目录，getenv 用于读取环境变量。
+// 无法确定用户目录时使用便携模式。
+func resolveAppPaths(exeDir, goos string, getenv func(string) string) AppPaths {
+	portable := AppPaths{
+		Portable:    true,
+		ExeDir:      exeDir,
+		ConfigDir:   exeDir,
+		DataDir:     exeDir,
+		DownloadDir: portableDownloadDir,
+	}
+	if _, err := os.Stat(filepath.Join(exeDir, PortableMarkerName)); err == nil {
+		return portable
+	}
+
+	// 只接受绝对路径，与 XDG 规范和 os.UserConfigDir 一致
+	env := func(name, fallback string) string {
+		if v := getenv(name); filepath.IsAbs(v) {
+			return v
+		}
+		return fallback
+	}
+	var home string
+	if goos == "windows" {
+		home = env("USERPROFILE", "")
+	} else {
+		home = env("HOME", "")
+	}
+	if home == "" {
+		return portable
+	}
+
+	paths := AppPaths{ExeDir: exeDir, DownloadDir: filepath.Join(home, "Downloads")}
+	switch goos {
+	case "windows":
+		paths.ConfigDir = filepath.Join(env("APPDATA", filepath.Join(home, "AppData", "Roaming")), appDirName)
+		paths.DataDir = filepath.Join(env("LOCALAPPDATA", filepath.Join(home, "AppData", "Local")), appDirName)
+	case "darwin":
+		paths.ConfigDir = filepath.Join(home, "Library", "Application Support", appDirName)
+		paths.DataDir = paths.ConfigDir
+	default:
+		configHome := env("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+		paths.ConfigDir = filepath.Join(configHome, appDirName)
+		paths.DataDir = filepath.Join(env("XDG_DATA_HOME", filepath.Join(home, ".local", "share")), appDirName)
+		if dir := xdgUserDir(configHome, home, "XDG_DOWNLOAD_DIR"); dir != "" {
+			paths.DownloadDir = dir
+		}
+	}
+	return paths
+}
+
+// xdgUserDir 从 user-dirs.dirs 读取用户目录（如下载目录），未设置时返回空。
+// 文件中的值形如 "$HOME/下载"。
+func xdgUserDir(configHome, home, key string) string {
+	data, err := os.ReadFile(filepath.Join(configHome, "user-dirs.dirs"))
+	if err != nil {
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || name != key {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		if rest, ok := strings.CutPrefix(value, "$HOME"); ok {
+			value = home + rest
+		}
+		// 设为 $HOME 表示未使用该目录
+		if !filepath.IsAbs(value) || filepath.Clean(value) == filepath.Clean(home) {
+			return ""
+		}
+		return filepath.Clean(value)
+	}
+	return ""
+}
+
+// findExecutable 依次在 dirs 和 PATH 中查找程序，name 不含扩展名。
+func findExecutable(name string, dirs ...string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	for _, dir := range dirs {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p, true
+		}
+	}
+	if pathP, err := exec.LookPath(name); err == nil {
+		return pathP, true
+	}
+	return "", false
+}
+
+// MigrateLegacyFiles 将旧版本保存在程序目录中的配置和数据移到当前使用的目录，返回移动的文件。
+// 只在非便携模式、程序目录中有配置文件且新位置没有配置文件时执行。
+// 配置中相对于程序目录的下载目录改为绝对路径，已下载的文件不移动。
+func MigrateLegacyFiles(paths AppPaths) ([]string, error) {
+	legacy := AppPaths{Portable: true, ExeDir: paths.ExeDir, ConfigDir: paths.ExeDir, DataDir: paths.ExeDir}
+	if paths.Portable || paths.ConfigDir == paths.ExeDir {
+		return nil, nil
+	}
+	if _, err := os.Stat(legacy.IniPath()); err != nil {
+		return nil, nil
+	}
+	if _, err := os.Stat(paths.IniPath()); err == nil {
+		return nil, nil
+	}
+
+	var moved []string
+	var errs []error
+	move := func(src, dst string) {
+		if _, err := os.Stat(src); err != nil {
+			return
+		}
+		if _, err := os.Stat(dst); err == nil {
+			return
+		}
+		if err := movePath(src, dst); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		moved = append(moved, dst)
+	}
+
+	for _, name := range []string{IniFileName, YTDLPConfName, ProxyCredentialsFileName} {
+		move(filepath.Join(legacy.ConfigDir, name), filepath.Join(paths.ConfigDir, name))
+	}
+	ytDlpName := "yt-dlp"
+	if runtime.GOOS == "windows" {
+		ytDlpName = "yt-dlp.exe"
+	}
+	for _, name := range []string{HistoryFileName, SubscriptionsFileName, SubscriptionsDirName, LogsDirName, ytDlpName} {
+		move(legacy.DataPath(name), paths.DataPath(name))
+	}
+	// 下载记录的文件名可在 yt-dlp.conf 中设置
+	if ytdlpCfg, err := LoadYTDLPConf(paths.YTDLPConfPath()); err == nil {
+		if archive := ytdlpCfg.DownloadArchive; archive != "" && !filepath.IsAbs(archive) {
+			move(legacy.Resolve(archive), paths.Resolve(archive))
+		}
+	}
+
+	if appCfg, err := LoadAppConfig(paths.IniPath()); err == nil {
+		changed := false
+		if appCfg.OutputDir != "" && !filepath.IsAbs(appCfg.OutputDir) {
+			appCfg.OutputDir = legacy.Resolve(appCfg.OutputDir)
+			changed = true
+		}
+		if appCfg.PostDownload.TransferDir != "" && !filepath.IsAbs(appCfg.PostDownload.TransferDir) {
+			appCfg.PostDownload.TransferDir = legacy.Resolve(appCfg.PostDownload.TransferDir)
+			changed = true
+		}
+		for name, c := range appCfg.ProfilePostDownload {
+			if c.TransferDir != "" && !filepath.IsAbs(c.TransferDir) {
+				c.TransferDir = legacy.Resolve(c.TransferDir)
+				appCfg.ProfilePostDownload[name] = c
+				changed = true
+			}
+		}
+		if changed {
+			if err := SaveAppConfig(paths.IniPath(), appCfg); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return moved, fmt.Errorf("迁移旧版本的文件失败: %w", err)
+	}
+	return moved, nil
+}
+
+// movePath 移动文件或目录。不在同一分区等无法重命名时复制，复制后尝试删除原文件，程序目录只读时保留原文件。
+func movePath(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyPath(src, dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return fmt.Errorf("复制 %s 失败: %w", src, err)
+	}
+	_ = os.RemoveAll(src)
+	return nil
+}
+
+func copyPath(src, dst string) error {
+	st, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if st.IsDir() {
+		if err := os.MkdirAll(dst, st.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := copyPath(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, st.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
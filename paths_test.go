@@ -0,0 +1,196 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveAppPaths(t *testing.T) {
+	home := t.TempDir()
+	exeDir := t.TempDir()
+	portableDir := t.TempDir()
+	writeTestFile(t, filepath.Join(portableDir, PortableMarkerName), "")
+	// user-dirs.dirs 中的下载目录
+	userDirsHome := t.TempDir()
+	os.MkdirAll(filepath.Join(userDirsHome, ".config"), 0755)
+	writeTestFile(t, filepath.Join(userDirsHome, ".config", "user-dirs.dirs"), "# comment\nXDG_DESKTOP_DIR=\"$HOME/桌面\"\nXDG_DOWNLOAD_DIR=\"$HOME/下载\"\n")
+
+	tests := []struct {
+		name   string
+		exeDir string
+		goos   string
+		env    map[string]string
+		want   AppPaths
+	}{
+		{
+			name:   "portable marker",
+			exeDir: portableDir,
+			goos:   "linux",
+			env:    map[string]string{"HOME": home},
+			want:   AppPaths{Portable: true, ExeDir: portableDir, ConfigDir: portableDir, DataDir: portableDir, DownloadDir: "下载"},
+		},
+		{
+			name:   "linux defaults",
+			exeDir: exeDir,
+			goos:   "linux",
+			env:    map[string]string{"HOME": home},
+			want: AppPaths{
+				ExeDir:      exeDir,
+				ConfigDir:   filepath.Join(home, ".config", "yt-dlp-simpgo"),
+				DataDir:     filepath.Join(home, ".local", "share", "yt-dlp-simpgo"),
+				DownloadDir: filepath.Join(home, "Downloads"),
+			},
+		},
+		{
+			name:   "linux xdg",
+			exeDir: exeDir,
+			goos:   "linux",
+			env:    map[string]string{"HOME": home, "XDG_CONFIG_HOME": "/xdg/config", "XDG_DATA_HOME": "/xdg/data"},
+			want: AppPaths{
+				ExeDir:      exeDir,
+				ConfigDir:   filepath.Join("/xdg/config", "yt-dlp-simpgo"),
+				DataDir:     filepath.Join("/xdg/data", "yt-dlp-simpgo"),
+				DownloadDir: filepath.Join(home, "Downloads"),
+			},
+		},
+		{
+			name:   "linux relative xdg ignored",
+			exeDir: exeDir,
+			goos:   "linux",
+			env:    map[string]string{"HOME": home, "XDG_DATA_HOME": "data"},
+			want: AppPaths{
+				ExeDir:      exeDir,
+				ConfigDir:   filepath.Join(home, ".config", "yt-dlp-simpgo"),
+				DataDir:     filepath.Join(home, ".local", "share", "yt-dlp-simpgo"),
+				DownloadDir: filepath.Join(home, "Downloads"),
+			},
+		},
+		{
+			name:   "linux user dirs",
+			exeDir: exeDir,
+			goos:   "linux",
+			env:    map[string]string{"HOME": userDirsHome},
+			want: AppPaths{
+				ExeDir:      exeDir,
+				ConfigDir:   filepath.Join(userDirsHome, ".config", "yt-dlp-simpgo"),
+				DataDir:     filepath.Join(userDirsHome, ".local", "share", "yt-dlp-simpgo"),
+				DownloadDir: filepath.Join(userDirsHome, "下载"),
+			},
+		},
+		{
+			name:   "windows",
+			exeDir: exeDir,
+			goos:   "windows",
+			env:    map[string]string{"USERPROFILE": home, "APPDATA": "/appdata/roaming", "LOCALAPPDATA": "/appdata/local"},
+			want: AppPaths{
+				ExeDir:      exeDir,
+				ConfigDir:   filepath.Join("/appdata/roaming", "yt-dlp-simpgo"),
+				DataDir:     filepath.Join("/appdata/local", "yt-dlp-simpgo"),
+				DownloadDir: filepath.Join(home, "Downloads"),
+			},
+		},
+		{
+			name:   "darwin",
+			exeDir: exeDir,
+			goos:   "darwin",
+			env:    map[string]string{"HOME": home},
+			want: AppPaths{
+				ExeDir:      exeDir,
+				ConfigDir:   filepath.Join(home, "Library", "Application Support", "yt-dlp-simpgo"),
+				DataDir:     filepath.Join(home, "Library", "Application Support", "yt-dlp-simpgo"),
+				DownloadDir: filepath.Join(home, "Downloads"),
+			},
+		},
+		{
+			name:   "no home",
+			exeDir: exeDir,
+			goos:   "linux",
+			want:   AppPaths{Portable: true, ExeDir: exeDir, ConfigDir: exeDir, DataDir: exeDir, DownloadDir: "下载"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveAppPaths(tt.exeDir, tt.goos, func(k string) string { return tt.env[k] })
+			if got != tt.want {
+				t.Errorf("resolveAppPaths =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppPathsResolveRel(t *testing.T) {
+	data := filepath.Join(t.TempDir(), "data")
+	p := AppPaths{DataDir: data}
+	tests := []struct {
+		path     string
+		resolved string
+		rel      string
+	}{
+		{path: "下载", resolved: filepath.Join(data, "下载")},
+		{path: filepath.Join(data, "下载", "音乐"), resolved: filepath.Join(data, "下载", "音乐"), rel: filepath.Join("下载", "音乐")},
+		{path: filepath.Join(data, "..", "other"), resolved: filepath.Join(data, "..", "other"), rel: filepath.Join(data, "..", "other")},
+		{path: filepath.Join(data+"-old", "x"), resolved: filepath.Join(data+"-old", "x"), rel: filepath.Join(data+"-old", "x")},
+	}
+	for _, tt := range tests {
+		if got := p.Resolve(tt.path); got != tt.resolved {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.path, got, tt.resolved)
+		}
+		if filepath.IsAbs(tt.path) {
+			if got := p.Rel(tt.path); got != tt.rel {
+				t.Errorf("Rel(%q) = %q, want %q", tt.path, got, tt.rel)
+			}
+		}
+	}
+}
+
+func TestMigrateLegacyFiles(t *testing.T) {
+	exeDir := t.TempDir()
+	root := t.TempDir()
+	paths := AppPaths{ExeDir: exeDir, ConfigDir: filepath.Join(root, "config"), DataDir: filepath.Join(root, "data"), DownloadDir: filepath.Join(root, "Downloads")}
+
+	writeTestFile(t, filepath.Join(exeDir, IniFileName), "[app]\noutput_dir=下载\n")
+	writeTestFile(t, filepath.Join(exeDir, YTDLPConfName), "--download-archive my-archive.txt\n")
+	writeTestFile(t, filepath.Join(exeDir, HistoryFileName), "{}\n")
+	writeTestFile(t, filepath.Join(exeDir, "my-archive.txt"), "youtube abc\n")
+	os.MkdirAll(filepath.Join(exeDir, SubscriptionsDirName), 0755)
+	writeTestFile(t, filepath.Join(exeDir, SubscriptionsDirName, "1.txt"), "archive")
+
+	moved, err := MigrateLegacyFiles(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 5 {
+		t.Errorf("移动了 %d 个文件: %q", len(moved), moved)
+	}
+	for _, p := range []string{
+		paths.IniPath(),
+		paths.YTDLPConfPath(),
+		paths.DataPath(HistoryFileName),
+		paths.DataPath("my-archive.txt"),
+		paths.DataPath(filepath.Join(SubscriptionsDirName, "1.txt")),
+	} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s 应存在: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(exeDir, IniFileName)); !os.IsNotExist(err) {
+		t.Error("程序目录中的配置文件应被移走")
+	}
+	// 相对于程序目录的下载目录改为绝对路径，已下载的文件仍在原位置
+	cfg, err := LoadAppConfig(paths.IniPath())
+	if err != nil || cfg.OutputDir != filepath.Join(exeDir, "下载") {
+		t.Errorf("OutputDir = %q, %v", cfg.OutputDir, err)
+	}
+
+	// 新位置已有配置时不再迁移
+	writeTestFile(t, filepath.Join(exeDir, IniFileName), "[app]\noutput_dir=other\n")
+	if moved, err := MigrateLegacyFiles(paths); err != nil || len(moved) != 0 {
+		t.Errorf("不应重复迁移: %q, %v", moved, err)
+	}
+	// 便携模式不迁移
+	portable := AppPaths{Portable: true, ExeDir: exeDir, ConfigDir: exeDir, DataDir: exeDir}
+	if moved, err := MigrateLegacyFiles(portable); err != nil || len(moved) != 0 {
+		t.Errorf("便携模式不应迁移: %q, %v", moved, err)
+	}
+}
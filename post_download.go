@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"yinr.cc/yt-dlp-simpgo/utils"
+)
+
+// afterMovePrefix 标记 yt-dlp 在文件移动到最终位置后打印的信息行。
+const afterMovePrefix = "[simpgo:after_move] "
+
+// afterMovePrintArgs 让 yt-dlp 在每个文件完成后打印其元数据（JSON）。
+// --print 默认隐含 --quiet，需用 --no-quiet 保留正常的进度输出。
+var afterMovePrintArgs = []string{
+	"--print", "after_move:" + afterMovePrefix + "%(.{id,title,webpage_url,filepath,extractor_key,uploader,upload_date,playlist_title})j",
+	"--no-quiet",
+}
+
+// 文件转移方式
+const (
+	TransferNone = ""
+	TransferMove = "move"
+	TransferCopy = "copy"
+)
+
+// PostDownloadConfig 为下载完成后执行的操作。
+type PostDownloadConfig struct {
+	OpenFolder   bool
+	TransferMode string // TransferNone, TransferMove, TransferCopy
+	TransferDir  string
+	Command      string // 支持占位符 {file} {dir} {title} {url} {id}
+	WebhookURL   string
+}
+
+// Validate 检查下载后操作的设置。
+func (c PostDownloadConfig) Validate() error {
+	if c.TransferMode != TransferNone && strings.TrimSpace(c.TransferDir) == "" {
+		return fmt.Errorf("移动或复制文件时必须设置目标目录")
+	}
+	if webhook := strings.TrimSpace(c.WebhookURL); webhook != "" {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Webhook 地址必须是 http(s) 网址")
+		}
+	}
+	if _, err := splitCommandLine(c.Command); err != nil {
+		return fmt.Errorf("自定义命令无效: %v", err)
+	}
+	return nil
+}
+
+// DownloadedFile 为 yt-dlp 通过 after_move 打印的单个文件信息。
+type DownloadedFile struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	URL        string `json:"webpage_url"`
+	FilePath   string `json:"filepath"`
+	Extractor  string `json:"extractor_key"`
+	Uploader   string `json:"uploader"`
+	UploadDate string `json:"upload_date"`
+	Playlist   string `json:"playlist_title"`
+}
+
+// parseAfterMoveLine 解析 afterMovePrintArgs 产生的输出行。
+func parseAfterMoveLine(line string) (*DownloadedFile, bool) {
+	if !strings.HasPrefix(line, afterMovePrefix) {
+		return nil, false
+	}
+	var f DownloadedFile
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, afterMovePrefix)), &f); err != nil {
+		return nil, false
+	}
+	if f.FilePath == "" {
+		return nil, false
+	}
+	return &f, true
+}
+
+// webhookPayload 为 Webhook 请求的 JSON 内容。
+type webhookPayload struct {
+	Event     string `json:"event"`
+	JobID     int    `json:"job_id"`
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	File      string `json:"file"`
+	Extractor string `json:"extractor"`
+	Uploader  string `json:"uploader"`
+	Time      string `json:"time"`
+}
+
+// PostDownloadResult 为对单个文件执行下载后操作的结果。
+type PostDownloadResult struct {
+	FilePath string  // 执行转移后的最终路径
+	Output   string  // 自定义命令的输出
+	Errors   []error // 各操作的错误，互不影响
+}
+
+// runPostDownloadActions 依次执行文件转移、自定义命令和 Webhook。
+// 打开文件夹由调用方在任务结束后统一处理。
+func runPostDownloadActions(cfg PostDownloadConfig, jobID int, f *DownloadedFile, client *http.Client) PostDownloadResult {
+	res := PostDownloadResult{FilePath: f.FilePath}
+
+	if cfg.TransferMode != TransferNone && cfg.TransferDir != "" {
+		dest, err := transferFile(f.FilePath, cfg.TransferDir, cfg.TransferMode == TransferMove)
+		if err != nil {
+			res.Errors = append(res.Errors, err)
+		} else if cfg.TransferMode == TransferMove {
+			res.FilePath = dest
+		}
+	}
+
+	if strings.TrimSpace(cfg.Command) != "" {
+		out, err := runPostDownloadCommand(cfg.Command, f, res.FilePath)
+		res.Output = out
+		if err != nil {
+			res.Errors = append(res.Errors, err)
+		}
+	}
+
+	if cfg.WebhookURL != "" {
+		if err := callWebhook(client, cfg.WebhookURL, jobID, f, res.FilePath); err != nil {
+			res.Errors = append(res.Errors, err)
+		}
+	}
+
+	return res
+}
+
+// transferFile 将 src 移动或复制到 dir 中，目标已存在时自动改名。
+func transferFile(src, dir string, move bool) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("无法创建目标目录: %w", err)
+	}
+	dest := uniquePath(filepath.Join(dir, filepath.Base(src)))
+	if move {
+		if err := os.Rename(src, dest); err == nil {
+			return dest, nil
+		}
+		// 跨分区时无法直接重命名，退回复制后删除
+	}
+	if err := copyFile(src, dest); err != nil {
+		_ = os.Remove(dest)
+		return "", fmt.Errorf("无法复制文件: %w", err)
+	}
+	if move {
+		if err := os.Remove(src); err != nil {
+			return dest, fmt.Errorf("已复制但无法删除原文件: %w", err)
+		}
+	}
+	return dest, nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// uniquePath 在 p 已存在时追加 " (1)"、" (2)" 等后缀。
+func uniquePath(p string) string {
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return p
+	}
+	ext := filepath.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// expandCommand 将命令模板拆分为参数并替换占位符。
+// 先拆分再替换，文件名中的空格和引号不会破坏参数边界。
+func expandCommand(template string, f *DownloadedFile, filePath string) ([]string, error) {
+	args, err := splitCommandLine(template)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("命令为空")
+	}
+	r := strings.NewReplacer(
+		"{file}", filePath,
+		"{dir}", filepath.Dir(filePath),
+		"{title}", f.Title,
+		"{url}", f.URL,
+		"{id}", f.ID,
+	)
+	for i, arg := range args {
+		args[i] = r.Replace(arg)
+	}
+	return args, nil
+}
+
+// quoteCommandLine 将参数合并为命令行，可由 splitCommandLine 还原。
+// 含空白或引号的参数用单引号括起，本身含单引号时改用双引号。
+func quoteCommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg != "" && !strings.ContainsAny(arg, " \t\r\n'\""):
+			quoted[i] = arg
+		case !strings.Contains(arg, "'"):
+			quoted[i] = "'" + arg + "'"
+		default:
+			quoted[i] = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// splitCommandLine 按空白拆分命令行，支持单引号和双引号。
+// 双引号内仅 \" 视为转义，Windows 路径中的反斜杠保持原样。
+func splitCommandLine(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '"' && r == '\\' && i+1 < len(runes) && runes[i+1] == '"':
+			cur.WriteRune('"')
+			i++
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("命令中的引号未闭合")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+func runPostDownloadCommand(template string, f *DownloadedFile, filePath string) (string, error) {
+	args, err := expandCommand(template, f, filePath)
+	if err != nil {
+		return "", fmt.Errorf("自定义命令无效: %w", err)
+	}
+	cmd := utils.ExecCmd(args[0], args[1:]...)
+	cmd.Dir = filepath.Dir(filePath)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("自定义命令执行失败: %w", err)
+	}
+	return string(out), nil
+}
+
+func callWebhook(client *http.Client, webhookURL string, jobID int, f *DownloadedFile, filePath string) error {
+	body, err := json.Marshal(webhookPayload{
+		Event:     "download_finished",
+		JobID:     jobID,
+		ID:        f.ID,
+		Title:     f.Title,
+		URL:       f.URL,
+		File:      filePath,
+		Extractor: f.Extractor,
+		Uploader:  f.Uploader,
+		Time:      time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("生成 Webhook 内容失败: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建 Webhook 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "yt-dlp-simpgo/"+Version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("调用 Webhook 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("Webhook 返回错误: %s", resp.Status)
+	}
+	return nil
+}
+
+// runJobPostDownload 对任务中已完成的文件执行下载后操作并报告结果。
+// 操作失败不影响下载本身的结果，单独记录日志和通知。Webhook 经由程序使用的代理 proxy 调用。
+func runJobPostDownload(cfg PostDownloadConfig, proxy ProxyRoute, files []*DownloadedFile, reporter ProgressReporter) {
+	if len(files) == 0 {
+		return
+	}
+	client := appHTTPClient(proxy, 30*time.Second)
+	var failed int
+	var lastPath string
+	for _, f := range files {
+		res := runPostDownloadActions(cfg, reporter.JobID, f, client)
+		lastPath = res.FilePath
+		if res.FilePath != f.FilePath {
+			reporter.appendLog("文件已移动到: " + res.FilePath)
+		}
+		if out := strings.TrimSpace(res.Output); out != "" {
+			reporter.appendLog(out)
+		}
+		for _, err := range res.Errors {
+			failed++
+			reporter.log(LogWarning, "后续操作失败: "+err.Error(), false)
+		}
+	}
+	if cfg.OpenFolder && lastPath != "" {
+		if err := openLocalPath(filepath.Dir(lastPath)); err != nil {
+			failed++
+			reporter.log(LogWarning, "后续操作失败: 无法打开文件夹: "+err.Error(), false)
+		}
+	}
+	if failed > 0 {
+		fyne.CurrentApp().SendNotification(&fyne.Notification{
+			Title:   "后续操作失败",
+			Content: fmt.Sprintf("%d 项下载后操作失败，详见日志", failed),
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAfterMoveLine(t *testing.T) {
+	line := afterMovePrefix + `{"id": "abc", "title": "\u6d4b\u8bd5 video", "webpage_url": "https://example.com/v/abc", "filepath": "/tmp/\u6d4b\u8bd5 video.mp4", "extractor_key": "Youtube", "uploader": null}`
+	f, ok := parseAfterMoveLine(line)
+	if !ok {
+		t.Fatal("应能解析 after_move 输出")
+	}
+	if f.ID != "abc" || f.Title != "测试 video" || f.FilePath != "/tmp/测试 video.mp4" || f.Extractor != "Youtube" || f.Uploader != "" {
+		t.Fatalf("解析结果不正确: %+v", f)
+	}
+
+	for _, bad := range []string{
+		"[download] Destination: a.mp4",
+		afterMovePrefix + "not json",
+		afterMovePrefix + `{"id": "abc"}`,
+	} {
+		if _, ok := parseAfterMoveLine(bad); ok {
+			t.Errorf("不应解析 %q", bad)
+		}
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{`notify-send done`, []string{"notify-send", "done"}, false},
+		{`cp "{file}" '/mnt/my videos'`, []string{"cp", "{file}", "/mnt/my videos"}, false},
+		{`"C:\Program Files\tool.exe" {file}`, []string{`C:\Program Files\tool.exe`, "{file}"}, false},
+		{`echo "say \"hi\""`, []string{"echo", `say "hi"`}, false},
+		{`echo ""`, []string{"echo", ""}, false},
+		{`echo "unterminated`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := splitCommandLine(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("splitCommandLine(%q) err = %v", tt.in, err)
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("splitCommandLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteCommandLine(t *testing.T) {
+	args := []string{"-f", "bv*+ba", "--add-header", "Referer: https://example.com", `C:\my dir\`, "it's", `say "hi"`, ""}
+	line := quoteCommandLine(args)
+	got, err := splitCommandLine(line)
+	if err != nil || !reflect.DeepEqual(got, args) {
+		t.Fatalf("splitCommandLine(%q) = %q, %v, want %q", line, got, err, args)
+	}
+	if line := quoteCommandLine([]string{"-f", "best"}); line != "-f best" {
+		t.Errorf("quoteCommandLine = %q, want -f best", line)
+	}
+}
+
+func TestExpandCommand(t *testing.T) {
+	f := &DownloadedFile{ID: "abc", Title: `a "quoted" title`, URL: "https://example.com/abc"}
+	got, err := expandCommand(`notify {title} --file={file} {url} {id}`, f, "/dl/my file.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"notify", `a "quoted" title`, "--file=/dl/my file.mp4", "https://example.com/abc", "abc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expandCommand = %q, want %q", got, want)
+	}
+}
+
+func TestTransferFile(t *testing.T) {
+	src := t.TempDir()
+	dest := filepath.Join(t.TempDir(), "nested")
+
+	write := func(name string) string {
+		p := filepath.Join(src, name)
+		if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	copied, err := transferFile(write("a.mp4"), dest, false)
+	if err != nil {
+		t.Fatalf("复制失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "a.mp4")); err != nil {
+		t.Fatal("复制后原文件应保留")
+	}
+	if copied != filepath.Join(dest, "a.mp4") {
+		t.Fatalf("复制目标 = %s", copied)
+	}
+
+	moved, err := transferFile(filepath.Join(src, "a.mp4"), dest, true)
+	if err != nil {
+		t.Fatalf("移动失败: %v", err)
+	}
+	if moved != filepath.Join(dest, "a (1).mp4") {
+		t.Fatalf("重名时应自动改名，实际为 %s", moved)
+	}
+	if _, err := os.Stat(filepath.Join(src, "a.mp4")); !os.IsNotExist(err) {
+		t.Fatal("移动后原文件应被删除")
+	}
+}
+
+func TestRunPostDownloadActions(t *testing.T) {
+	var got webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode webhook payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "v.mp4")
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "done")
+	f := &DownloadedFile{ID: "abc", Title: "标题", URL: "https://example.com/abc", FilePath: file}
+	cfg := PostDownloadConfig{TransferMode: TransferMove, TransferDir: target, WebhookURL: server.URL}
+
+	res := runPostDownloadActions(cfg, 7, f, &http.Client{Timeout: 5 * time.Second})
+	if len(res.Errors) != 0 {
+		t.Fatalf("不应有错误: %v", res.Errors)
+	}
+	if res.FilePath != filepath.Join(target, "v.mp4") {
+		t.Fatalf("最终路径 = %s", res.FilePath)
+	}
+	if got.Event != "download_finished" || got.JobID != 7 || got.File != res.FilePath || got.Title != "标题" {
+		t.Fatalf("Webhook 内容不正确: %+v", got)
+	}
+}
+
+func TestRunPostDownloadActions_ReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	f := &DownloadedFile{FilePath: filepath.Join(t.TempDir(), "v.mp4")}
+	cfg := PostDownloadConfig{
+		Command:    "yt-dlp-simpgo-command-that-does-not-exist {file}",
+		WebhookURL: server.URL,
+	}
+	res := runPostDownloadActions(cfg, 1, f, &http.Client{Timeout: 5 * time.Second})
+	if len(res.Errors) != 2 {
+		t.Fatalf("命令和 Webhook 的失败应分别报告，实际: %v", res.Errors)
+	}
+}
+
+func TestPostDownloadConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     PostDownloadConfig
+		wantErr bool
+	}{
+		{"empty", PostDownloadConfig{}, false},
+		{"move", PostDownloadConfig{TransferMode: TransferMove, TransferDir: "/mnt/nas"}, false},
+		{"move without dir", PostDownloadConfig{TransferMode: TransferCopy, TransferDir: " "}, true},
+		{"webhook", PostDownloadConfig{WebhookURL: "https://example.com/hook"}, false},
+		{"bad webhook", PostDownloadConfig{WebhookURL: "ftp://example.com"}, true},
+		{"bad command", PostDownloadConfig{Command: `echo "{file}`}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
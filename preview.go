@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"yinr.cc/yt-dlp-simpgo/utils"
+)
+
+// previewCacheSize 为缓存的预览数量上限，超出时丢弃最早的。
+const previewCacheSize = 50
+
+// thumbnailMaxSize 为缩略图的最大字节数，避免异常响应占用过多内存。
+const thumbnailMaxSize = 5 << 20
+
+// VideoInfo 为 yt-dlp -J 输出中预览需要的字段。
+type VideoInfo struct {
+	Type             string         `json:"_type"`
+	ID               string         `json:"id"`
+	Title            string         `json:"title"`
+	Uploader         string         `json:"uploader"`
+	Channel          string         `json:"channel"`
+	Duration         float64        `json:"duration"`
+	UploadDate       string         `json:"upload_date"`
+	Thumbnail        string         `json:"thumbnail"`
+	Thumbnails       []VideoThumb   `json:"thumbnails"`
+	Filesize         int64          `json:"filesize"`
+	FilesizeApprox   int64          `json:"filesize_approx"`
+	RequestedFormats []VideoFormat  `json:"requested_formats"`
+	PlaylistCount    int            `json:"playlist_count"`
+	Entries          []FlatEntry    `json:"entries"`
+	Chapters         []VideoChapter `json:"chapters"`
+
+	Subtitles         map[string][]SubtitleTrack `json:"subtitles"`
+	AutomaticCaptions map[string][]SubtitleTrack `json:"automatic_captions"`
+}
+
+// VideoThumb 为候选缩略图。
+type VideoThumb struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// VideoFormat 为按当前配置选中的格式。
+type VideoFormat struct {
+	Filesize       int64 `json:"filesize"`
+	FilesizeApprox int64 `json:"filesize_approx"`
+}
+
+func (f VideoFormat) size() int64 {
+	if f.Filesize > 0 {
+		return f.Filesize
+	}
+	return f.FilesizeApprox
+}
+
+// IsPlaylist 表示地址为播放列表或频道。
+func (v *VideoInfo) IsPlaylist() bool {
+	return v.Type == "playlist" || v.Type == "multi_video"
+}
+
+// UploaderName 返回上传者名称，缺失时使用频道名。
+func (v *VideoInfo) UploaderName() string {
+	if v.Uploader != "" {
+		return v.Uploader
+	}
+	return v.Channel
+}
+
+// EstimatedSize 返回按当前格式选择估算的文件大小，未知时返回 0。
+func (v *VideoInfo) EstimatedSize() int64 {
+	if len(v.RequestedFormats) > 0 {
+		var total int64
+		for _, f := range v.RequestedFormats {
+			s := f.size()
+			if s <= 0 {
+				return 0
+			}
+			total += s
+		}
+		return total
+	}
+	return VideoFormat{Filesize: v.Filesize, FilesizeApprox: v.FilesizeApprox}.size()
+}
+
+// ThumbnailURL 返回主缩略图地址，缺失时使用候选列表中的最后一个（通常分辨率最高）。
+func (v *VideoInfo) ThumbnailURL() string {
+	if v.Thumbnail != "" {
+		return v.Thumbnail
+	}
+	for i := len(v.Thumbnails) - 1; i >= 0; i-- {
+		if v.Thumbnails[i].URL != "" {
+			return v.Thumbnails[i].URL
+		}
+	}
+	return ""
+}
+
+// Summary 返回上传者、时长、上传日期和估算大小组成的说明，缺失的项省略。
+func (v *VideoInfo) Summary() string {
+	var parts []string
+	if name := v.UploaderName(); name != "" {
+		parts = append(parts, "上传者: "+name)
+	}
+	if v.IsPlaylist() {
+		count := v.PlaylistCount
+		if count == 0 {
+			count = len(v.Entries)
+		}
+		parts = append(parts, fmt.Sprintf("播放列表，共 %d 项", count))
+	}
+	if v.Duration > 0 {
+		parts = append(parts, "时长: "+formatDuration(v.Duration))
+	}
+	if t, err := time.Parse("20060102", v.UploadDate); err == nil {
+		parts = append(parts, "上传于: "+t.Format("2006-01-02"))
+	}
+	if size := v.EstimatedSize(); size > 0 {
+		parts = append(parts, "预计大小: "+formatBytes(size))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// formatDuration 将秒数格式化为 m:ss 或 h:mm:ss。
+func formatDuration(seconds float64) string {
+	s := int(seconds + 0.5)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// fetchVideoInfo 使用 yt-dlp 获取视频信息，播放列表只获取条目列表，ctx 取消时结束进程。
+// proxy 为 yt-dlp 使用的代理。
+func fetchVideoInfo(ctx context.Context, ytDlpPath string, paths AppPaths, url string, proxy ProxyRoute) (*VideoInfo, error) {
+	args := []string{"-J", "--flat-playlist", "--no-warnings", "--config-location", paths.YTDLPConfPath()}
+	args = append(append(args, ytDlpProxyArgs(proxy)...), url)
+	cmd := utils.ExecCmd(ytDlpPath, args...)
+	cmd.Dir = paths.DataDir
+	applyProxyEnv(cmd, proxy)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("获取视频信息失败: %w", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("获取视频信息失败: %s", msg)
+		}
+		return nil, fmt.Errorf("获取视频信息失败: %w", err)
+	}
+	var info VideoInfo
+	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+		return nil, fmt.Errorf("无法解析视频信息: %w", err)
+	}
+	return &info, nil
+}
+
+// fetchThumbnail 下载缩略图。
+func fetchThumbnail(ctx context.Context, client *http.Client, thumbURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, thumbURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("缩略图地址无效: %w", err)
+	}
+	req.Header.Set("User-Agent", "yt-dlp-simpgo/"+Version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载缩略图失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载缩略图失败: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, thumbnailMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("下载缩略图失败: %w", err)
+	}
+	if len(data) > thumbnailMaxSize {
+		return nil, errors.New("缩略图过大")
+	}
+	return data, nil
+}
+
+// VideoPreview 为一个地址的预览信息，缩略图获取失败时 Thumbnail 为空。
+type VideoPreview struct {
+	Info      *VideoInfo
+	Thumbnail []byte
+}
+
+// PreviewLoader 获取并按地址缓存预览信息。
+type PreviewLoader struct {
+	fetchInfo  func(ctx context.Context, url string) (*VideoInfo, error)
+	fetchThumb func(ctx context.Context, thumbURL string) ([]byte, error)
+
+	mu    sync.Mutex
+	items map[string]*VideoPreview
+	order []string
+}
+
+// NewPreviewLoader 创建预览加载器。
+func NewPreviewLoader(fetchInfo func(ctx context.Context, url string) (*VideoInfo, error),
+	fetchThumb func(ctx context.Context, thumbURL string) ([]byte, error)) *PreviewLoader {
+	return &PreviewLoader{
+		fetchInfo:  fetchInfo,
+		fetchThumb: fetchThumb,
+		items:      map[string]*VideoPreview{},
+	}
+}
+
+// Cached 返回已缓存的预览信息，没有时返回 nil。
+func (l *PreviewLoader) Cached(url string) *VideoPreview {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.items[url]
+}
+
+// Load 返回地址的预览信息，已缓存时直接返回。获取失败的结果不缓存。
+func (l *PreviewLoader) Load(ctx context.Context, url string) (*VideoPreview, error) {
+	l.mu.Lock()
+	if p, ok := l.items[url]; ok {
+		l.mu.Unlock()
+		return p, nil
+	}
+	l.mu.Unlock()
+
+	info, err := l.fetchInfo(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	p := &VideoPreview{Info: info}
+	if thumbURL := info.ThumbnailURL(); thumbURL != "" && l.fetchThumb != nil {
+		// 缩略图获取失败仍显示文字信息
+		p.Thumbnail, _ = l.fetchThumb(ctx, thumbURL)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.items[url]; !ok {
+		l.order = append(l.order, url)
+		if len(l.order) > previewCacheSize {
+			delete(l.items, l.order[0])
+			l.order = l.order[1:]
+		}
+	}
+	l.items[url] = p
+	return p, nil
+}
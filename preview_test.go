@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVideoInfoSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+		size int64
+	}{
+		{
+			name: "merged formats",
+			json: `{"_type":"video","title":"t","uploader":"up","duration":3725.4,"upload_date":"20240102",
+				"requested_formats":[{"filesize":1048576},{"filesize_approx":524288}]}`,
+			want: "上传者: up  时长: 1:02:05  上传于: 2024-01-02  预计大小: 1.5 MB",
+			size: 1572864,
+		},
+		{
+			name: "unknown format size",
+			json: `{"title":"t","channel":"ch","duration":59,"requested_formats":[{"filesize":100},{}]}`,
+			want: "上传者: ch  时长: 0:59",
+		},
+		{
+			name: "single file",
+			json: `{"title":"t","filesize_approx":2048}`,
+			want: "预计大小: 2.0 KB",
+			size: 2048,
+		},
+		{
+			name: "playlist",
+			json: `{"_type":"playlist","title":"list","uploader":"up","entries":[{"id":"a"},{"id":"b"}]}`,
+			want: "上传者: up  播放列表，共 2 项",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info VideoInfo
+			if err := json.Unmarshal([]byte(tt.json), &info); err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Summary(); got != tt.want {
+				t.Errorf("Summary() = %q, want %q", got, tt.want)
+			}
+			if got := info.EstimatedSize(); got != tt.size {
+				t.Errorf("EstimatedSize() = %d, want %d", got, tt.size)
+			}
+		})
+	}
+}
+
+func TestVideoInfoThumbnailURL(t *testing.T) {
+	info := VideoInfo{Thumbnails: []VideoThumb{{URL: "small.jpg"}, {URL: "large.webp"}, {}}}
+	if got := info.ThumbnailURL(); got != "large.webp" {
+		t.Errorf("ThumbnailURL() = %q, want large.webp", got)
+	}
+	info.Thumbnail = "main.jpg"
+	if got := info.ThumbnailURL(); got != "main.jpg" {
+		t.Errorf("ThumbnailURL() = %q, want main.jpg", got)
+	}
+}
+
+func TestPreviewLoaderCache(t *testing.T) {
+	infoCalls := map[string]int{}
+	thumbCalls := 0
+	loader := NewPreviewLoader(
+		func(ctx context.Context, url string) (*VideoInfo, error) {
+			infoCalls[url]++
+			if strings.HasSuffix(url, "/bad") {
+				return nil, errors.New("not found")
+			}
+			return &VideoInfo{Title: url, Thumbnail: url + ".jpg"}, nil
+		},
+		func(ctx context.Context, thumbURL string) ([]byte, error) {
+			thumbCalls++
+			return []byte(thumbURL), nil
+		})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		p, err := loader.Load(ctx, "https://example.com/a")
+		if err != nil || p.Info.Title != "https://example.com/a" || string(p.Thumbnail) != "https://example.com/a.jpg" {
+			t.Fatalf("Load = %+v, %v", p, err)
+		}
+	}
+	if infoCalls["https://example.com/a"] != 1 || thumbCalls != 1 {
+		t.Errorf("缓存未生效: info %d, thumb %d", infoCalls["https://example.com/a"], thumbCalls)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := loader.Load(ctx, "https://example.com/bad"); err == nil {
+			t.Fatal("获取失败时应返回错误")
+		}
+	}
+	if infoCalls["https://example.com/bad"] != 2 {
+		t.Error("获取失败的结果不应缓存")
+	}
+
+	// 超出缓存上限时丢弃最早的条目
+	for i := 0; i < previewCacheSize; i++ {
+		if _, err := loader.Load(ctx, fmt.Sprintf("https://example.com/%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := loader.Load(ctx, "https://example.com/a"); err != nil {
+		t.Fatal(err)
+	}
+	if infoCalls["https://example.com/a"] != 2 {
+		t.Errorf("最早的缓存应被丢弃, calls = %d", infoCalls["https://example.com/a"])
+	}
+}
+
+func TestFetchThumbnail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok.jpg":
+			fmt.Fprint(w, "image")
+		case "/huge.jpg":
+			w.Write(make([]byte, thumbnailMaxSize+1))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	data, err := fetchThumbnail(context.Background(), srv.Client(), srv.URL+"/ok.jpg")
+	if err != nil || string(data) != "image" {
+		t.Fatalf("fetchThumbnail = %q, %v", data, err)
+	}
+	if _, err := fetchThumbnail(context.Background(), srv.Client(), srv.URL+"/huge.jpg"); err == nil {
+		t.Error("过大的缩略图应返回错误")
+	}
+	if _, err := fetchThumbnail(context.Background(), srv.Client(), srv.URL+"/missing.jpg"); err == nil {
+		t.Error("404 应返回错误")
+	}
+}
+
+func TestIsPreviewableURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://www.youtube.com/watch?v=abc": true,
+		"http://example.com/v":                true,
+		"www.youtube.com/watch":               false,
+		"ftp://example.com/file":              false,
+		"":                                    false,
+		"https://":                            false,
+	}
+	for in, want := range tests {
+		if got := isPreviewableURL(in); got != want {
+			t.Errorf("isPreviewableURL(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	_ "golang.org/x/image/webp"
+)
+
+// previewDebounce 为输入网址后等待多久再获取预览，避免边输入边请求。
+const previewDebounce = 600 * time.Millisecond
+
+// previewThumbSize 为预览卡片中缩略图的显示尺寸（16:9）。
+var previewThumbSize = fyne.NewSize(160, 90)
+
+// previewAction 为预览卡片上针对当前视频的操作按钮。
+type previewAction struct {
+	Label string
+	// Available 判断按钮是否对该视频可用，不可用时隐藏
+	Available func(info *VideoInfo) bool
+	OnTapped  func(url string, info *VideoInfo)
+}
+
+// newPreviewCard 创建视频预览卡片，返回卡片和在网址变化时调用的函数。
+// 卡片在没有有效网址时隐藏；获取到视频信息后显示可用的操作按钮。
+func newPreviewCard(loader *PreviewLoader, actions ...previewAction) (fyne.CanvasObject, func(string)) {
+	thumb := canvas.NewImageFromImage(nil)
+	thumb.FillMode = canvas.ImageFillContain
+	thumb.SetMinSize(previewThumbSize)
+
+	title := widget.NewLabel("")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	title.Truncation = fyne.TextTruncateEllipsis
+	summary := widget.NewLabel("")
+	summary.Wrapping = fyne.TextWrapWord
+
+	var mu sync.Mutex
+	var cancel context.CancelFunc
+	var timer *time.Timer
+	var current string
+	var currentInfo *VideoInfo
+
+	buttons := make([]*widget.Button, len(actions))
+	actionBox := container.NewVBox()
+	for i, a := range actions {
+		buttons[i] = widget.NewButton(a.Label, func() {
+			mu.Lock()
+			u, info := current, currentInfo
+			mu.Unlock()
+			if info != nil {
+				a.OnTapped(u, info)
+			}
+		})
+		buttons[i].Hide()
+		actionBox.Add(buttons[i])
+	}
+	hideActions := func() {
+		for _, b := range buttons {
+			b.Hide()
+		}
+	}
+
+	card := container.NewBorder(nil, nil, thumb, actionBox, container.NewVBox(title, summary))
+	card.Hide()
+
+	show := func(titleText, summaryText string, importance widget.Importance, img image.Image) {
+		title.SetText(titleText)
+		summary.Importance = importance
+		summary.SetText(summaryText)
+		thumb.Image = img
+		thumb.Refresh()
+		hideActions()
+		card.Show()
+	}
+
+	load := func(ctx context.Context, u string) {
+		p, err := loader.Load(ctx, u)
+		fyne.Do(func() {
+			mu.Lock()
+			stale := u != current
+			mu.Unlock()
+			if stale || errors.Is(err, context.Canceled) {
+				return
+			}
+			if err != nil {
+				show("无法获取视频信息", err.Error(), widget.DangerImportance, nil)
+				return
+			}
+			var img image.Image
+			if len(p.Thumbnail) > 0 {
+				img, _, _ = image.Decode(bytes.NewReader(p.Thumbnail))
+			}
+			show(p.Info.Title, p.Info.Summary(), widget.MediumImportance, img)
+			mu.Lock()
+			currentInfo = p.Info
+			mu.Unlock()
+			for i, a := range actions {
+				if a.Available(p.Info) {
+					buttons[i].Show()
+				}
+			}
+		})
+	}
+
+	onURL := func(text string) {
+		u := strings.TrimSpace(text)
+		mu.Lock()
+		defer mu.Unlock()
+		if u == current {
+			return
+		}
+		current = u
+		currentInfo = nil
+		if cancel != nil {
+			cancel()
+			cancel = nil
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if !isPreviewableURL(u) {
+			card.Hide()
+			return
+		}
+		show("正在获取视频信息…", u, widget.LowImportance, nil)
+		ctx, c := context.WithCancel(context.Background())
+		cancel = c
+		timer = time.AfterFunc(previewDebounce, func() { load(ctx, u) })
+	}
+	return card, onURL
+}
+
+// isPreviewableURL 判断输入是否为可获取预览的 http(s) 网址。
+func isPreviewableURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
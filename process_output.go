@@ -23,18 +23,38 @@ func decodeProcessLine(line []byte) string {
 	return strings.TrimSpace(strings.TrimRight(string(out), "\r\n"))
 }
 
+// readPipe 逐行读取子进程输出。除换行外，单独的 \r 也视为行结束，
+// 以便及时读取用 \r 原地刷新的进度（如等待倒计时、ffmpeg 的录制状态）。
 func readPipe(r io.Reader, appendLog func(string)) {
 	br := bufio.NewReader(r)
+	var line []byte
+	afterCR := false
 	for {
-		line, err := br.ReadBytes('\n')
-		if len(line) > 0 {
-			appendLog(decodeProcessLine(line))
-		}
+		b, err := br.ReadByte()
 		if err != nil {
+			if len(line) > 0 {
+				appendLog(decodeProcessLine(line))
+			}
 			if err != io.EOF {
 				appendLog("读取子进程输出出错: " + err.Error())
 			}
 			break
 		}
+		switch b {
+		case '\n':
+			// \r\n 已在 \r 处结束该行
+			if !afterCR || len(line) > 0 {
+				appendLog(decodeProcessLine(line))
+			}
+			line = line[:0]
+		case '\r':
+			if len(line) > 0 {
+				appendLog(decodeProcessLine(line))
+			}
+			line = line[:0]
+		default:
+			line = append(line, b)
+		}
+		afterCR = b == '\r'
 	}
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPipe(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"换行", "a\nb\n", []string{"a", "b"}},
+		{"CRLF", "a\r\nb\r\n", []string{"a", "b"}},
+		{"空行", "a\n\nb", []string{"a", "", "b"}},
+		{"原地刷新", "\r[wait] 00:00:03\r[wait] 00:00:02\r[wait] 00:00:01\ndone\n", []string{"[wait] 00:00:03", "[wait] 00:00:02", "[wait] 00:00:01", "done"}},
+		{"末尾无换行", "last", []string{"last"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			readPipe(strings.NewReader(tt.in), func(line string) { got = append(got, line) })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readPipe(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,24 +1,34 @@
 package main
 
 type ProgressReporter struct {
-	AppendLog   func(string)
-	RewriteLog  func(string)
+	JobID       int
+	Log         func(LogEntry)
 	SetProgress func(status string, value float64)
 	Clear       func()
 }
 
-func (p ProgressReporter) appendLog(text string) {
-	if p.AppendLog != nil {
-		p.AppendLog(text)
+// forJob 返回写入指定任务日志的 reporter 副本。
+func (p ProgressReporter) forJob(jobID int) ProgressReporter {
+	p.JobID = jobID
+	return p
+}
+
+func (p ProgressReporter) log(level LogLevel, text string, rewritable bool) {
+	if p.Log != nil {
+		p.Log(LogEntry{JobID: p.JobID, Level: level, Text: text, Rewritable: rewritable})
 	}
 }
 
+func (p ProgressReporter) appendLog(text string) {
+	p.log(LogInfo, text, false)
+}
+
 func (p ProgressReporter) rewriteLog(text string) {
-	if p.RewriteLog != nil {
-		p.RewriteLog(text)
-	} else {
-		p.appendLog(text)
-	}
+	p.log(LogInfo, text, true)
+}
+
+func (p ProgressReporter) errorLog(text string) {
+	p.log(LogError, text, false)
 }
 
 func (p ProgressReporter) setProgress(status string, value float64) {
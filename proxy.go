@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"gopkg.in/ini.v1"
+)
+
+// ProxyCredentialsFileName 为保存代理账号密码的文件名，与程序配置分开保存，只允许当前用户读写。
+const ProxyCredentialsFileName = "proxy-credentials.ini"
+
+// ProxySystem 为“跟随系统代理”在设置中的取值，与代理名称区分。
+const ProxySystem = "@system"
+
+// proxySchemes 为支持的代理协议。socks5h 由代理服务器解析域名。
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// ProxyProfile 为一个命名的代理。
+type ProxyProfile struct {
+	Name     string
+	Scheme   string
+	Address  string // 主机:端口
+	Username string
+	Password string
+}
+
+// URL 返回包含账号密码的代理地址。
+func (p ProxyProfile) URL() *url.URL {
+	u := &url.URL{Scheme: p.Scheme, Host: p.Address}
+	if p.Username != "" || p.Password != "" {
+		u.User = url.UserPassword(p.Username, p.Password)
+	}
+	return u
+}
+
+// Display 返回不含密码的代理地址，用于界面和日志。
+func (p ProxyProfile) Display() string {
+	u := p.URL()
+	if u.User != nil {
+		u.User = url.User(p.Username)
+	}
+	return u.String()
+}
+
+// Validate 检查代理设置是否完整有效。
+func (p ProxyProfile) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("代理名称不能为空")
+	}
+	// 点在 ini 中表示子节，[proxy.a.b] 会读到 [proxy.a] 的设置
+	if strings.ContainsAny(p.Name, "[].\r\n") {
+		return fmt.Errorf("代理名称不能包含方括号、点或换行")
+	}
+	if strings.HasPrefix(p.Name, "@") {
+		return fmt.Errorf("代理名称不能以 @ 开头")
+	}
+	return p.validateAddress()
+}
+
+func (p ProxyProfile) validateAddress() error {
+	if !slices.Contains(proxySchemes, p.Scheme) {
+		return fmt.Errorf("不支持的代理协议: %s", p.Scheme)
+	}
+	host, port, err := net.SplitHostPort(p.Address)
+	if err != nil || host == "" {
+		return fmt.Errorf("代理地址应为 主机:端口，如 127.0.0.1:7890")
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("代理端口无效: %s", port)
+	}
+	return nil
+}
+
+// parseProxyURL 解析代理地址，未写协议时视为 http。返回的代理没有名称。
+func parseProxyURL(s string) (ProxyProfile, error) {
+	u, err := url.Parse(normalizeProxyURL(strings.TrimSpace(s)))
+	if err != nil {
+		return ProxyProfile{}, fmt.Errorf("代理地址无效: %w", err)
+	}
+	p := ProxyProfile{Scheme: strings.ToLower(u.Scheme), Address: u.Host}
+	if u.User != nil {
+		p.Username = u.User.Username()
+		p.Password, _ = u.User.Password()
+	}
+	if err := p.validateAddress(); err != nil {
+		return ProxyProfile{}, err
+	}
+	return p, nil
+}
+
+// normalizeProxyURL 为未写协议的代理地址（如 yt-dlp 配置中的 127.0.0.1:7890）补上 http://。
+func normalizeProxyURL(proxy string) string {
+	if proxy != "" && !strings.Contains(proxy, "://") {
+		return "http://" + proxy
+	}
+	return proxy
+}
+
+// ProxyRoute 为一类网络请求选择的代理：固定的代理、跟随系统代理，零值表示直连。
+type ProxyRoute struct {
+	URL    *url.URL
+	System bool
+}
+
+// ProxyConfig 为代理设置：命名的代理列表，以及程序自身和 yt-dlp 各自使用的代理。
+// App 和 YtDlp 为代理名称，为空时直连，为 ProxySystem 时跟随系统代理。
+type ProxyConfig struct {
+	Profiles []ProxyProfile
+	App      string // 检查更新、下载 yt-dlp 等程序自身的网络请求使用的代理
+	YtDlp    string // yt-dlp 使用的代理
+}
+
+// Profile 返回名为 name 的代理，不存在时返回 nil。
+func (c ProxyConfig) Profile(name string) *ProxyProfile {
+	if name == "" {
+		return nil
+	}
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == name {
+			return &c.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// AppRoute 返回程序自身使用的代理。
+func (c ProxyConfig) AppRoute() ProxyRoute {
+	return c.route(c.App)
+}
+
+// YtDlpRoute 返回 yt-dlp 使用的代理。
+func (c ProxyConfig) YtDlpRoute() ProxyRoute {
+	return c.route(c.YtDlp)
+}
+
+func (c ProxyConfig) route(name string) ProxyRoute {
+	if name == ProxySystem {
+		return ProxyRoute{System: true}
+	}
+	if p := c.Profile(name); p != nil {
+		return ProxyRoute{URL: p.URL()}
+	}
+	return ProxyRoute{}
+}
+
+// Validate 检查代理列表，名称不能重复，选择的代理必须存在。
+func (c ProxyConfig) Validate() error {
+	var names []string
+	for _, p := range c.Profiles {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("代理 %s: %w", p.Name, err)
+		}
+		if slices.Contains(names, p.Name) {
+			return fmt.Errorf("代理名称重复: %s", p.Name)
+		}
+		names = append(names, p.Name)
+	}
+	for _, name := range []string{c.App, c.YtDlp} {
+		if name != "" && name != ProxySystem && !slices.Contains(names, name) {
+			return fmt.Errorf("未找到代理: %s", name)
+		}
+	}
+	return nil
+}
+
+// ytDlpProxyArgs 返回让 yt-dlp 使用代理的命令行参数，覆盖 yt-dlp 配置文件中的设置。
+// 直连时传入空的 --proxy；跟随系统代理或代理带账号密码时不传参数，由 applyProxyEnv 设置环境变量，
+// 避免其他用户从进程列表中看到密码。
+func ytDlpProxyArgs(proxy ProxyRoute) []string {
+	switch {
+	case proxy.System || proxy.hasCredentials():
+		return nil
+	case proxy.URL != nil:
+		return []string{"--proxy", proxy.URL.String()}
+	}
+	return []string{"--proxy", ""}
+}
+
+// hasCredentials 表示固定的代理带账号密码。
+func (r ProxyRoute) hasCredentials() bool {
+	return r.URL != nil && r.URL.User != nil
+}
+
+// applyProxyEnv 以 HTTP_PROXY、HTTPS_PROXY 和 NO_PROXY 环境变量将代理传给子进程：
+// 跟随系统代理时传入系统代理设置（包括 Windows Internet 选项中的设置），
+// 代理带账号密码时传入该代理（见 ytDlpProxyArgs）。
+func applyProxyEnv(cmd *exec.Cmd, proxy ProxyRoute) {
+	var cfg httpproxy.Config
+	switch {
+	case proxy.System:
+		cfg = systemProxyConfig()
+	case proxy.hasCredentials():
+		cfg = httpproxy.Config{HTTPProxy: proxy.URL.String(), HTTPSProxy: proxy.URL.String()}
+	default:
+		return
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = systemProxyEnv(env, cfg)
+}
+
+// appHTTPClient 返回按 proxy 访问网络的 HTTP 客户端，跟随系统代理时按 NO_PROXY 决定哪些地址直连。
+func appHTTPClient(proxy ProxyRoute, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	switch {
+	case proxy.System:
+		transport.Proxy = systemProxyFunc()
+	case proxy.URL != nil:
+		transport.Proxy = http.ProxyURL(proxy.URL)
+	default:
+		transport.Proxy = nil
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// proxySectionPrefix 为程序配置中每个代理所在分区的名称前缀。
+const proxySectionPrefix = "proxy."
+
+// loadProxyConfig 从程序配置和 credPath 中的账号密码读取代理设置，未设置时跟随系统代理。
+// 旧版本的 download_proxy 转为名为“下载代理”的代理。
+func loadProxyConfig(cfg *ini.File, credPath string) ProxyConfig {
+	var creds *ini.File
+	if _, err := os.Stat(credPath); err == nil {
+		creds, _ = ini.Load(credPath)
+	}
+	sec := cfg.Section("proxy")
+	pc := ProxyConfig{App: ProxySystem, YtDlp: ProxySystem}
+	if sec.HasKey("app") {
+		pc.App = sec.Key("app").String()
+	}
+	if sec.HasKey("yt_dlp") {
+		pc.YtDlp = sec.Key("yt_dlp").String()
+	}
+	for _, s := range cfg.Sections() {
+		name, ok := strings.CutPrefix(s.Name(), proxySectionPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		p := ProxyProfile{
+			Name:    name,
+			Scheme:  s.Key("scheme").In("http", proxySchemes),
+			Address: s.Key("address").MustString(""),
+		}
+		if creds != nil && creds.HasSection(name) {
+			p.Username = creds.Section(name).Key("username").MustString("")
+			p.Password = creds.Section(name).Key("password").MustString("")
+		}
+		pc.Profiles = append(pc.Profiles, p)
+	}
+
+	if legacy := cfg.Section("app").Key("download_proxy").MustString(""); legacy != "" && len(pc.Profiles) == 0 {
+		if p, err := parseProxyURL(legacy); err == nil {
+			p.Name = "下载代理"
+			pc.Profiles = append(pc.Profiles, p)
+			pc.App = p.Name
+		}
+	}
+	return pc
+}
+
+// saveProxyConfig 将代理设置写入程序配置，账号密码单独写入 credPath，没有账号密码时删除该文件。
+func saveProxyConfig(cfg *ini.File, credPath string, pc ProxyConfig) error {
+	sec := cfg.Section("proxy")
+	sec.Key("app").SetValue(pc.App)
+	sec.Key("yt_dlp").SetValue(pc.YtDlp)
+
+	creds := ini.Empty()
+	hasCreds := false
+	// 用 NewKey 在各节中创建键，旧版本允许名称含点，Key 会返回父节中的同名键
+	for _, p := range pc.Profiles {
+		s := cfg.Section(proxySectionPrefix + p.Name)
+		_, _ = s.NewKey("scheme", p.Scheme)
+		_, _ = s.NewKey("address", p.Address)
+		if p.Username != "" || p.Password != "" {
+			c := creds.Section(p.Name)
+			_, _ = c.NewKey("username", p.Username)
+			_, _ = c.NewKey("password", p.Password)
+			hasCreds = true
+		}
+	}
+
+	if !hasCreds {
+		if err := os.Remove(credPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("无法删除代理账号文件: %w", err)
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	if _, err := creds.WriteTo(&buf); err != nil {
+		return fmt.Errorf("无法生成代理账号文件: %w", err)
+	}
+	tmp := credPath + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("无法保存代理账号文件: %w", err)
+	}
+	if err := os.Rename(tmp, credPath); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("无法保存代理账号文件: %w", err)
+	}
+	return nil
+}
+
+// proxyCredentialsPath 返回与程序配置文件位于同一目录的代理账号文件路径。
+func proxyCredentialsPath(iniPath string) string {
+	return filepath.Join(filepath.Dir(iniPath), ProxyCredentialsFileName)
+}
+
+// migrateYtDlpProxy 将旧版本写在 yt-dlp.conf 中的代理转为代理设置，之后由程序通过命令行传给 yt-dlp，
+// 账号密码不再写入 yt-dlp.conf。返回是否有改动。
+func migrateYtDlpProxy(appCfg *AppConfig, ytdlpCfg *YTDLPConfig) bool {
+	if !ytdlpCfg.UseProxy || ytdlpCfg.ProxyAddress == "" || appCfg.Proxy.Profile(appCfg.Proxy.YtDlp) != nil {
+		return false
+	}
+	p, err := parseProxyURL(ytdlpCfg.ProxyAddress)
+	if err != nil {
+		return false
+	}
+	ytdlpCfg.UseProxy = false
+	for _, existing := range appCfg.Proxy.Profiles {
+		if existing.URL().String() == p.URL().String() {
+			appCfg.Proxy.YtDlp = existing.Name
+			return true
+		}
+	}
+	p.Name = "yt-dlp 代理"
+	for i := 2; appCfg.Proxy.Profile(p.Name) != nil; i++ {
+		p.Name = fmt.Sprintf("yt-dlp 代理 %d", i)
+	}
+	appCfg.Proxy.Profiles = append(appCfg.Proxy.Profiles, p)
+	appCfg.Proxy.YtDlp = p.Name
+	return true
+}
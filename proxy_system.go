@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxyEnvNames 为与代理有关的环境变量，大小写两种写法都会被读取。
+var proxyEnvNames = []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "NO_PROXY"}
+
+// systemProxyConfig 读取系统代理设置。设置了 HTTP_PROXY 等环境变量时以环境变量为准，
+// 否则读取系统设置（目前只支持 Windows 的 Internet 选项）。
+func systemProxyConfig() httpproxy.Config {
+	if cfg := envProxyConfig(os.Getenv); cfg.HTTPProxy != "" || cfg.HTTPSProxy != "" {
+		return cfg
+	}
+	if cfg, ok := platformProxyConfig(); ok {
+		return cfg
+	}
+	return httpproxy.Config{}
+}
+
+// envProxyConfig 从环境变量读取代理设置，小写的变量优先。ALL_PROXY 用于未单独设置的协议。
+func envProxyConfig(getenv func(string) string) httpproxy.Config {
+	get := func(name string) string {
+		if v := getenv(strings.ToLower(name)); v != "" {
+			return v
+		}
+		return getenv(name)
+	}
+	all := get("ALL_PROXY")
+	cfg := httpproxy.Config{
+		HTTPProxy:  get("HTTP_PROXY"),
+		HTTPSProxy: get("HTTPS_PROXY"),
+		NoProxy:    get("NO_PROXY"),
+	}
+	if cfg.HTTPProxy == "" {
+		cfg.HTTPProxy = all
+	}
+	if cfg.HTTPSProxy == "" {
+		cfg.HTTPSProxy = all
+	}
+	return cfg
+}
+
+// parseWinProxySettings 将 Windows Internet 选项中的代理服务器和例外列表转为环境变量的格式。
+// server 可以是 “主机:端口”，也可以按协议分别设置，如 “http=a:1;https=b:2;socks=c:3”。
+func parseWinProxySettings(server, override string) httpproxy.Config {
+	var cfg httpproxy.Config
+	var socks string
+	for _, part := range strings.Split(server, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		scheme, addr, ok := strings.Cut(part, "=")
+		if !ok {
+			cfg.HTTPProxy, cfg.HTTPSProxy = part, part
+			continue
+		}
+		switch strings.ToLower(scheme) {
+		case "http":
+			cfg.HTTPProxy = addr
+		case "https":
+			cfg.HTTPSProxy = addr
+		case "socks":
+			socks = "socks5://" + addr
+		}
+	}
+	if socks != "" {
+		if cfg.HTTPProxy == "" {
+			cfg.HTTPProxy = socks
+		}
+		if cfg.HTTPSProxy == "" {
+			cfg.HTTPSProxy = socks
+		}
+	}
+
+	var bypass []string
+	for _, p := range strings.Split(override, ";") {
+		if p = winBypassToNoProxy(strings.TrimSpace(p)); p != "" {
+			bypass = append(bypass, p)
+		}
+	}
+	cfg.NoProxy = strings.Join(bypass, ",")
+	return cfg
+}
+
+// winLocalBypass 为 Windows 代理例外中表示“本地地址不使用代理”的写法，即不含点的主机名。
+// 转换后保留在 NO_PROXY 中，由 noProxyFunc 处理；其他程序会将其视为不匹配任何地址的主机名。
+const winLocalBypass = "<local>"
+
+// winBypassToNoProxy 将 Windows 代理例外中的一项转为 NO_PROXY 的写法，无法表示的返回空字符串。
+// “*.example.com” 匹配子域名，“10.*” 这类 IP 前缀转为网段，
+// “<local>” 转为本机地址，不含点的主机名由 noProxyFunc 判断。
+func winBypassToNoProxy(p string) string {
+	switch {
+	case p == "":
+		return ""
+	case strings.EqualFold(p, winLocalBypass):
+		return "localhost,127.0.0.1,::1," + winLocalBypass
+	case strings.HasPrefix(p, "*."):
+		if strings.Contains(p[2:], "*") {
+			return ""
+		}
+		return p[1:]
+	case strings.HasSuffix(p, ".*"):
+		octets := strings.Split(strings.TrimSuffix(p, ".*"), ".")
+		if len(octets) > 3 {
+			return ""
+		}
+		for _, o := range octets {
+			if n, err := strconv.Atoi(o); err != nil || n < 0 || n > 255 {
+				return ""
+			}
+		}
+		network := append(octets, "0", "0", "0")[:4]
+		return strings.Join(network, ".") + "/" + strconv.Itoa(8*len(octets))
+	case strings.Contains(p, "*"):
+		return ""
+	}
+	return p
+}
+
+// systemProxyFunc 返回按系统代理设置为请求选择代理的函数，NO_PROXY 中的地址直连。
+func systemProxyFunc() func(*http.Request) (*url.URL, error) {
+	proxyFor := noProxyFunc(systemProxyConfig())
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFor(req.URL)
+	}
+}
+
+// noProxyFunc 返回按 cfg 选择代理的函数。NO_PROXY 中有 winLocalBypass 时，
+// 不含点的主机名（如 http://intranet/）也直连。
+func noProxyFunc(cfg httpproxy.Config) func(*url.URL) (*url.URL, error) {
+	proxyFor := cfg.ProxyFunc()
+	if !slices.Contains(strings.Split(cfg.NoProxy, ","), winLocalBypass) {
+		return proxyFor
+	}
+	return func(u *url.URL) (*url.URL, error) {
+		if host := u.Hostname(); host != "" && !strings.Contains(host, ".") && net.ParseIP(host) == nil {
+			return nil, nil
+		}
+		return proxyFor(u)
+	}
+}
+
+// systemProxyEnv 返回将 environ 中的代理环境变量替换为 cfg 后的环境变量，传给 yt-dlp 等子进程。
+func systemProxyEnv(environ []string, cfg httpproxy.Config) []string {
+	env := make([]string, 0, len(environ)+3)
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		isProxy := false
+		for _, n := range proxyEnvNames {
+			if strings.EqualFold(name, n) {
+				isProxy = true
+				break
+			}
+		}
+		if !isProxy {
+			env = append(env, kv)
+		}
+	}
+	for _, kv := range [][2]string{{"HTTP_PROXY", cfg.HTTPProxy}, {"HTTPS_PROXY", cfg.HTTPSProxy}, {"NO_PROXY", cfg.NoProxy}} {
+		if kv[1] != "" {
+			env = append(env, kv[0]+"="+kv[1])
+		}
+	}
+	return env
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "golang.org/x/net/http/httpproxy"
+
+// platformProxyConfig 在 Windows 以外的系统上只使用环境变量，总是返回 false。
+func platformProxyConfig() (httpproxy.Config, bool) {
+	return httpproxy.Config{}, false
+}
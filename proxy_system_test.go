@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+func TestEnvProxyConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want httpproxy.Config
+	}{
+		{name: "empty", env: nil, want: httpproxy.Config{}},
+		{
+			name: "lowercase first",
+			env:  map[string]string{"http_proxy": "a:1", "HTTP_PROXY": "b:2", "NO_PROXY": ".lan"},
+			want: httpproxy.Config{HTTPProxy: "a:1", NoProxy: ".lan"},
+		},
+		{
+			name: "all proxy",
+			env:  map[string]string{"ALL_PROXY": "socks5://c:3", "HTTPS_PROXY": "d:4"},
+			want: httpproxy.Config{HTTPProxy: "socks5://c:3", HTTPSProxy: "d:4"},
+		},
+	}
+	for _, tt := range tests {
+		got := envProxyConfig(func(k string) string { return tt.env[k] })
+		if got != tt.want {
+			t.Errorf("%s: envProxyConfig = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseWinProxySettings(t *testing.T) {
+	tests := []struct {
+		server, override string
+		want             httpproxy.Config
+	}{
+		{server: "127.0.0.1:7890", want: httpproxy.Config{HTTPProxy: "127.0.0.1:7890", HTTPSProxy: "127.0.0.1:7890"}},
+		{server: "http=a:1;https=b:2;ftp=c:3", want: httpproxy.Config{HTTPProxy: "a:1", HTTPSProxy: "b:2"}},
+		{server: "socks=s:1080", want: httpproxy.Config{HTTPProxy: "socks5://s:1080", HTTPSProxy: "socks5://s:1080"}},
+		{server: "http=a:1;socks=s:1080", want: httpproxy.Config{HTTPProxy: "a:1", HTTPSProxy: "socks5://s:1080"}},
+		{
+			server:   "p:8080",
+			override: "*.corp.example;<local>;10.*;192.168.*; intranet ;a*b.com",
+			want:     httpproxy.Config{HTTPProxy: "p:8080", HTTPSProxy: "p:8080", NoProxy: ".corp.example,localhost,127.0.0.1,::1,<local>,10.0.0.0/8,192.168.0.0/16,intranet"},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseWinProxySettings(tt.server, tt.override); got != tt.want {
+			t.Errorf("parseWinProxySettings(%q, %q) = %+v, want %+v", tt.server, tt.override, got, tt.want)
+		}
+	}
+}
+
+func TestSystemProxyResolve(t *testing.T) {
+	cfg := parseWinProxySettings("http=h:1;https=s:2", "*.corp.example;10.*;intranet")
+	proxyFor := noProxyFunc(cfg)
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://www.youtube.com/watch", want: "http://s:2"},
+		{url: "http://example.com/", want: "http://h:1"},
+		{url: "https://wiki.corp.example/", want: ""},
+		{url: "http://10.1.2.3/", want: ""},
+		{url: "http://intranet/", want: ""},
+		{url: "http://sub.intranet/", want: ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		got, err := proxyFor(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotStr := ""
+		if got != nil {
+			gotStr = got.String()
+		}
+		if gotStr != tt.want {
+			t.Errorf("proxy for %s = %v, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSystemProxyResolveLocal(t *testing.T) {
+	tests := []struct {
+		override string
+		url      string
+		want     bool // 使用代理
+	}{
+		{override: "<local>", url: "http://nas/", want: false},
+		{override: "<local>", url: "http://nas:5000/", want: false},
+		{override: "<local>", url: "http://localhost:8080/", want: false},
+		{override: "<local>", url: "http://[::1]/", want: false},
+		{override: "<local>", url: "http://nas.lan/", want: true},
+		{override: "<local>", url: "http://192.168.1.2/", want: true},
+		{override: "*.corp.example", url: "http://nas/", want: true},
+	}
+	for _, tt := range tests {
+		proxyFor := noProxyFunc(parseWinProxySettings("p:8080", tt.override))
+		u, _ := url.Parse(tt.url)
+		got, err := proxyFor(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got != nil) != tt.want {
+			t.Errorf("例外 %q 时 %s 的代理 = %v, want 使用代理 %v", tt.override, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSystemProxyEnv(t *testing.T) {
+	environ := []string{"PATH=/bin", "http_proxy=old:1", "Https_Proxy=old:2", "ALL_PROXY=old:3", "no_proxy=old", "HOME=/home/u"}
+	cfg := httpproxy.Config{HTTPProxy: "h:1", HTTPSProxy: "s:2", NoProxy: ".lan"}
+	want := []string{"PATH=/bin", "HOME=/home/u", "HTTP_PROXY=h:1", "HTTPS_PROXY=s:2", "NO_PROXY=.lan"}
+	if got := systemProxyEnv(environ, cfg); !slices.Equal(got, want) {
+		t.Errorf("systemProxyEnv = %q, want %q", got, want)
+	}
+	if got := systemProxyEnv(environ, httpproxy.Config{}); !slices.Equal(got, []string{"PATH=/bin", "HOME=/home/u"}) {
+		t.Errorf("未设置系统代理时应去掉代理变量: %q", got)
+	}
+}
+
+func TestAppHTTPClientProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("NO_PROXY", "skip.example")
+	for _, k := range []string{"http_proxy", "https_proxy", "no_proxy", "ALL_PROXY", "all_proxy"} {
+		t.Setenv(k, "")
+	}
+	fixed, _ := url.Parse("socks5://fixed:1080")
+
+	tests := []struct {
+		name  string
+		route ProxyRoute
+		url   string
+		want  string
+	}{
+		{name: "direct", route: ProxyRoute{}, url: "http://example.com/", want: "<nil>"},
+		{name: "fixed", route: ProxyRoute{URL: fixed}, url: "http://skip.example/", want: "socks5://fixed:1080"},
+		{name: "system", route: ProxyRoute{System: true}, url: "http://example.com/", want: "http://env-proxy:3128"},
+		{name: "system no_proxy", route: ProxyRoute{System: true}, url: "http://skip.example/", want: "<nil>"},
+	}
+	for _, tt := range tests {
+		transport := appHTTPClient(tt.route, 0).Transport.(*http.Transport)
+		var got *url.URL
+		if transport.Proxy != nil {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			var err error
+			if got, err = transport.Proxy(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s: proxy = %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
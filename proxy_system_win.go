@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/sys/windows/registry"
+)
+
+// platformProxyConfig 读取 Windows Internet 选项中手动设置的代理，未启用时返回 false。
+func platformProxyConfig() (httpproxy.Config, bool) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.QUERY_VALUE)
+	if err != nil {
+		return httpproxy.Config{}, false
+	}
+	defer k.Close()
+	if enabled, _, err := k.GetIntegerValue("ProxyEnable"); err != nil || enabled == 0 {
+		return httpproxy.Config{}, false
+	}
+	server, _, err := k.GetStringValue("ProxyServer")
+	if err != nil || server == "" {
+		return httpproxy.Config{}, false
+	}
+	override, _, _ := k.GetStringValue("ProxyOverride")
+	return parseWinProxySettings(server, override), true
+}
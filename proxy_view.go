@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// proxyTestTimeout 为测试代理连接的超时时间。
+const proxyTestTimeout = 10 * time.Second
+
+const (
+	proxyNone   = "不使用代理"
+	proxySystem = "跟随系统代理"
+)
+
+// proxySelectLabel 返回设置中的代理名称在选择框中显示的文字。
+func proxySelectLabel(name string) string {
+	switch name {
+	case "":
+		return proxyNone
+	case ProxySystem:
+		return proxySystem
+	}
+	return name
+}
+
+// proxySelectValue 为 proxySelectLabel 的反向转换。
+func proxySelectValue(label string) string {
+	switch label {
+	case proxyNone:
+		return ""
+	case proxySystem:
+		return ProxySystem
+	}
+	return label
+}
+
+// newProxySettings 返回设置中的代理界面：代理列表、程序和 yt-dlp 各自使用的代理。
+// 返回的函数读取编辑后的设置，在保存时调用。
+func newProxySettings(w fyne.Window, cfg ProxyConfig) (fyne.CanvasObject, func() ProxyConfig) {
+	profiles := slices.Clone(cfg.Profiles)
+	selected := -1
+
+	appSelect := widget.NewSelect(nil, nil)
+	ytDlpSelect := widget.NewSelect(nil, nil)
+	refreshSelects := func() {
+		options := []string{proxyNone, proxySystem}
+		for _, p := range profiles {
+			options = append(options, p.Name)
+		}
+		for _, sel := range []*widget.Select{appSelect, ytDlpSelect} {
+			current := sel.Selected
+			sel.Options = options
+			if !slices.Contains(options, current) {
+				current = proxyNone
+			}
+			sel.SetSelected(current)
+		}
+	}
+	appSelect.Selected = proxySelectLabel(cfg.App)
+	ytDlpSelect.Selected = proxySelectLabel(cfg.YtDlp)
+	refreshSelects()
+
+	list := widget.NewList(
+		func() int { return len(profiles) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id < len(profiles) {
+				obj.(*widget.Label).SetText(profiles[id].Name + "  " + profiles[id].Display())
+			}
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) { selected = id }
+	list.OnUnselected = func(widget.ListItemID) { selected = -1 }
+
+	// rename 在代理改名后更新使用它的选择
+	rename := func(oldName, newName string) {
+		for _, sel := range []*widget.Select{appSelect, ytDlpSelect} {
+			if oldName != "" && sel.Selected == oldName {
+				sel.Selected = newName
+			}
+		}
+	}
+	nameTaken := func(name string, except int) bool {
+		if name == proxyNone || name == proxySystem {
+			return true
+		}
+		for i, p := range profiles {
+			if i != except && p.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	addBtn := widget.NewButton("添加...", func() {
+		showProxyEditDialog(w, ProxyProfile{Scheme: "http"}, func(name string) bool { return nameTaken(name, -1) }, func(p ProxyProfile) {
+			profiles = append(profiles, p)
+			refreshSelects()
+			list.Refresh()
+		})
+	})
+	editBtn := widget.NewButton("编辑...", func() {
+		if selected < 0 || selected >= len(profiles) {
+			return
+		}
+		i := selected
+		showProxyEditDialog(w, profiles[i], func(name string) bool { return nameTaken(name, i) }, func(p ProxyProfile) {
+			old := profiles[i].Name
+			profiles[i] = p
+			rename(old, p.Name)
+			refreshSelects()
+			list.Refresh()
+		})
+	})
+	removeBtn := widget.NewButton("删除", func() {
+		if selected < 0 || selected >= len(profiles) {
+			return
+		}
+		profiles = slices.Delete(profiles, selected, selected+1)
+		list.UnselectAll()
+		refreshSelects()
+		list.Refresh()
+	})
+	testBtn := widget.NewButton("测试连接", func() {
+		if selected < 0 || selected >= len(profiles) {
+			return
+		}
+		testProxyConnection(w, profiles[selected])
+	})
+
+	systemHint := widget.NewLabel("跟随系统代理：使用 HTTP_PROXY、HTTPS_PROXY 环境变量，未设置时使用系统设置中的代理（Windows），NO_PROXY 和代理例外中的地址直连。代理例外中的“<local>”对程序自身的请求表示所有不含点的主机名，对 yt-dlp 只包括本机地址。")
+	systemHint.Wrapping = fyne.TextWrapWord
+
+	listScroll := container.NewVScroll(list)
+	listScroll.SetMinSize(fyne.NewSize(0, 120))
+	content := container.NewVBox(
+		container.NewBorder(nil, nil, nil, container.NewVBox(addBtn, editBtn, removeBtn, testBtn), listScroll),
+		container.NewBorder(nil, nil, widget.NewLabel("程序使用（检查更新、下载 yt-dlp）："), nil, appSelect),
+		container.NewBorder(nil, nil, widget.NewLabel("yt-dlp 使用（下载视频、获取信息和缩略图）："), nil, ytDlpSelect),
+		systemHint,
+	)
+
+	return content, func() ProxyConfig {
+		return ProxyConfig{
+			Profiles: slices.Clone(profiles),
+			App:      proxySelectValue(appSelect.Selected),
+			YtDlp:    proxySelectValue(ytDlpSelect.Selected),
+		}
+	}
+}
+
+// showProxyEditDialog 编辑单个代理，taken 判断名称是否已被其他代理使用。
+func showProxyEditDialog(w fyne.Window, initial ProxyProfile, taken func(name string) bool, onSaved func(ProxyProfile)) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(initial.Name)
+	schemeSelect := widget.NewSelect(proxySchemes, nil)
+	schemeSelect.SetSelected(initial.Scheme)
+	addressEntry := widget.NewEntry()
+	addressEntry.SetText(initial.Address)
+	addressEntry.SetPlaceHolder("主机:端口，例如：127.0.0.1:7890")
+	userEntry := widget.NewEntry()
+	userEntry.SetText(initial.Username)
+	userEntry.SetPlaceHolder("不需要认证时留空")
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetText(initial.Password)
+
+	current := func() ProxyProfile {
+		return ProxyProfile{
+			Name:     strings.TrimSpace(nameEntry.Text),
+			Scheme:   schemeSelect.Selected,
+			Address:  strings.TrimSpace(addressEntry.Text),
+			Username: strings.TrimSpace(userEntry.Text),
+			Password: passwordEntry.Text,
+		}
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("名称", nameEntry),
+		widget.NewFormItem("协议", schemeSelect),
+		widget.NewFormItem("地址", addressEntry),
+		widget.NewFormItem("用户名", userEntry),
+		widget.NewFormItem("密码", passwordEntry),
+	)
+	hint := widget.NewLabel("账号密码单独保存在 " + ProxyCredentialsFileName + " 中。socks5h 由代理服务器解析域名。")
+	hint.Wrapping = fyne.TextWrapWord
+
+	title := "编辑代理"
+	if initial.Name == "" {
+		title = "添加代理"
+	}
+	dlg := dialog.NewCustomWithoutButtons(title, container.NewVBox(form, hint), w)
+	dlg.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("取消", func() { dlg.Hide() }),
+		widget.NewButton("测试连接", func() {
+			p := current()
+			if err := p.validateAddress(); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			testProxyConnection(w, p)
+		}),
+		&widget.Button{Text: "确定", Importance: widget.HighImportance, OnTapped: func() {
+			p := current()
+			if err := p.Validate(); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if taken(p.Name) {
+				dialog.ShowError(fmt.Errorf("代理名称重复: %s", p.Name), w)
+				return
+			}
+			dlg.Hide()
+			onSaved(p)
+		}},
+	})
+	dlg.Resize(fyne.NewSize(480, 0))
+	dlg.Show()
+}
+
+// testProxyConnection 在后台经由代理访问测试地址，显示延迟或错误。
+func testProxyConnection(w fyne.Window, p ProxyProfile) {
+	progress := dialog.NewCustomWithoutButtons("测试连接", widget.NewProgressBarInfinite(), w)
+	progress.Show()
+	go func() {
+		check := probeProxy(p.Name, &p, diagnosticsProbeURL, proxyTestTimeout)
+		fyne.Do(func() {
+			progress.Hide()
+			if check.Err != nil {
+				dialog.ShowError(fmt.Errorf("经由 %s 连接失败: %w", p.Display(), check.Err), w)
+				return
+			}
+			dialog.ShowInformation("连接成功", fmt.Sprintf("经由 %s 连接成功，延迟 %d ms", p.Display(), check.Latency.Milliseconds()), w)
+		})
+	}()
+}
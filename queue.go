@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// JobStatus 为队列中任务的状态。
+type JobStatus int
+
+const (
+	JobQueued JobStatus = iota
+	JobScheduled
+	JobRunning
+	JobDone
+	JobFailed
+	JobCancelled
+	JobPaused
+)
+
+func (s JobStatus) String() string {
+	switch s {
+	case JobQueued:
+		return "排队中"
+	case JobScheduled:
+		return "等待中"
+	case JobRunning:
+		return "下载中"
+	case JobDone:
+		return "已完成"
+	case JobFailed:
+		return "失败"
+	case JobCancelled:
+		return "已取消"
+	case JobPaused:
+		return "已暂停"
+	}
+	return "未知"
+}
+
+// finished 表示任务已结束，不会再被执行。
+func (s JobStatus) finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+// Job 为下载队列中的一个任务。
+type Job struct {
+	ID        int
+	URL       string
+	Profile   string    // 下载方案的名称（见 downloadProfiles），为空时使用默认方案
+	ExtraArgs []string  // 追加到该任务 yt-dlp 命令行的参数，位于下载方案的参数之后
+	NotBefore time.Time // 最早开始时间，零值表示立即
+	// EstimatedSize 为预计的下载大小（字节），未知时为 0，用于开始前检查剩余空间
+	EstimatedSize int64
+	Status        JobStatus
+	Detail        string // 状态说明，如等待原因、限速或错误信息
+	Live          bool   // 正在录制直播，可停止录制
+	LogFile       string // 任务日志的文件名（位于日志目录中），开始执行后设置
+}
+
+// JobControl 为运行中的任务与队列之间的交互。
+type JobControl struct {
+	// Stop 在请求停止录制时关闭，任务应正常结束以保存已录制的内容
+	Stop <-chan struct{}
+	// SetLive 标记任务正在录制直播
+	SetLive func()
+	// SetLogFile 记录任务日志的文件名
+	SetLogFile func(name string)
+}
+
+// JobRunner 执行单个下载任务，rateLimit 非空时应作为 --limit-rate 传给 yt-dlp。
+// ctx 取消时应尽快终止下载并返回。
+type JobRunner func(ctx context.Context, job Job, rateLimit string, ctl JobControl) error
+
+// queueRecheckInterval 为等待时间窗口时重新检查的最长间隔，
+// 避免系统休眠或调整时钟后错过开始时间。
+const queueRecheckInterval = time.Minute
+
+// diskCheckInterval 为检查剩余空间的间隔，包括下载过程中和因空间不足暂停时。
+const diskCheckInterval = 30 * time.Second
+
+// SpaceCheck 检查剩余空间是否足够下载 size 字节（未知或已在下载时为 0），
+// 不足时返回 *LowDiskSpaceError。
+type SpaceCheck func(size int64) error
+
+// DownloadQueue 逐个执行下载任务，开始时间受定时和时间窗口限制，
+// 可以开始的任务中先加入的先执行，尚未到开始时间的任务不会阻塞之后的任务。
+type DownloadQueue struct {
+	clock    Clock
+	schedule func() ScheduleConfig
+	run      JobRunner
+	space    SpaceCheck
+
+	mu        sync.Mutex
+	jobs      []*Job
+	cancel    context.CancelCauseFunc // 正在运行任务的取消函数
+	stop      func()                  // 请求正在运行的任务停止录制
+	listeners []func()
+	wake      chan struct{}
+}
+
+// NewDownloadQueue 创建下载队列，schedule 在每次调度时调用以获取最新设置。
+// space 不为 nil 时在任务开始前和下载过程中检查剩余空间，不足时暂停队列，空间恢复后继续。
+func NewDownloadQueue(clock Clock, schedule func() ScheduleConfig, run JobRunner, space SpaceCheck) *DownloadQueue {
+	return &DownloadQueue{
+		clock:    clock,
+		schedule: schedule,
+		run:      run,
+		space:    space,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Start 启动后台工作协程。
+func (q *DownloadQueue) Start() {
+	go q.loop()
+}
+
+// Add 将任务加入队列末尾并返回其副本。
+func (q *DownloadQueue) Add(url string, extraArgs []string, notBefore time.Time) Job {
+	return q.AddJob(Job{URL: url, ExtraArgs: extraArgs, NotBefore: notBefore})
+}
+
+// AddJob 按 job 中的地址、参数等设置加入任务，编号和状态由队列分配。
+func (q *DownloadQueue) AddJob(job Job) Job {
+	job.ID = newJobID()
+	job.Status = JobQueued
+	job.Detail = ""
+	job.Live = false
+	job.LogFile = ""
+	q.mu.Lock()
+	q.jobs = append(q.jobs, &job)
+	snapshot := job
+	q.mu.Unlock()
+	q.changed()
+	q.Wake()
+	return snapshot
+}
+
+// Cancel 取消排队中的任务或终止正在运行的任务。
+func (q *DownloadQueue) Cancel(id int) {
+	q.mu.Lock()
+	for _, job := range q.jobs {
+		if job.ID != id || job.Status.finished() {
+			continue
+		}
+		if job.Status == JobRunning {
+			if q.cancel != nil {
+				q.cancel(context.Canceled)
+			}
+		} else {
+			job.Status = JobCancelled
+			job.Detail = ""
+		}
+	}
+	q.mu.Unlock()
+	q.changed()
+	q.Wake()
+}
+
+// Stop 请求正在录制直播的任务停止录制并保存已录制的内容。
+func (q *DownloadQueue) Stop(id int) {
+	q.mu.Lock()
+	for _, job := range q.jobs {
+		if job.ID == id && job.Status == JobRunning && job.Live && q.stop != nil {
+			q.stop()
+			job.Detail = "正在停止录制"
+		}
+	}
+	q.mu.Unlock()
+	q.changed()
+}
+
+// setLive 标记正在运行的任务为直播录制。
+func (q *DownloadQueue) setLive(id int) {
+	q.mu.Lock()
+	for _, job := range q.jobs {
+		if job.ID == id && job.Status == JobRunning && !job.Live {
+			job.Live = true
+			job.Detail = "直播录制中"
+		}
+	}
+	q.mu.Unlock()
+	q.changed()
+}
+
+// setLogFile 记录任务日志的文件名。
+func (q *DownloadQueue) setLogFile(id int, name string) {
+	q.mu.Lock()
+	for _, job := range q.jobs {
+		if job.ID == id {
+			job.LogFile = name
+		}
+	}
+	q.mu.Unlock()
+	q.changed()
+}
+
+// ClearFinished 移除已结束的任务。
+func (q *DownloadQueue) ClearFinished() {
+	q.mu.Lock()
+	kept := q.jobs[:0]
+	for _, job := range q.jobs {
+		if !job.Status.finished() {
+			kept = append(kept, job)
+		}
+	}
+	q.jobs = kept
+	q.mu.Unlock()
+	q.changed()
+}
+
+// Snapshot 返回所有任务的副本。
+func (q *DownloadQueue) Snapshot() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, len(q.jobs))
+	for i, job := range q.jobs {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+// OnChange 注册任务状态变化时的回调，回调在队列的协程中执行。
+func (q *DownloadQueue) OnChange(fn func()) {
+	q.mu.Lock()
+	q.listeners = append(q.listeners, fn)
+	q.mu.Unlock()
+}
+
+// Wake 让工作协程重新评估队列，用于设置变更后立即生效。
+func (q *DownloadQueue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *DownloadQueue) changed() {
+	q.mu.Lock()
+	listeners := append([]func(){}, q.listeners...)
+	q.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// next 返回最早可以开始的待执行任务及其开始时刻和限速，开始时刻相同时按加入顺序。
+// 尚不能开始的任务标记为等待中，changed 表示是否有任务的状态因此改变。
+func (q *DownloadQueue) next(now time.Time) (job *Job, start time.Time, rateLimit string, changed bool) {
+	schedule := q.schedule()
+	for _, j := range q.jobs {
+		if j.Status != JobQueued && j.Status != JobScheduled && j.Status != JobPaused {
+			continue
+		}
+		s, rate := schedule.NextStart(now, j.NotBefore)
+		if s.After(now) {
+			detail := "等待至 " + s.Format("01-02 15:04")
+			if j.Status != JobScheduled || j.Detail != detail {
+				j.Status = JobScheduled
+				j.Detail = detail
+				changed = true
+			}
+		}
+		if job == nil || s.Before(start) {
+			job, start, rateLimit = j, s, rate
+		}
+	}
+	return job, start, rateLimit, changed
+}
+
+func (q *DownloadQueue) loop() {
+	for {
+		q.mu.Lock()
+		now := q.clock.Now()
+		job, start, rateLimit, changed := q.next(now)
+		if job == nil {
+			q.mu.Unlock()
+			<-q.wake
+			continue
+		}
+
+		// 没有任务可以立即开始时等待到最早的开始时刻
+		if start.After(now) {
+			q.mu.Unlock()
+			if changed {
+				q.changed()
+			}
+			wait := start.Sub(now)
+			if wait > queueRecheckInterval {
+				wait = queueRecheckInterval
+			}
+			select {
+			case <-q.clock.After(wait):
+			case <-q.wake:
+			}
+			continue
+		}
+
+		if err := q.checkSpace(job.EstimatedSize); err != nil {
+			notify := changed || job.Status != JobPaused || job.Detail != err.Error()
+			job.Status = JobPaused
+			job.Detail = err.Error()
+			q.mu.Unlock()
+			if notify {
+				q.changed()
+			}
+			select {
+			case <-q.clock.After(diskCheckInterval):
+			case <-q.wake:
+			}
+			continue
+		}
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		job.Status = JobRunning
+		job.Detail = ""
+		if rateLimit != "" {
+			job.Detail = "限速 " + rateLimit
+		}
+		stopCh := make(chan struct{})
+		q.cancel = cancel
+		q.stop = sync.OnceFunc(func() { close(stopCh) })
+		snapshot := *job
+		q.mu.Unlock()
+		q.changed()
+
+		if q.space != nil {
+			go q.monitorSpace(ctx, cancel)
+		}
+		err := q.run(ctx, snapshot, rateLimit, JobControl{
+			Stop:       stopCh,
+			SetLive:    func() { q.setLive(snapshot.ID) },
+			SetLogFile: func(name string) { q.setLogFile(snapshot.ID, name) },
+		})
+
+		q.mu.Lock()
+		var lowSpace *LowDiskSpaceError
+		switch {
+		case errors.As(context.Cause(ctx), &lowSpace):
+			// 空间恢复后重新开始，yt-dlp 会继续未完成的下载
+			job.Status = JobPaused
+			job.Detail = lowSpace.Error()
+			job.Live = false
+		case ctx.Err() != nil || errors.Is(err, context.Canceled):
+			job.Status = JobCancelled
+			job.Detail = ""
+		case err != nil:
+			job.Status = JobFailed
+			job.Detail = err.Error()
+		default:
+			job.Status = JobDone
+			job.Detail = ""
+		}
+		cancel(nil)
+		q.cancel = nil
+		q.stop = nil
+		q.mu.Unlock()
+		q.changed()
+	}
+}
+
+// checkSpace 检查剩余空间，未设置检查时总是通过。
+func (q *DownloadQueue) checkSpace(size int64) error {
+	if q.space == nil {
+		return nil
+	}
+	return q.space(size)
+}
+
+// monitorSpace 在任务运行期间定期检查剩余空间，不足时以 *LowDiskSpaceError 取消任务。
+func (q *DownloadQueue) monitorSpace(ctx context.Context, cancel context.CancelCauseFunc) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.clock.After(diskCheckInterval):
+		}
+		if err := q.space(0); err != nil {
+			cancel(err)
+			return
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor 轮询直到 cond 成立，超时则测试失败。
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func jobStatus(q *DownloadQueue, id int) JobStatus {
+	for _, job := range q.Snapshot() {
+		if job.ID == id {
+			return job.Status
+		}
+	}
+	return -1
+}
+
+func TestDownloadQueueRunsInOrder(t *testing.T) {
+	clock := newFakeClock(at(10, 0))
+	started := make(chan int, 10)
+	release := make(chan struct{})
+	q := NewDownloadQueue(clock, func() ScheduleConfig { return ScheduleConfig{} },
+		func(ctx context.Context, job Job, rateLimit string, ctl JobControl) error {
+			started <- job.ID
+			<-release
+			if job.URL == "bad" {
+				return errors.New("exit status 1")
+			}
+			return nil
+		}, nil)
+	q.Start()
+
+	a := q.Add("a", nil, time.Time{})
+	b := q.Add("bad", nil, time.Time{})
+
+	if id := <-started; id != a.ID {
+		t.Fatalf("第一个任务应为 #%d，实际 #%d", a.ID, id)
+	}
+	if s := jobStatus(q, b.ID); s != JobQueued {
+		t.Fatalf("第二个任务在第一个结束前状态应为排队中，实际 %s", s)
+	}
+	release <- struct{}{}
+	if id := <-started; id != b.ID {
+		t.Fatalf("第二个任务应为 #%d，实际 #%d", b.ID, id)
+	}
+	release <- struct{}{}
+	waitFor(t, "第二个任务失败", func() bool { return jobStatus(q, b.ID) == JobFailed })
+	if s := jobStatus(q, a.ID); s != JobDone {
+		t.Errorf("第一个任务状态 = %s, want 已完成", s)
+	}
+
+	q.ClearFinished()
+	if jobs := q.Snapshot(); len(jobs) != 0 {
+		t.Errorf("清除后仍有任务: %+v", jobs)
+	}
+}
+
+func TestDownloadQueueWaitsForWindow(t *testing.T) {
+	clock := newFakeClock(at(20, 0))
+	schedule := ScheduleConfig{Enabled: true, Windows: []ScheduleWindow{{Start: 22 * 60, End: 7 * 60, RateLimit: "2M"}}}
+	rates := make(chan string, 1)
+	q := NewDownloadQueue(clock, func() ScheduleConfig { return schedule },
+		func(ctx context.Context, job Job, rateLimit string, ctl JobControl) error {
+			rates <- rateLimit
+			return nil
+		}, nil)
+	q.Start()
+
+	job := q.Add("a", nil, time.Time{})
+	waitFor(t, "任务进入等待", func() bool { return jobStatus(q, job.ID) == JobScheduled && clock.Waiters() > 0 })
+
+	// 等待时按固定间隔重新检查，推进到窗口开始前不应启动
+	for clock.Now().Before(at(21, 59)) {
+		waitFor(t, "等待定时器", func() bool { return clock.Waiters() > 0 })
+		clock.Advance(queueRecheckInterval)
+	}
+	select {
+	case <-rates:
+		t.Fatal("窗口开始前不应启动任务")
+	default:
+	}
+
+	waitFor(t, "等待定时器", func() bool { return clock.Waiters() > 0 })
+	clock.Advance(queueRecheckInterval)
+	select {
+	case rate := <-rates:
+		if rate != "2M" {
+			t.Errorf("rateLimit = %q, want 2M", rate)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("到达窗口后任务未启动")
+	}
+	waitFor(t, "任务完成", func() bool { return jobStatus(q, job.ID) == JobDone })
+}
+
+func TestDownloadQueueNotBefore(t *testing.T) {
+	clock := newFakeClock(at(10, 0))
+	ran := make(chan struct{}, 1)
+	q := NewDownloadQueue(clock, func() ScheduleConfig { return ScheduleConfig{} },
+		func(ctx context.Context, job Job, rateLimit string, ctl JobControl) error {
+			ran <- struct{}{}
+			return nil
+		}, nil)
+	q.Start()
+
+	job := q.Add("a", nil, at(10, 0).Add(30*time.Second))
+	waitFor(t, "任务进入等待", func() bool { return jobStatus(q, job.ID) == JobScheduled && clock.Waiters() > 0 })
+	clock.Advance(30 * time.Second)
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("到达开始时间后任务未启动")
+	}
+}
+
+func TestDownloadQueueNotBeforeDoesNotBlock(t *testing.T) {
+	clock := newFakeClock(at(10, 0))
+	started := make(chan int, 2)
+	q := NewDownloadQueue(clock, func() ScheduleConfig { return ScheduleConfig{} },
+		func(ctx context.Context, job Job, rateLimit string, ctl JobControl) error {
+			started <- job.ID
+			return nil
+		}, nil)
+	q.Start()
+
+	later := q.Add("later", nil, at(11, 0))
+	now := q.Add("now", nil, time.Time{})
+	select {
+	case id := <-started:
+		if id != now.ID {
+			t.Fatalf("应先执行可立即开始的任务 #%d，实际 #%d", now.ID, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("可立即开始的任务未启动")
+	}
+	waitFor(t, "定时任务进入等待", func() bool { return jobStatus(q, later.ID) == JobScheduled && clock.Waiters() > 0 })
+
+	for clock.Now().Before(at(11, 0)) {
+		waitFor(t, "等待定时器", func() bool { return clock.Waiters() > 0 })
+		clock.Advance(queueRecheckInterval)
+	}
+	select {
+	case id := <-started:
+		if id != later.ID {
+			t.Fatalf("第二个启动的任务应为 #%d，实际 #%d", later.ID, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("到达开始时间后定时任务未启动")
+	}
+}
+
+func TestDownloadQueueCancel(t *testing.T) {
+	clock := newFakeClock(at(10, 0))
+	started := make(chan struct{}, 1)
+	q := NewDownloadQueue(clock, func() ScheduleConfig { return ScheduleConfig{} },
+		func(ctx context.Context, job Job, rateLimit string, ctl JobControl) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}, nil)
+	q.Start()
+
+	running := q.Add("a", nil, time.Time{})
+	waiting := q.Add("b", nil, time.Time{})
+	<-started
+
+	q.Cancel(waiting.ID)
+	if s := jobStatus(q, waiting.ID); s != JobCancelled {
+		t.Fatalf("排队任务状态 = %s, want 已取消", s)
+	}
+	q.Cancel(running.ID)
+	waitFor(t, "运行中的任务被取消", func() bool { return jobStatus(q, running.ID) == JobCancelled })
+
+	select {
+	case <-started:
+		t.Fatal("已取消的排队任务不应启动")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDownloadQueueStopLive(t *testing.T) {
+	clock := newFakeClock(at(10, 0))
+	started := make(chan JobControl, 1)
+	q := NewDownloadQueue(clock, func() ScheduleConfig { return ScheduleConfig{} },
+		func(ctx context.Context, job Job, rateLimit string, ctl JobControl) error {
+			started <- ctl
+			<-ctl.Stop
+			return nil
+		}, nil)
+	q.Start()
+
+	job := q.Add("live", nil, time.Time{})
+	ctl := <-started
+
+	// 未标记为直播时不响应停止录制
+	q.Stop(job.ID)
+	select {
+	case <-ctl.Stop:
+		t.Fatal("非直播任务不应被停止录制")
+	default:
+	}
+
+	ctl.SetLive()
+	ctl.SetLogFile("job-20260101-100000-1.log")
+	waitFor(t, "任务标记为直播", func() bool {
+		jobs := q.Snapshot()
+		return len(jobs) == 1 && jobs[0].Live && jobs[0].LogFile == "job-20260101-100000-1.log"
+	})
+	q.Stop(job.ID)
+	q.Stop(job.ID) // 重复请求不应出错
+	waitFor(t, "停止录制后任务完成", func() bool { return jobStatus(q, job.ID) == JobDone })
+}
+
+func TestDownloadQueueDiskSpace(t *testing.T) {
+	clock := newFakeClock(at(10, 0))
+	var low atomic.Bool
+	var checkedSize atomic.Int64
+	space := func(size int64) error {
+		if size > 0 {
+			checkedSize.Store(size)
+		}
+		if low.Load() {
+			return &LowDiskSpaceError{Free: 1, Need: 2}
+		}
+		return nil
+	}
+	started := make(chan struct{}, 10)
+	q := NewDownloadQueue(clock, func() ScheduleConfig { return ScheduleConfig{} },
+		func(ctx context.Context, job Job, rateLimit string, ctl JobControl) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}, space)
+	q.Start()
+
+	// 开始前空间不足：暂停，空间恢复后开始
+	low.Store(true)
+	job := q.AddJob(Job{URL: "a", EstimatedSize: 123})
+	waitFor(t, "任务暂停", func() bool { return jobStatus(q, job.ID) == JobPaused && clock.Waiters() > 0 })
+	if got := checkedSize.Load(); got != 123 {
+		t.Errorf("开始前检查的大小 = %d, want 123", got)
+	}
+	low.Store(false)
+	clock.Advance(diskCheckInterval)
+	<-started
+	waitFor(t, "任务开始", func() bool { return jobStatus(q, job.ID) == JobRunning })
+
+	// 下载过程中空间不足：终止下载并暂停，空间恢复后重新开始
+	low.Store(true)
+	waitFor(t, "监控等待中", func() bool { return clock.Waiters() > 0 })
+	clock.Advance(diskCheckInterval)
+	waitFor(t, "下载中暂停", func() bool { return jobStatus(q, job.ID) == JobPaused })
+	low.Store(false)
+	waitFor(t, "暂停后等待重试", func() bool { return clock.Waiters() > 0 })
+	clock.Advance(diskCheckInterval)
+	<-started
+	waitFor(t, "任务重新开始", func() bool { return jobStatus(q, job.ID) == JobRunning })
+
+	q.Cancel(job.ID)
+	waitFor(t, "任务取消", func() bool { return jobStatus(q, job.ID) == JobCancelled })
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newQueueView 创建下载队列列表，显示每个任务的状态并可取消未结束的任务，
+// 正在录制直播的任务可停止录制并保存已录制的内容，已开始的任务可打开其日志（位于 logsDir）。
+// onCleanup 打开残留文件清理工具。
+func newQueueView(w fyne.Window, queue *DownloadQueue, logsDir string, onCleanup func()) fyne.CanvasObject {
+	var jobs []Job
+
+	list := widget.NewList(
+		func() int { return len(jobs) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			buttons := container.NewHBox(widget.NewButton("停止录制", nil), widget.NewButton("打开日志", nil), widget.NewButton("取消", nil))
+			return container.NewBorder(nil, nil, nil, buttons, label)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(jobs) {
+				return
+			}
+			job := jobs[id]
+			row := obj.(*fyne.Container)
+			label := row.Objects[0].(*widget.Label)
+			buttons := row.Objects[1].(*fyne.Container)
+			stopBtn := buttons.Objects[0].(*widget.Button)
+			logBtn := buttons.Objects[1].(*widget.Button)
+			cancelBtn := buttons.Objects[2].(*widget.Button)
+
+			text := fmt.Sprintf("#%d  [%s]  %s", job.ID, job.Status, job.URL)
+			if job.Detail != "" {
+				text += "  (" + job.Detail + ")"
+			}
+			switch job.Status {
+			case JobFailed:
+				label.Importance = widget.DangerImportance
+			case JobDone, JobCancelled:
+				label.Importance = widget.LowImportance
+			default:
+				label.Importance = widget.MediumImportance
+			}
+			label.SetText(text)
+
+			stopBtn.OnTapped = func() { queue.Stop(job.ID) }
+			if job.Live && job.Status == JobRunning {
+				stopBtn.Show()
+			} else {
+				stopBtn.Hide()
+			}
+			logBtn.OnTapped = func() {
+				p, ok := existingJobLog(logsDir, job.LogFile)
+				if !ok {
+					dialog.ShowInformation("打开日志", "任务日志不存在，可能已被清理", w)
+					return
+				}
+				if err := openLocalPath(p); err != nil {
+					dialog.ShowError(fmt.Errorf("无法打开任务日志: %v", err), w)
+				}
+			}
+			if job.LogFile != "" {
+				logBtn.Enable()
+			} else {
+				logBtn.Disable()
+			}
+			cancelBtn.OnTapped = func() { queue.Cancel(job.ID) }
+			if job.Status.finished() {
+				cancelBtn.Disable()
+			} else {
+				cancelBtn.Enable()
+			}
+		},
+	)
+
+	refresh := func() {
+		jobs = queue.Snapshot()
+		list.Refresh()
+	}
+	queue.OnChange(func() { fyne.Do(refresh) })
+
+	clearBtn := widget.NewButton("清除已结束", queue.ClearFinished)
+	cleanupBtn := widget.NewButton("清理未完成的文件...", onCleanup)
+	toolbar := container.NewHBox(layout.NewSpacer(), cleanupBtn, clearBtn)
+	return container.NewBorder(toolbar, nil, nil, nil, list)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Clock 抽象当前时间和定时等待，便于测试调度逻辑。
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+var rateLimitPattern = regexp.MustCompile(`^[0-9]+(?:\.[0-9]+)?[KMGkmg]?$`)
+
+// ScheduleWindow 为每天允许开始下载的时间段，Start/End 为距零点的分钟数。
+// End 小于 Start 表示跨越午夜，两者相等表示全天。RateLimit 非空时作为 --limit-rate 传给 yt-dlp。
+type ScheduleWindow struct {
+	Start     int
+	End       int
+	RateLimit string
+}
+
+// ScheduleConfig 为下载时间窗口设置。未启用或没有时间段时不限制开始时间。
+type ScheduleConfig struct {
+	Enabled bool
+	Windows []ScheduleWindow
+}
+
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// contains 判断 t 是否落在时间段内。
+func (w ScheduleWindow) contains(t time.Time) bool {
+	m := minuteOfDay(t)
+	switch {
+	case w.Start == w.End:
+		return true
+	case w.Start < w.End:
+		return m >= w.Start && m < w.End
+	default:
+		return m >= w.Start || m < w.End
+	}
+}
+
+// nextStartAfter 返回 t 之后（含 t 所在分钟之后）该时间段下一次开始的时刻。
+func (w ScheduleWindow) nextStartAfter(t time.Time) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), w.Start/60, w.Start%60, 0, 0, t.Location())
+	if !start.After(t) {
+		start = start.AddDate(0, 0, 1)
+	}
+	return start
+}
+
+func (w ScheduleWindow) String() string {
+	s := fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+	if w.RateLimit != "" {
+		s += "@" + w.RateLimit
+	}
+	return s
+}
+
+// NextStart 返回不早于 notBefore 的最早可开始下载的时刻及该时刻适用的限速。
+func (c ScheduleConfig) NextStart(now, notBefore time.Time) (time.Time, string) {
+	t := now
+	if notBefore.After(t) {
+		t = notBefore
+	}
+	if !c.Enabled || len(c.Windows) == 0 {
+		return t, ""
+	}
+	for _, w := range c.Windows {
+		if w.contains(t) {
+			return t, w.RateLimit
+		}
+	}
+	var best time.Time
+	var rate string
+	for _, w := range c.Windows {
+		if next := w.nextStartAfter(t); best.IsZero() || next.Before(best) {
+			best = next
+			rate = w.RateLimit
+		}
+	}
+	return best, rate
+}
+
+// ParseScheduleWindows 解析以逗号或换行分隔的时间段，如 "22:00-07:00@2M, 12:00-13:00"。
+func ParseScheduleWindows(s string) ([]ScheduleWindow, error) {
+	var windows []ScheduleWindow
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' || r == '\n' }) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var w ScheduleWindow
+		span := part
+		if idx := strings.Index(part, "@"); idx >= 0 {
+			span = strings.TrimSpace(part[:idx])
+			w.RateLimit = strings.TrimSpace(part[idx+1:])
+			if !rateLimitPattern.MatchString(w.RateLimit) {
+				return nil, fmt.Errorf("限速格式无效: %s（示例：500K、2M）", w.RateLimit)
+			}
+		}
+		bounds := strings.Split(span, "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("时间段格式无效: %s（示例：22:00-07:00）", part)
+		}
+		var err error
+		if w.Start, err = parseClock(bounds[0]); err != nil {
+			return nil, err
+		}
+		if w.End, err = parseClock(bounds[1]); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// FormatScheduleWindows 将时间段格式化为 ParseScheduleWindows 可解析的字符串。
+func FormatScheduleWindows(windows []ScheduleWindow) string {
+	parts := make([]string, len(windows))
+	for i, w := range windows {
+		parts[i] = w.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseClock 解析 "HH:MM" 为距零点的分钟数，允许 "24:00" 表示午夜。
+func parseClock(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	var h, m int
+	if n, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || n != 2 || len(s) < 4 {
+		return 0, fmt.Errorf("时间格式无效: %s（示例：07:30）", s)
+	}
+	if h == 24 && m == 0 {
+		return 0, nil
+	}
+	if h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("时间超出范围: %s", s)
+	}
+	return h*60 + m, nil
+}
+
+// nextClockTime 返回 now 之后第一次到达 "HH:MM" 的时刻，用于定时下载。
+func nextClockTime(now time.Time, clock string) (time.Time, error) {
+	m, err := parseClock(clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ScheduleWindow{Start: m}.nextStartAfter(now), nil
+}
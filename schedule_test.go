@@ -0,0 +1,155 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 为可手动推进的时钟，After 的通道在推进到期后触发。
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance 推进时钟并触发到期的等待。
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	kept := c.waiters[:0]
+	for _, w := range c.waiters {
+		if !w.at.After(c.now) {
+			w.ch <- c.now
+		} else {
+			kept = append(kept, w)
+		}
+	}
+	c.waiters = kept
+}
+
+// Waiters 返回尚未到期的等待数。
+func (c *fakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func at(hour, min int) time.Time {
+	return time.Date(2024, 5, 1, hour, min, 0, 0, time.Local)
+}
+
+func TestParseScheduleWindows(t *testing.T) {
+	windows, err := ParseScheduleWindows(" 22:00-07:00@2M，12:00-13:30\n09:15-09:45@500K ")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	want := []ScheduleWindow{
+		{Start: 22 * 60, End: 7 * 60, RateLimit: "2M"},
+		{Start: 12 * 60, End: 13*60 + 30},
+		{Start: 9*60 + 15, End: 9*60 + 45, RateLimit: "500K"},
+	}
+	if len(windows) != len(want) {
+		t.Fatalf("windows = %+v, want %+v", windows, want)
+	}
+	for i := range want {
+		if windows[i] != want[i] {
+			t.Errorf("windows[%d] = %+v, want %+v", i, windows[i], want[i])
+		}
+	}
+	if got := FormatScheduleWindows(windows); got != "22:00-07:00@2M,12:00-13:30,09:15-09:45@500K" {
+		t.Errorf("FormatScheduleWindows = %q", got)
+	}
+
+	for _, bad := range []string{"22:00", "25:00-07:00", "22:00-07:00@fast", "aa:bb-07:00", "22:00-07:60"} {
+		if _, err := ParseScheduleWindows(bad); err == nil {
+			t.Errorf("ParseScheduleWindows(%q) 应返回错误", bad)
+		}
+	}
+}
+
+func TestScheduleNextStart(t *testing.T) {
+	cfg := ScheduleConfig{
+		Enabled: true,
+		Windows: []ScheduleWindow{
+			{Start: 22 * 60, End: 7 * 60, RateLimit: "2M"},
+			{Start: 12 * 60, End: 13 * 60},
+		},
+	}
+	tests := []struct {
+		name      string
+		cfg       ScheduleConfig
+		now       time.Time
+		notBefore time.Time
+		wantStart time.Time
+		wantRate  string
+	}{
+		{"disabled", ScheduleConfig{Windows: cfg.Windows}, at(10, 0), time.Time{}, at(10, 0), ""},
+		{"no windows", ScheduleConfig{Enabled: true}, at(10, 0), time.Time{}, at(10, 0), ""},
+		{"inside overnight window after midnight", cfg, at(3, 0), time.Time{}, at(3, 0), "2M"},
+		{"inside overnight window before midnight", cfg, at(23, 0), time.Time{}, at(23, 0), "2M"},
+		{"inside daytime window", cfg, at(12, 30), time.Time{}, at(12, 30), ""},
+		{"window end is exclusive", cfg, at(7, 0), time.Time{}, at(12, 0), ""},
+		{"wait for next window", cfg, at(14, 0), time.Time{}, at(22, 0), "2M"},
+		{"not before inside window", cfg, at(8, 0), at(12, 15), at(12, 15), ""},
+		{"not before outside window", cfg, at(8, 0), at(13, 30), at(22, 0), "2M"},
+		{"not before without windows", ScheduleConfig{}, at(8, 0), at(9, 0), at(9, 0), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, rate := tt.cfg.NextStart(tt.now, tt.notBefore)
+			if !start.Equal(tt.wantStart) || rate != tt.wantRate {
+				t.Errorf("NextStart = %v %q, want %v %q", start, rate, tt.wantStart, tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestScheduleNextStartWrapsToTomorrow(t *testing.T) {
+	cfg := ScheduleConfig{Enabled: true, Windows: []ScheduleWindow{{Start: 1 * 60, End: 5 * 60}}}
+	start, _ := cfg.NextStart(at(6, 0), time.Time{})
+	if want := at(1, 0).AddDate(0, 0, 1); !start.Equal(want) {
+		t.Errorf("NextStart = %v, want %v", start, want)
+	}
+}
+
+func TestNextClockTime(t *testing.T) {
+	got, err := nextClockTime(at(10, 0), "02:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := at(2, 30).AddDate(0, 0, 1); !got.Equal(want) {
+		t.Errorf("nextClockTime = %v, want %v", got, want)
+	}
+	got, _ = nextClockTime(at(10, 0), "10:30")
+	if !got.Equal(at(10, 30)) {
+		t.Errorf("nextClockTime = %v, want %v", got, at(10, 30))
+	}
+	if _, err := nextClockTime(at(10, 0), "later"); err == nil {
+		t.Error("无效时间应返回错误")
+	}
+}
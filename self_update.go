@@ -1,13 +1,13 @@
 package main
 
 import (
-	"crypto/sha256"
+	"context"
+	"crypto/ed25519"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -22,7 +22,15 @@ import (
 
 const appLatestReleaseAPI = "https://api.github.com/repos/Yinr/yt-dlp-simpgo/releases/latest"
 
-var semverPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)$`)
+// appReleasesAPI 为最近发布的列表，包含预发布版本。
+const appReleasesAPI = "https://api.github.com/repos/Yinr/yt-dlp-simpgo/releases?per_page=20"
+
+// semverPattern 匹配发布版本号，预发布版本只接受 alpha、beta、rc，
+// 以排除 git describe 生成的开发版本号（如 v1.0.0-3-gabcdef、v1.0.0-dirty）。
+var semverPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-(alpha|beta|rc)\.?(\d*))?$`)
+
+// prereleaseRanks 为预发布阶段的先后顺序，正式版本排在所有预发布版本之后。
+var prereleaseRanks = map[string]int{"alpha": 0, "beta": 1, "rc": 2, "": 3}
 
 type AppUpdateInfo struct {
 	CurrentVersion string
@@ -30,13 +38,19 @@ type AppUpdateInfo struct {
 	AssetName      string
 	AssetURL       string
 	Digest         string
+	SignatureURL   string // 签名文件地址，发布中没有签名文件时为空
 	ReleaseURL     string
+	ReleaseNotes   string // 发布说明（Markdown）
+	Prerelease     bool
 }
 
 type appRelease struct {
-	TagName string            `json:"tag_name"`
-	HTMLURL string            `json:"html_url"`
-	Assets  []appReleaseAsset `json:"assets"`
+	TagName    string            `json:"tag_name"`
+	HTMLURL    string            `json:"html_url"`
+	Body       string            `json:"body"`
+	Draft      bool              `json:"draft"`
+	Prerelease bool              `json:"prerelease"`
+	Assets     []appReleaseAsset `json:"assets"`
 }
 
 type appReleaseAsset struct {
@@ -45,17 +59,6 @@ type appReleaseAsset struct {
 	Digest             string `json:"digest"`
 }
 
-func appHTTPClient(proxy string, timeout time.Duration) *http.Client {
-	client := &http.Client{Timeout: timeout}
-	if proxy != "" {
-		proxyURL, err := url.Parse(proxy)
-		if err == nil {
-			client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
-		}
-	}
-	return client
-}
-
 func currentPlatformAssetName() string {
 	name := "yt-dlp-simpgo-" + runtime.GOOS + "-" + runtime.GOARCH
 	if runtime.GOOS == "windows" {
@@ -64,8 +67,9 @@ func currentPlatformAssetName() string {
 	return name
 }
 
-func parseSemver(version string) ([3]int, bool) {
-	var parts [3]int
+// parseSemver 解析版本号，返回主、次、修订版本号，预发布阶段和预发布序号。
+func parseSemver(version string) ([5]int, bool) {
+	var parts [5]int
 	matches := semverPattern.FindStringSubmatch(strings.TrimSpace(version))
 	if matches == nil {
 		return parts, false
@@ -77,6 +81,14 @@ func parseSemver(version string) ([3]int, bool) {
 		}
 		parts[i] = value
 	}
+	parts[3] = prereleaseRanks[matches[4]]
+	if matches[5] != "" {
+		value, err := strconv.Atoi(matches[5])
+		if err != nil {
+			return parts, false
+		}
+		parts[4] = value
+	}
 	return parts, true
 }
 
@@ -89,7 +101,7 @@ func compareSemver(a, b string) (int, bool) {
 	if !ok {
 		return 0, false
 	}
-	for i := 0; i < 3; i++ {
+	for i := range av {
 		if av[i] < bv[i] {
 			return -1, true
 		}
@@ -100,31 +112,36 @@ func compareSemver(a, b string) (int, bool) {
 	return 0, true
 }
 
-func CheckAppUpdate(currentVersion string, proxy string) (*AppUpdateInfo, error) {
+// CheckAppUpdate 检查程序是否有新版本，没有新版本或当前为开发版本时返回 nil。
+// prerelease 为 true 时包含预发布版本。
+func CheckAppUpdate(currentVersion string, src UpdateSource, prerelease bool) (*AppUpdateInfo, error) {
 	if _, ok := parseSemver(currentVersion); !ok {
 		return nil, nil
 	}
 
-	client := appHTTPClient(proxy, 30*time.Second)
-	req, err := http.NewRequest(http.MethodGet, appLatestReleaseAPI, nil)
-	if err != nil {
-		return nil, fmt.Errorf("创建更新检查请求失败: %w", err)
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("User-Agent", "yt-dlp-simpgo/"+currentVersion)
+	client := appHTTPClient(src.Proxy, 30*time.Second)
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("User-Agent", "yt-dlp-simpgo/"+currentVersion)
 
-	resp, err := client.Do(req)
+	api := appLatestReleaseAPI
+	if prerelease {
+		api = appReleasesAPI
+	}
+	resp, err := getWithFallback(client, src.Mirrors.APIURLs(api), header)
 	if err != nil {
 		return nil, fmt.Errorf("检查程序更新失败: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API 返回错误: %s", resp.Status)
-	}
-
 	var release appRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+	if prerelease {
+		var releases []appRelease
+		if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+			return nil, fmt.Errorf("解析程序更新响应失败: %w", err)
+		}
+		release = newestRelease(releases)
+	} else if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
 		return nil, fmt.Errorf("解析程序更新响应失败: %w", err)
 	}
 
@@ -134,69 +151,104 @@ func CheckAppUpdate(currentVersion string, proxy string) (*AppUpdateInfo, error)
 	}
 
 	assetName := currentPlatformAssetName()
+	var info *AppUpdateInfo
 	for _, asset := range release.Assets {
 		if asset.Name == assetName && asset.BrowserDownloadURL != "" {
-			return &AppUpdateInfo{
+			info = &AppUpdateInfo{
 				CurrentVersion: currentVersion,
 				LatestVersion:  release.TagName,
 				AssetName:      asset.Name,
 				AssetURL:       asset.BrowserDownloadURL,
 				Digest:         asset.Digest,
 				ReleaseURL:     release.HTMLURL,
-			}, nil
+				ReleaseNotes:   release.Body,
+				Prerelease:     release.Prerelease,
+			}
+			break
+		}
+	}
+	if info == nil {
+		return nil, fmt.Errorf("未找到当前平台更新文件: %s", assetName)
+	}
+	for _, asset := range release.Assets {
+		if asset.Name == assetName+utils.UpdateSignatureExt {
+			info.SignatureURL = asset.BrowserDownloadURL
 		}
 	}
+	return info, nil
+}
 
-	return nil, fmt.Errorf("未找到当前平台更新文件: %s", assetName)
+// newestRelease 返回版本号最新的发布，忽略草稿和无法解析版本号的发布。
+func newestRelease(releases []appRelease) appRelease {
+	var newest appRelease
+	for _, r := range releases {
+		if r.Draft {
+			continue
+		}
+		if _, ok := parseSemver(r.TagName); !ok {
+			continue
+		}
+		if cmp, ok := compareSemver(r.TagName, newest.TagName); !ok || cmp > 0 {
+			newest = r
+		}
+	}
+	return newest
 }
 
-func DownloadAppUpdate(info *AppUpdateInfo, proxy string, onProgress func(received, total int64)) (string, error) {
+// DownloadAppUpdate 下载更新文件并用内置公钥校验签名，返回下载的文件路径。
+// allowUnsigned 为 false 时拒绝没有签名或无法校验签名的更新。
+func DownloadAppUpdate(info *AppUpdateInfo, src UpdateSource, allowUnsigned bool, onProgress func(received, total int64)) (string, error) {
 	exePath, err := os.Executable()
 	if err != nil {
 		return "", fmt.Errorf("无法定位当前程序: %w", err)
 	}
+	return downloadAppUpdateTo(filepath.Dir(exePath), info, src, UpdatePublicKey, allowUnsigned, onProgress)
+}
 
-	ext := filepath.Ext(info.AssetName)
-	tmp, err := os.CreateTemp(filepath.Dir(exePath), ".yt-dlp-simpgo-update-*"+ext)
-	if err != nil {
-		return "", fmt.Errorf("无法创建临时更新文件: %w", err)
-	}
-	tmpPath := tmp.Name()
-	defer tmp.Close()
-
-	client := appHTTPClient(proxy, 10*time.Minute)
-	req, err := http.NewRequest(http.MethodGet, info.AssetURL, nil)
-	if err != nil {
-		_ = os.Remove(tmpPath)
-		return "", fmt.Errorf("创建更新下载请求失败: %w", err)
-	}
-	req.Header.Set("User-Agent", "yt-dlp-simpgo/"+Version)
-
-	resp, err := client.Do(req)
+func downloadAppUpdateTo(dir string, info *AppUpdateInfo, src UpdateSource, publicKey string, allowUnsigned bool, onProgress func(received, total int64)) (string, error) {
+	// 先获取签名，避免下载完才发现无法校验
+	var key ed25519.PublicKey
+	if publicKey != "" {
+		k, err := utils.ParseUpdatePublicKey(publicKey)
+		if err != nil {
+			return "", err
+		}
+		key = k
+	}
+	var sig []byte
+	switch {
+	case key == nil && !allowUnsigned:
+		return "", fmt.Errorf("当前程序未内置更新签名公钥，无法校验更新，请从发布页手动下载")
+	case key == nil:
+		// 允许未签名的更新，无法校验签名
+	case info.SignatureURL == "" && !allowUnsigned:
+		return "", fmt.Errorf("新版本 %s 没有签名文件，已拒绝更新", info.LatestVersion)
+	case info.SignatureURL != "":
+		s, err := fetchUpdateSignature(info, src)
+		if err != nil {
+			return "", err
+		}
+		sig = s
+	}
+
+	// 文件名固定，下载中断后再次下载同一版本时从断点继续
+	tmpPath := filepath.Join(dir, ".yt-dlp-simpgo-update-"+info.LatestVersion+filepath.Ext(info.AssetName))
+	err := newHTTPDownloader(src.Proxy).Download(context.Background(), httpDownload{
+		URLs:       src.Mirrors.DownloadURLs(info.AssetURL),
+		Dest:       tmpPath,
+		Digest:     info.Digest,
+		UserAgent:  "yt-dlp-simpgo/" + Version,
+		OnProgress: onProgress,
+	})
 	if err != nil {
-		_ = os.Remove(tmpPath)
 		return "", fmt.Errorf("下载程序更新失败: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		_ = os.Remove(tmpPath)
-		return "", fmt.Errorf("下载程序更新失败: %s", resp.Status)
-	}
 
-	hash := sha256.New()
-	if err := copyWithProgress(io.MultiWriter(tmp, hash), resp.Body, resp.ContentLength, onProgress); err != nil {
-		_ = os.Remove(tmpPath)
-		return "", fmt.Errorf("写入程序更新失败: %w", err)
-	}
-	if err := tmp.Close(); err != nil {
-		_ = os.Remove(tmpPath)
-		return "", fmt.Errorf("关闭程序更新文件失败: %w", err)
-	}
-
-	if err := verifyAssetDigest(info.Digest, hash.Sum(nil)); err != nil {
-		_ = os.Remove(tmpPath)
-		return "", err
+	if sig != nil {
+		if err := utils.VerifyUpdate(key, tmpPath, info.AssetName, info.LatestVersion, sig); err != nil {
+			_ = os.Remove(tmpPath)
+			return "", fmt.Errorf("程序更新签名校验失败: %w", err)
+		}
 	}
 
 	if runtime.GOOS != "windows" {
@@ -209,6 +261,23 @@ func DownloadAppUpdate(info *AppUpdateInfo, proxy string, onProgress func(receiv
 	return tmpPath, nil
 }
 
+// fetchUpdateSignature 下载更新文件的签名。
+func fetchUpdateSignature(info *AppUpdateInfo, src UpdateSource) ([]byte, error) {
+	header := http.Header{}
+	header.Set("User-Agent", "yt-dlp-simpgo/"+Version)
+	resp, err := getWithFallback(appHTTPClient(src.Proxy, 30*time.Second), src.Mirrors.DownloadURLs(info.SignatureURL), header)
+	if err != nil {
+		return nil, fmt.Errorf("下载程序更新签名失败: %w", err)
+	}
+	defer resp.Body.Close()
+	// 签名文件只有一行 base64，限制大小避免读入异常的响应
+	sig, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return nil, fmt.Errorf("下载程序更新签名失败: %w", err)
+	}
+	return sig, nil
+}
+
 func copyWithProgress(dst io.Writer, src io.Reader, total int64, onProgress func(received, total int64)) error {
 	buf := make([]byte, 32*1024)
 	var written int64
@@ -243,7 +312,7 @@ func verifyAssetDigest(digest string, sum []byte) error {
 	}
 	const prefix = "sha256:"
 	if !strings.HasPrefix(strings.ToLower(digest), prefix) {
-		return nil
+		return fmt.Errorf("程序更新校验失败: 不支持的摘要算法: %s", digest)
 	}
 	expected := strings.TrimPrefix(strings.ToLower(digest), prefix)
 	actual := hex.EncodeToString(sum)
@@ -253,63 +322,108 @@ func verifyAssetDigest(digest string, sum []byte) error {
 	return nil
 }
 
+// 安装程序更新时保留的旧版本和新版本启动成功时写入的确认文件
+const (
+	appBackupPrefix     = ".yt-dlp-simpgo-backup"
+	appUpdateReadyName  = ".yt-dlp-simpgo-update.ok"
+	appUpdateReadyEnv   = "YT_DLP_SIMPGO_UPDATE_READY"
+	appUpdateStartLimit = 30 * time.Second
+)
+
+// InstallAppUpdateAndRestart 用 updatePath 替换当前程序并启动新版本，旧版本保留为备份。
+// 新版本未在限定时间内确认启动成功（见 ConfirmAppUpdateStarted）时结束新版本并恢复旧版本，返回错误，
+// 当前程序可继续运行；返回 nil 时调用方应退出。
 func InstallAppUpdateAndRestart(updatePath string) error {
 	exePath, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("无法定位当前程序: %w", err)
 	}
-	exeDir := filepath.Dir(exePath)
-
-	if runtime.GOOS == "windows" {
-		return installAppUpdateWindows(updatePath, exePath, exeDir)
+	if p, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = p
 	}
+	return installAppUpdate(updatePath, exePath, startAppProcess, appUpdateStartLimit)
+}
 
-	if err := os.Rename(updatePath, exePath); err != nil {
-		return fmt.Errorf("替换程序失败: %w", err)
-	}
+// appBackupPath 返回 exePath 的备份路径，与程序在同一目录以便直接重命名。
+func appBackupPath(exePath string) string {
+	return filepath.Join(filepath.Dir(exePath), appBackupPrefix+filepath.Ext(exePath))
+}
+
+// startAppProcess 启动程序，通过环境变量告知确认文件的路径。
+// 返回的通道在进程退出时收到 Wait 的结果，kill 结束进程。
+func startAppProcess(exePath, readyPath string) (exited <-chan error, kill func(), err error) {
 	cmd := exec.Command(exePath)
-	cmd.Dir = exeDir
+	cmd.Dir = filepath.Dir(exePath)
+	cmd.Env = append(os.Environ(), appUpdateReadyEnv+"="+readyPath)
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("重启程序失败: %w", err)
+		return nil, nil, err
 	}
-	return nil
+	ch := make(chan error, 1)
+	go func() { ch <- cmd.Wait() }()
+	return ch, func() { _ = cmd.Process.Kill() }, nil
 }
 
-func installAppUpdateWindows(updatePath string, exePath string, exeDir string) error {
-	scriptPath := filepath.Join(exeDir, ".yt-dlp-simpgo-update.ps1")
-	script := fmt.Sprintf(`
-$ErrorActionPreference = 'Stop'
-$pidToWait = %d
-$source = %s
-$target = %s
-$workdir = %s
-Wait-Process -Id $pidToWait -ErrorAction SilentlyContinue
-Start-Sleep -Milliseconds 500
-for ($i = 0; $i -lt 20; $i++) {
-    try {
-        Move-Item -LiteralPath $source -Destination $target -Force
-        break
-    } catch {
-        if ($i -eq 19) { throw }
-        Start-Sleep -Milliseconds 300
-    }
-}
-Start-Process -FilePath $target -WorkingDirectory $workdir
-Remove-Item -LiteralPath $PSCommandPath -Force -ErrorAction SilentlyContinue
-`, os.Getpid(), powershellQuote(updatePath), powershellQuote(exePath), powershellQuote(exeDir))
+// installAppUpdate 将 exePath 重命名为备份，再将 updatePath 重命名为 exePath，然后启动并等待新版本确认。
+// Windows 上不能覆盖正在运行的程序，但可以重命名，因此不需要等当前进程退出。
+func installAppUpdate(updatePath, exePath string, start func(exePath, readyPath string) (<-chan error, func(), error), limit time.Duration) error {
+	backup := appBackupPath(exePath)
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("无法删除旧的备份: %w", err)
+	}
+	if err := os.Rename(exePath, backup); err != nil {
+		return fmt.Errorf("备份当前程序失败: %w", err)
+	}
+	rollback := func(cause error) error {
+		if err := replaceFile(backup, exePath); err != nil {
+			return fmt.Errorf("%w；恢复旧版本失败，请将 %s 改名为 %s: %v", cause, backup, exePath, err)
+		}
+		return fmt.Errorf("%w，已恢复当前版本", cause)
+	}
+	if err := os.Rename(updatePath, exePath); err != nil {
+		return rollback(fmt.Errorf("替换程序失败: %w", err))
+	}
 
-	if err := os.WriteFile(scriptPath, []byte(script), 0600); err != nil {
-		return fmt.Errorf("创建更新脚本失败: %w", err)
+	readyPath := filepath.Join(filepath.Dir(exePath), appUpdateReadyName)
+	_ = os.Remove(readyPath)
+	defer os.Remove(readyPath)
+	exited, kill, err := start(exePath, readyPath)
+	if err != nil {
+		return rollback(fmt.Errorf("启动新版本失败: %w", err))
 	}
 
-	cmd := utils.ExecCmd("powershell.exe", "-NoProfile", "-ExecutionPolicy", "Bypass", "-WindowStyle", "Hidden", "-File", scriptPath)
-	cmd.Dir = exeDir
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("启动更新脚本失败: %w", err)
+	deadline := time.After(limit)
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			if _, err := os.Stat(readyPath); err == nil {
+				return nil
+			}
+		case err := <-exited:
+			if _, serr := os.Stat(readyPath); serr == nil {
+				// 确认启动成功后才退出，不算失败
+				return nil
+			}
+			if err == nil {
+				err = fmt.Errorf("进程已退出")
+			}
+			return rollback(fmt.Errorf("新版本启动失败: %w", err))
+		case <-deadline:
+			kill()
+			<-exited
+			return rollback(fmt.Errorf("新版本未在 %s 内完成启动", limit))
+		}
 	}
-	return nil
 }
 
-func powershellQuote(value string) string {
-	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+// ConfirmAppUpdateStarted 在程序由更新启动时写入确认文件，告知更新程序新版本已正常启动。
+// 应在主窗口显示后调用。
+func ConfirmAppUpdateStarted() {
+	readyPath := os.Getenv(appUpdateReadyEnv)
+	if readyPath == "" {
+		return
+	}
+	os.Unsetenv(appUpdateReadyEnv)
+	_ = os.WriteFile(readyPath, []byte(Version), 0644)
 }
@@ -2,9 +2,21 @@ package main
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"yinr.cc/yt-dlp-simpgo/utils"
 )
 
 func TestCompareSemver(t *testing.T) {
@@ -19,6 +31,11 @@ func TestCompareSemver(t *testing.T) {
 		{"equal", "1.0.0", "v1.0.0", 0, true},
 		{"greater", "v1.2.0", "v1.1.9", 1, true},
 		{"dirty version", "v1.0.0-dirty", "v1.0.1", 0, false},
+		{"git describe version", "v1.0.0-3-gabcdef", "v1.0.1", 0, false},
+		{"prerelease before release", "v1.1.0-rc.1", "v1.1.0", -1, true},
+		{"prerelease after previous release", "v1.1.0-beta.2", "v1.0.9", 1, true},
+		{"beta after alpha", "v1.1.0-beta", "v1.1.0-alpha.3", 1, true},
+		{"prerelease number", "v1.1.0-rc.2", "v1.1.0-rc.10", -1, true},
 		{"dev version", "dev", "v1.0.0", 0, false},
 	}
 
@@ -59,6 +76,10 @@ func TestVerifyAssetDigest(t *testing.T) {
 	if err := verifyAssetDigest("", sum[:]); err != nil {
 		t.Fatalf("empty digest should be ignored: %v", err)
 	}
+
+	if err := verifyAssetDigest("md5:5d41402abc4b2a76b9719d911017c592", sum[:]); err == nil {
+		t.Fatal("unsupported digest algorithm should be rejected")
+	}
 }
 
 func TestCopyWithProgress(t *testing.T) {
@@ -84,3 +105,236 @@ func TestCopyWithProgress(t *testing.T) {
 		t.Fatalf("progress = (%d, %d), want (5, 5)", lastReceived, lastTotal)
 	}
 }
+
+// newTestReleaseServer 模拟 GitHub 发布：/latest 返回发布信息，/download/ 下为发布文件。
+func newTestReleaseServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/releases/latest") {
+			release := appRelease{TagName: "v1.2.0", HTMLURL: srv.URL + "/release"}
+			for name := range files {
+				release.Assets = append(release.Assets, appReleaseAsset{Name: name, BrowserDownloadURL: srv.URL + "/download/" + name})
+			}
+			json.NewEncoder(w).Encode(release)
+			return
+		}
+		content, ok := files[strings.TrimPrefix(r.URL.Path, "/download/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReleaseUpdatePublicKey(t *testing.T) {
+	if _, err := utils.ParseUpdatePublicKey(releaseUpdatePublicKey); err != nil {
+		t.Errorf("内置的发布公钥无效: %v", err)
+	}
+}
+
+func TestDownloadAppUpdateSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+
+	assetName := currentPlatformAssetName()
+	content := []byte("new binary")
+	signWith := func(key ed25519.PrivateKey, version string) []byte {
+		path := filepath.Join(t.TempDir(), assetName)
+		writeTestFile(t, path, string(content))
+		sig, err := utils.SignUpdate(key, path, assetName, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+
+	tests := []struct {
+		name          string
+		sig           []byte // nil 表示发布中没有签名文件
+		publicKey     string
+		allowUnsigned bool
+		wantErr       string
+	}{
+		{name: "valid", sig: signWith(priv, "v1.2.0"), publicKey: publicKey},
+		{name: "other key", sig: signWith(otherPriv, "v1.2.0"), publicKey: publicKey, wantErr: "签名校验失败"},
+		{name: "other version", sig: signWith(priv, "v1.1.0"), publicKey: publicKey, wantErr: "签名校验失败"},
+		{name: "bad signature even if unsigned allowed", sig: []byte("garbage"), publicKey: publicKey, allowUnsigned: true, wantErr: "签名校验失败"},
+		{name: "missing signature", publicKey: publicKey, wantErr: "没有签名文件"},
+		{name: "missing signature allowed", publicKey: publicKey, allowUnsigned: true},
+		{name: "no public key", sig: signWith(priv, "v1.2.0"), wantErr: "未内置更新签名公钥"},
+		{name: "no public key allowed", sig: signWith(priv, "v1.2.0"), allowUnsigned: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{assetName: content}
+			if tt.sig != nil {
+				files[assetName+utils.UpdateSignatureExt] = tt.sig
+			}
+			srv := newTestReleaseServer(t, files)
+			// 通过镜像访问测试服务器，不请求 GitHub
+			src := UpdateSource{Mirrors: MirrorConfig{API: []string{srv.URL + "{path}"}, Preferred: true}}
+
+			info, err := CheckAppUpdate("v1.0.0", src, false)
+			if err != nil || info == nil {
+				t.Fatalf("CheckAppUpdate = %v, %v", info, err)
+			}
+			if (info.SignatureURL != "") != (tt.sig != nil) {
+				t.Fatalf("SignatureURL = %q", info.SignatureURL)
+			}
+
+			dir := t.TempDir()
+			path, err := downloadAppUpdateTo(dir, info, src, tt.publicKey, tt.allowUnsigned, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				// 校验失败的文件不能留下，避免之后被当作已下载的更新
+				if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+					t.Errorf("校验失败后应删除下载的文件: %v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(path); !bytes.Equal(got, content) {
+				t.Errorf("下载的内容不一致")
+			}
+		})
+	}
+}
+
+func TestCheckAppUpdatePrerelease(t *testing.T) {
+	assetName := currentPlatformAssetName()
+	release := func(tag string, prerelease bool) appRelease {
+		return appRelease{
+			TagName:    tag,
+			Body:       "notes " + tag,
+			Prerelease: prerelease,
+			Assets:     []appReleaseAsset{{Name: assetName, BrowserDownloadURL: "https://github.com/Yinr/yt-dlp-simpgo/releases/download/" + tag + "/" + assetName}},
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/releases/latest") {
+			json.NewEncoder(w).Encode(release("v1.1.0", false))
+			return
+		}
+		draft := release("v2.0.0", false)
+		draft.Draft = true
+		json.NewEncoder(w).Encode([]appRelease{draft, release("v1.2.0-beta.1", true), release("v1.1.0", false), release("nightly", true)})
+	}))
+	defer srv.Close()
+	src := UpdateSource{Mirrors: MirrorConfig{API: []string{srv.URL + "{path}"}, Preferred: true}}
+
+	tests := []struct {
+		name       string
+		current    string
+		prerelease bool
+		want       string
+	}{
+		{name: "stable", current: "v1.0.0", want: "v1.1.0"},
+		{name: "prerelease", current: "v1.0.0", prerelease: true, want: "v1.2.0-beta.1"},
+		{name: "up to date", current: "v1.2.0-beta.1", prerelease: true},
+		{name: "dev", current: "dev", prerelease: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := CheckAppUpdate(tt.current, src, tt.prerelease)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if info != nil {
+					t.Fatalf("不应有更新: %+v", info)
+				}
+				return
+			}
+			if info == nil || info.LatestVersion != tt.want {
+				t.Fatalf("info = %+v, want %s", info, tt.want)
+			}
+			if info.ReleaseNotes != "notes "+tt.want || info.Prerelease != tt.prerelease {
+				t.Errorf("发布说明或预发布标记不正确: %+v", info)
+			}
+		})
+	}
+}
+
+func TestInstallAppUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		child   func(readyPath string, exited chan<- error, killed <-chan struct{})
+		wantErr string
+	}{
+		{
+			name: "confirmed",
+			child: func(readyPath string, exited chan<- error, killed <-chan struct{}) {
+				time.Sleep(50 * time.Millisecond)
+				os.WriteFile(readyPath, []byte("v1.2.0"), 0644)
+			},
+		},
+		{
+			name: "crashed",
+			child: func(readyPath string, exited chan<- error, killed <-chan struct{}) {
+				exited <- errors.New("exit status 2")
+			},
+			wantErr: "新版本启动失败: exit status 2，已恢复当前版本",
+		},
+		{
+			name: "hung",
+			child: func(readyPath string, exited chan<- error, killed <-chan struct{}) {
+				<-killed
+				exited <- errors.New("killed")
+			},
+			wantErr: "未在",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			exePath := filepath.Join(dir, "yt-dlp-simpgo")
+			updatePath := filepath.Join(dir, ".yt-dlp-simpgo-update-v1.2.0")
+			writeTestFile(t, exePath, "old")
+			writeTestFile(t, updatePath, "new")
+
+			start := func(p, readyPath string) (<-chan error, func(), error) {
+				if got, _ := os.ReadFile(p); string(got) != "new" {
+					t.Errorf("启动时程序应已替换为新版本: %q", got)
+				}
+				exited := make(chan error, 1)
+				killed := make(chan struct{})
+				go tt.child(readyPath, exited, killed)
+				return exited, func() { close(killed) }, nil
+			}
+			err := installAppUpdate(updatePath, exePath, start, 300*time.Millisecond)
+
+			got, _ := os.ReadFile(exePath)
+			backup, backupErr := os.ReadFile(appBackupPath(exePath))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != "new" || string(backup) != "old" {
+					t.Errorf("程序 = %q，备份 = %q, want new, old", got, backup)
+				}
+			} else {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if string(got) != "old" || !os.IsNotExist(backupErr) {
+					t.Errorf("失败后应恢复旧版本: 程序 = %q，备份错误 = %v", got, backupErr)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, appUpdateReadyName)); !os.IsNotExist(err) {
+				t.Error("确认文件应被删除")
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 
 	"fyne.io/fyne/v2"
@@ -15,37 +18,263 @@ import (
 )
 
 // showSettingsDialog shows the settings dialog with tabs
-func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig, exeDir string, onSaved func(*AppConfig, *YTDLPConfig)) {
+func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig, paths AppPaths, onSaved func(*AppConfig, *YTDLPConfig)) {
 	// Create input bindings for app config
 	outputDirEntry := widget.NewEntry()
 	outputDirEntry.SetText(appCfg.OutputDir)
 	outputDirEntry.Disable()
 
-	downloadProxyEntry := widget.NewEntry()
-	downloadProxyEntry.SetText(appCfg.DownloadProxy)
-	downloadProxyEntry.SetPlaceHolder("留空则不使用代理，例如：http://127.0.0.1:7890")
+	proxySettings, proxyConfig := newProxySettings(w, appCfg.Proxy)
 
 	ytDlpURLEntry := widget.NewEntry()
 	ytDlpURLEntry.SetText(appCfg.YtDlpURL)
 	ytDlpURLEntry.SetPlaceHolder("留空则使用官方地址")
 
-	// Create input bindings for yt-dlp config
-	useProxyCheck := widget.NewCheck("启用代理", func(b bool) {
-		ytdlpCfg.UseProxy = b
+	// GitHub 镜像，每行一个地址模板
+	mirrorAPIEntry := widget.NewMultiLineEntry()
+	mirrorAPIEntry.SetText(strings.Join(appCfg.Mirrors.API, "\n"))
+	mirrorAPIEntry.SetPlaceHolder("例如：https://gh-api.example.com{path}")
+	mirrorAPIEntry.SetMinRowsVisible(2)
+	mirrorDownloadEntry := widget.NewMultiLineEntry()
+	mirrorDownloadEntry.SetText(strings.Join(appCfg.Mirrors.Download, "\n"))
+	mirrorDownloadEntry.SetPlaceHolder("例如：https://ghfast.top/{url}")
+	mirrorDownloadEntry.SetMinRowsVisible(2)
+	mirrorPreferredCheck := widget.NewCheck("优先使用镜像（GitHub 无法访问时可减少等待）", nil)
+	mirrorPreferredCheck.Checked = appCfg.Mirrors.Preferred
+
+	// 检查更新
+	updateCfg := appCfg.Update
+	var intervalLabels []string
+	intervalHours := map[string]int{}
+	for _, o := range updateIntervalOptions {
+		intervalLabels = append(intervalLabels, o.Label)
+		intervalHours[o.Label] = o.Hours
+	}
+	// 手动编辑配置文件设置的其他间隔也作为选项
+	if label := updateIntervalLabel(updateCfg.CheckIntervalHours); !slices.Contains(intervalLabels, label) {
+		intervalLabels = append(intervalLabels, label)
+		intervalHours[label] = updateCfg.CheckIntervalHours
+	}
+	updateIntervalSelect := widget.NewSelect(intervalLabels, nil)
+	updateIntervalSelect.SetSelected(updateIntervalLabel(updateCfg.CheckIntervalHours))
+	remindDaysEntry := widget.NewEntry()
+	remindDaysEntry.SetText(strconv.Itoa(updateCfg.remindDays()))
+	autoUpdateYtDlpCheck := widget.NewCheck("yt-dlp 有新版本时直接更新，不再询问", nil)
+	autoUpdateYtDlpCheck.Checked = updateCfg.AutoUpdateYtDlp
+	prereleaseCheck := widget.NewCheck("检查程序更新时包含预发布版本", nil)
+	prereleaseCheck.Checked = updateCfg.Prerelease
+	resetSkippedBtn := widget.NewButton("重新提示已跳过的版本", nil)
+	resetSkippedBtn.OnTapped = func() {
+		updateCfg.App, updateCfg.YtDlp = UpdatePrompt{}, UpdatePrompt{}
+		resetSkippedBtn.Disable()
+	}
+	if updateCfg.App == (UpdatePrompt{}) && updateCfg.YtDlp == (UpdatePrompt{}) {
+		resetSkippedBtn.Disable()
+	}
+
+	// 链接协议，注册后立即生效，不随设置保存
+	registerSchemeBtn := widget.NewButton("关联 "+URLScheme+":// 链接", func() {
+		if err := RegisterURLScheme(paths); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		dialog.ShowInformation("链接协议", "已关联，点击 "+URLScheme+":// 链接时将由本程序打开。\n程序移动位置后需要重新关联。", w)
+	})
+	if runtime.GOOS == "darwin" {
+		registerSchemeBtn.Disable()
+	}
+
+	// Create input bindings for post-download actions
+	openFolderCheck := widget.NewCheck("完成后打开所在文件夹", nil)
+
+	transferLabels := map[string]string{
+		TransferNone: "不处理",
+		TransferMove: "移动到",
+		TransferCopy: "复制到",
+	}
+	transferSelect := widget.NewSelect([]string{"不处理", "移动到", "复制到"}, nil)
+
+	transferDirEntry := widget.NewEntry()
+	transferDirEntry.SetPlaceHolder("目标目录")
+	chooseTransferDirBtn := widget.NewButton("选择...", func() {
+		p, err := nativeDialog.Directory().Title("选择目标目录").SetStartDir(transferDirEntry.Text).Browse()
+		if err != nil || p == "" {
+			return
+		}
+		transferDirEntry.SetText(p)
 	})
-	useProxyCheck.Checked = ytdlpCfg.UseProxy
 
-	proxyAddressEntry := widget.NewEntry()
-	proxyAddressEntry.SetText(ytdlpCfg.ProxyAddress)
-	proxyAddressEntry.SetPlaceHolder("例如：127.0.0.1:7890 或 http://127.0.0.1:7890")
+	// Create input bindings for file organization
+	organizeCheck := widget.NewCheck("下载完成后按以下规则将文件整理到子目录", nil)
+	organizeCheck.Checked = appCfg.Organize.Enabled
 
-	syncProxyBtn := widget.NewButton("同步程序代理", func() {
-		syncProxyFromApp(appCfg, ytdlpCfg)
-		proxyAddressEntry.SetText(ytdlpCfg.ProxyAddress)
-		useProxyCheck.Checked = ytdlpCfg.UseProxy
+	organizeNone := "（无）"
+	organizeOptions := []string{organizeNone}
+	for _, r := range organizeRules {
+		organizeOptions = append(organizeOptions, r.Label)
+	}
+	organizeExample := widget.NewLabel("")
+	organizeSelects := make([]*widget.Select, len(organizeRules))
+	selectedOrganizeRules := func() []string {
+		var rules []string
+		for _, sel := range organizeSelects {
+			for _, r := range organizeRules {
+				if r.Label == sel.Selected && !slices.Contains(rules, r.Key) {
+					rules = append(rules, r.Key)
+				}
+			}
+		}
+		return rules
+	}
+	updateOrganizeExample := func() {
+		example := DownloadedFile{
+			FilePath:   "视频标题.mp4",
+			Extractor:  "Youtube",
+			Uploader:   "上传者",
+			Playlist:   "播放列表",
+			UploadDate: "20240131",
+		}
+		p := OrganizedPath("", selectedOrganizeRules(), example)
+		organizeExample.SetText("示例：" + filepath.Join("下载目录", p))
+	}
+	for i := range organizeSelects {
+		sel := widget.NewSelect(organizeOptions, func(string) { updateOrganizeExample() })
+		sel.Selected = organizeNone
+		if i < len(appCfg.Organize.Rules) {
+			for _, r := range organizeRules {
+				if r.Key == appCfg.Organize.Rules[i] {
+					sel.Selected = r.Label
+				}
+			}
+		}
+		organizeSelects[i] = sel
+	}
+	updateOrganizeExample()
+	organizeLevels := container.NewHBox()
+	for i, sel := range organizeSelects {
+		if i > 0 {
+			organizeLevels.Add(widget.NewLabel("/"))
+		}
+		organizeLevels.Add(sel)
+	}
+	reorganizeBtn := widget.NewButton("整理已下载的文件...", func() {
+		rules := selectedOrganizeRules()
+		if len(rules) == 0 {
+			dialog.ShowError(fmt.Errorf("请先选择至少一级整理规则"), w)
+			return
+		}
+		root := paths.Resolve(appCfg.OutputDir)
+		dialog.ShowConfirm("整理已下载的文件", "将根据下载历史中的信息，把下载目录中已下载的文件按当前规则移动到子目录。是否继续？", func(ok bool) {
+			if !ok {
+				return
+			}
+			var moved int
+			err := UpdateHistory(paths.DataPath(HistoryFileName), func(entries []HistoryEntry) error {
+				var err error
+				moved, err = ReorganizeHistory(root, rules, entries)
+				return err
+			})
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("已整理 %d 个文件，部分文件整理失败: %w", moved, err), w)
+				return
+			}
+			dialog.ShowInformation("整理完成", fmt.Sprintf("已整理 %d 个文件", moved), w)
+		}, w)
 	})
-	syncProxyBtn.Importance = widget.MediumImportance
 
+	commandEntry := widget.NewEntry()
+	commandEntry.SetPlaceHolder("例如：ffmpeg -i \"{file}\" \"{dir}/{id}.mp3\"")
+
+	webhookEntry := widget.NewEntry()
+	webhookEntry.SetPlaceHolder("留空则不调用，例如：http://127.0.0.1:8080/hook")
+
+	// 下载后操作可按下载方案单独设置。postConfigs 为编辑中的设置，键为方案名称，空键为默认设置
+	postConfigs := map[string]PostDownloadConfig{"": appCfg.PostDownload}
+	for name, c := range appCfg.ProfilePostDownload {
+		postConfigs[name] = c
+	}
+	postProfile := ""
+	readPostForm := func() PostDownloadConfig {
+		c := PostDownloadConfig{
+			OpenFolder:  openFolderCheck.Checked,
+			TransferDir: strings.TrimSpace(transferDirEntry.Text),
+			Command:     strings.TrimSpace(commandEntry.Text),
+			WebhookURL:  strings.TrimSpace(webhookEntry.Text),
+		}
+		for mode, label := range transferLabels {
+			if label == transferSelect.Selected {
+				c.TransferMode = mode
+			}
+		}
+		return c
+	}
+	writePostForm := func(c PostDownloadConfig) {
+		openFolderCheck.SetChecked(c.OpenFolder)
+		transferSelect.SetSelected(transferLabels[c.TransferMode])
+		transferDirEntry.SetText(c.TransferDir)
+		commandEntry.SetText(c.Command)
+		webhookEntry.SetText(c.WebhookURL)
+	}
+	setPostFormEnabled := func(enabled bool) {
+		for _, d := range []fyne.Disableable{openFolderCheck, transferSelect, transferDirEntry, chooseTransferDirBtn, commandEntry, webhookEntry} {
+			if enabled {
+				d.Enable()
+			} else {
+				d.Disable()
+			}
+		}
+	}
+	postOverrideCheck := widget.NewCheck("为该方案单独设置（否则使用默认设置）", func(on bool) { setPostFormEnabled(on) })
+	// storePostForm 保存正在编辑的方案的设置，未单独设置的方案删除
+	storePostForm := func() {
+		if postProfile == "" || postOverrideCheck.Checked {
+			postConfigs[postProfile] = readPostForm()
+		} else {
+			delete(postConfigs, postProfile)
+		}
+	}
+	postProfileSelect := widget.NewSelect(append([]string{"默认（所有方案）"}, profileLabels()...), nil)
+	postProfileSelect.OnChanged = func(string) {
+		storePostForm()
+		postProfile = ""
+		if i := postProfileSelect.SelectedIndex(); i > 0 {
+			postProfile = downloadProfiles[i-1].Name
+		}
+		c, ok := postConfigs[postProfile]
+		if !ok {
+			c = postConfigs[""]
+		}
+		writePostForm(c)
+		if postProfile == "" {
+			postOverrideCheck.Hide()
+			setPostFormEnabled(true)
+		} else {
+			postOverrideCheck.Show()
+			postOverrideCheck.SetChecked(ok)
+			setPostFormEnabled(ok)
+		}
+	}
+	// 先填入默认设置，切换到默认方案时保存的即为原设置
+	writePostForm(appCfg.PostDownload)
+	postProfileSelect.SetSelectedIndex(0)
+
+	// Create input bindings for download schedule
+	scheduleCheck := widget.NewCheck("仅在以下时间段内开始下载", nil)
+	scheduleCheck.Checked = appCfg.Schedule.Enabled
+
+	scheduleEntry := widget.NewEntry()
+	scheduleEntry.SetText(FormatScheduleWindows(appCfg.Schedule.Windows))
+	scheduleEntry.SetPlaceHolder("例如：22:00-07:00@2M, 12:00-13:00")
+
+	// Create input bindings for disk space guard
+	diskGuardCheck := widget.NewCheck("下载目录剩余空间不足时暂停下载", nil)
+	diskGuardCheck.Checked = appCfg.DiskGuard.Enabled
+
+	minFreeEntry := widget.NewEntry()
+	minFreeEntry.SetText(strconv.Itoa(appCfg.DiskGuard.MinFreeMB))
+	minFreeEntry.SetPlaceHolder(strconv.Itoa(DefaultMinFreeMB))
+
+	// Create input bindings for yt-dlp config
 	writeSubsCheck := widget.NewCheck("下载字幕", func(b bool) {
 		ytdlpCfg.WriteSubs = b
 	})
@@ -60,6 +289,12 @@ func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig,
 	})
 	embedSubsCheck.Checked = ytdlpCfg.EmbedSubs
 
+	writeAutoSubsCheck := widget.NewCheck("同时下载自动生成的字幕", nil)
+	writeAutoSubsCheck.Checked = ytdlpCfg.WriteAutoSubs
+
+	convertSubsSelect := widget.NewSelect(subtitleConvertLabels(), nil)
+	convertSubsSelect.SetSelected(subtitleConvertLabel(ytdlpCfg.ConvertSubs))
+
 	embedChaptersCheck := widget.NewCheck("内嵌章节", func(b bool) {
 		ytdlpCfg.EmbedChapters = b
 	})
@@ -88,47 +323,137 @@ func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig,
 		mergeFormatSelect.SetSelected(ytdlpCfg.MergeOutputFormat)
 	}
 
+	useArchiveCheck := widget.NewCheck("启用下载记录，跳过已下载的视频", nil)
+	useArchiveCheck.Checked = ytdlpCfg.UseDownloadArchive
+
+	archiveEntry := widget.NewEntry()
+	archiveEntry.SetText(ytdlpCfg.DownloadArchive)
+	archiveEntry.SetPlaceHolder(DefaultDownloadArchive)
+	archivePath := func() string {
+		p := strings.TrimSpace(archiveEntry.Text)
+		if p == "" {
+			p = DefaultDownloadArchive
+		}
+		return paths.Resolve(p)
+	}
+	manageArchiveBtn := widget.NewButton("管理...", func() {
+		showArchiveDialog(w, archivePath(), paths.DataPath(HistoryFileName), paths.DataPath(LogsDirName))
+	})
+
+	liveFromStartCheck := widget.NewCheck("录制直播时从头开始（而不是从当前时间）", nil)
+	liveFromStartCheck.Checked = ytdlpCfg.LiveFromStart
+
+	waitForVideoCheck := widget.NewCheck("等待预定的直播或首映开始", nil)
+	waitForVideoCheck.Checked = ytdlpCfg.UseWaitForVideo
+
+	waitForVideoEntry := widget.NewEntry()
+	waitForVideoEntry.SetText(ytdlpCfg.WaitForVideo)
+	waitForVideoEntry.SetPlaceHolder(DefaultWaitForVideo)
+
+	// SponsorBlock 类别以界面名称显示在复选框中
+	sponsorBlockGroup := func(removable bool, selected []string) (*widget.CheckGroup, func() []string) {
+		cats := sponsorBlockOptions(removable)
+		labels := make([]string, len(cats))
+		var checked []string
+		for i, c := range cats {
+			labels[i] = c.Label
+			if slices.Contains(selected, c.Name) {
+				checked = append(checked, c.Label)
+			}
+		}
+		group := widget.NewCheckGroup(labels, nil)
+		group.Horizontal = true
+		group.Selected = checked
+		return group, func() []string {
+			var names []string
+			for _, c := range cats {
+				if slices.Contains(group.Selected, c.Label) {
+					names = append(names, c.Name)
+				}
+			}
+			return names
+		}
+	}
+	sponsorMarkGroup, sponsorMarkSelected := sponsorBlockGroup(false, ytdlpCfg.SponsorBlockMark)
+	sponsorRemoveGroup, sponsorRemoveSelected := sponsorBlockGroup(true, ytdlpCfg.SponsorBlockRemove)
+
+	sponsorAPIEntry := widget.NewEntry()
+	sponsorAPIEntry.SetText(ytdlpCfg.SponsorBlockAPI)
+	sponsorAPIEntry.SetPlaceHolder("留空则使用默认地址 " + DefaultSponsorBlockAPI)
+
 	extraArgsEntry := widget.NewMultiLineEntry()
 	extraArgsEntry.SetText(ytdlpCfg.ExtraArgs)
 	extraArgsEntry.SetPlaceHolder("输入其他 yt-dlp 命令行参数，每行一个，支持注释\n例如：\n--concurrent-fragments 4\n--fragment-retries 10\n\n# 这是注释，不会被解析")
 
 	// Tab 1: App Settings
 	chooseDirBtn := widget.NewButton("选择...", func() {
-		startDir := appCfg.OutputDir
-		if !filepath.IsAbs(startDir) {
-			startDir = filepath.Join(exeDir, startDir)
-		}
+		startDir := paths.Resolve(appCfg.OutputDir)
 		p, err := nativeDialog.Directory().Title("选择下载目录").SetStartDir(startDir).Browse()
 		if err != nil || p == "" {
 			return
 		}
-		if rel, rerr := filepath.Rel(exeDir, p); rerr == nil && !strings.HasPrefix(rel, "..") {
-			appCfg.OutputDir = rel
-		} else {
-			appCfg.OutputDir = p
-		}
+		appCfg.OutputDir = paths.Rel(p)
 		outputDirEntry.SetText(appCfg.OutputDir)
 	})
 
 	appSettingsContent := container.NewVBox(
 		widget.NewCard("下载目录", "", container.NewBorder(nil, nil, nil, chooseDirBtn, outputDirEntry)),
-		widget.NewCard("下载代理（用于下载/更新 yt-dlp）", "", downloadProxyEntry),
+		widget.NewCard("代理", "支持 http、https、socks5 和 socks5h 代理", proxySettings),
 		widget.NewCard("yt-dlp 自定义下载 URL", "", ytDlpURLEntry),
+		widget.NewCard("GitHub 镜像", "检查更新和下载程序更新、yt-dlp 时，GitHub 访问失败后依次尝试镜像。每行一个地址，{url} 替换为原地址，{path} 替换为原地址的路径", widget.NewForm(
+			widget.NewFormItem("发布信息 API", mirrorAPIEntry),
+			widget.NewFormItem("文件下载", mirrorDownloadEntry),
+			widget.NewFormItem("", mirrorPreferredCheck),
+		)),
+		widget.NewCard("检查更新", "启动时按间隔检查程序和 yt-dlp 的新版本", widget.NewForm(
+			widget.NewFormItem("检查间隔", updateIntervalSelect),
+			widget.NewFormItem("稍后提醒（天）", remindDaysEntry),
+			widget.NewFormItem("", autoUpdateYtDlpCheck),
+			widget.NewFormItem("", prereleaseCheck),
+			widget.NewFormItem("", container.NewHBox(resetSkippedBtn)),
+		)),
+		widget.NewCard("网页链接", "形如 "+URLScheme+"://download?url=<编码后的网址>&profile=audio 的链接会加入下载队列，profile 可为 "+downloadProfileNames()+"，省略时按设置下载视频", container.NewHBox(registerSchemeBtn)),
+		widget.NewCard("下载时间段", "多个时间段以逗号分隔，可用 @ 指定该时间段的限速（如 500K、2M）", container.NewVBox(
+			scheduleCheck,
+			scheduleEntry,
+		)),
+		widget.NewCard("磁盘空间", "开始下载前和下载过程中检查剩余空间，不足时暂停下载队列，空间足够后自动继续", container.NewVBox(
+			diskGuardCheck,
+			container.NewBorder(nil, nil, widget.NewLabel("最低剩余空间（MB）："), nil, minFreeEntry),
+		)),
 	)
 	appSettingsTab := container.NewVScroll(appSettingsContent)
 
+	// Tab: Post-download actions
+	postDownloadContent := container.NewVBox(
+		widget.NewCard("整理文件", "依次选择各级子目录，缺少对应信息（如不属于播放列表）时跳过该级；文件名会处理为在各系统中都可用的名称", container.NewVBox(
+			organizeCheck,
+			organizeLevels,
+			organizeExample,
+			container.NewHBox(reorganizeBtn),
+		)),
+		widget.NewCard("下载方案", "可为各下载方案单独设置下载后操作，未单独设置的方案使用默认设置", container.NewVBox(
+			postProfileSelect,
+			postOverrideCheck,
+		)),
+		widget.NewCard("文件", "", container.NewVBox(
+			openFolderCheck,
+			container.NewBorder(nil, nil, transferSelect, chooseTransferDirBtn, transferDirEntry),
+		)),
+		widget.NewCard("自定义命令", "每个文件下载完成后执行，可用占位符：{file} {dir} {title} {url} {id}", commandEntry),
+		widget.NewCard("Webhook", "每个文件下载完成后以 POST 发送 JSON", webhookEntry),
+	)
+	postDownloadTab := container.NewVScroll(postDownloadContent)
+
 	// Tab 2: yt-dlp Basic Settings
 	basicSettingsContent := container.NewVBox(
-		widget.NewCard("代理设置", "", container.NewVBox(
-			useProxyCheck,
-			widget.NewLabel("代理地址："),
-			container.NewBorder(nil, nil, nil, syncProxyBtn, proxyAddressEntry),
-		)),
 		widget.NewCard("字幕设置", "", container.NewVBox(
 			writeSubsCheck,
 			widget.NewLabel("字幕语言："),
 			subLangsEntry,
+			writeAutoSubsCheck,
 			embedSubsCheck,
+			container.NewHBox(widget.NewLabel("转换格式："), convertSubsSelect),
 		)),
 		widget.NewCard("其他选项", "", container.NewVBox(
 			embedChaptersCheck,
@@ -136,6 +461,24 @@ func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig,
 			embedMetadataCheck,
 			container.NewVBox(widget.NewLabel("输出格式："), mergeFormatSelect),
 		)),
+		widget.NewCard("下载记录", "", container.NewVBox(
+			useArchiveCheck,
+			widget.NewLabel("记录文件（相对路径基于程序目录）："),
+			container.NewBorder(nil, nil, nil, manageArchiveBtn, archiveEntry),
+		)),
+		widget.NewCard("直播录制", "录制中可在下载队列中停止录制，已录制的内容会被保存", container.NewVBox(
+			liveFromStartCheck,
+			waitForVideoCheck,
+			container.NewBorder(nil, nil, widget.NewLabel("重试间隔（秒）："), nil, waitForVideoEntry),
+		)),
+		widget.NewCard("SponsorBlock", "根据 SponsorBlock 数据库处理视频中的赞助广告等片段", container.NewVBox(
+			widget.NewLabel("标记为章节："),
+			sponsorMarkGroup,
+			widget.NewLabel("从视频中移除（需要重新处理视频）："),
+			sponsorRemoveGroup,
+			widget.NewLabel("API 地址："),
+			sponsorAPIEntry,
+		)),
 	)
 	basicSettingsTab := container.NewVScroll(basicSettingsContent)
 
@@ -155,6 +498,7 @@ func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig,
 		container.NewTabItem("程序设置", appSettingsTab),
 		container.NewTabItem("基础选项", basicSettingsTab),
 		container.NewTabItem("高级选项", advancedSettingsTab),
+		container.NewTabItem("下载后操作", postDownloadTab),
 	)
 
 	// Create save button first (will be used in dialog)
@@ -180,8 +524,18 @@ func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig,
 	}
 	saveBtn.OnTapped = func() {
 		// Validate inputs
-		if useProxyCheck.Checked && strings.TrimSpace(proxyAddressEntry.Text) == "" {
-			dialog.ShowError(fmt.Errorf("启用代理时必须设置代理地址"), w)
+		proxyCfg := proxyConfig()
+		if err := proxyCfg.Validate(); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		mirrors := MirrorConfig{
+			API:       parseMirrorTemplates(mirrorAPIEntry.Text),
+			Download:  parseMirrorTemplates(mirrorDownloadEntry.Text),
+			Preferred: mirrorPreferredCheck.Checked,
+		}
+		if err := mirrors.Validate(); err != nil {
+			dialog.ShowError(err, w)
 			return
 		}
 		if writeSubsCheck.Checked && strings.TrimSpace(subLangsEntry.Text) == "" {
@@ -189,11 +543,98 @@ func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig,
 			return
 		}
 
+		storePostForm()
+		var profilePost map[string]PostDownloadConfig
+		for i, name := range append([]string{""}, profileNames()...) {
+			c, ok := postConfigs[name]
+			if !ok {
+				continue
+			}
+			if err := c.Validate(); err != nil {
+				dialog.ShowError(fmt.Errorf("下载后操作（%s）: %v", postProfileSelect.Options[i], err), w)
+				return
+			}
+			if name != "" {
+				if profilePost == nil {
+					profilePost = map[string]PostDownloadConfig{}
+				}
+				profilePost[name] = c
+			}
+		}
+
+		scheduleWindows, err := ParseScheduleWindows(scheduleEntry.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("下载时间段无效: %v", err), w)
+			return
+		}
+		if scheduleCheck.Checked && len(scheduleWindows) == 0 {
+			dialog.ShowError(fmt.Errorf("启用下载时间段时必须设置至少一个时间段"), w)
+			return
+		}
+
+		waitForVideo := strings.TrimSpace(waitForVideoEntry.Text)
+		if waitForVideo == "" {
+			waitForVideo = DefaultWaitForVideo
+		}
+		if !waitForVideoPattern.MatchString(waitForVideo) {
+			dialog.ShowError(fmt.Errorf("重试间隔应为秒数，如 60 或 30-600"), w)
+			return
+		}
+
+		sponsorMark, sponsorRemove := sponsorMarkSelected(), sponsorRemoveSelected()
+		sponsorAPI := strings.TrimSpace(sponsorAPIEntry.Text)
+		if err := validateSponsorBlock(sponsorMark, sponsorRemove, sponsorAPI); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		organizeRulesSelected := selectedOrganizeRules()
+		if organizeCheck.Checked && len(organizeRulesSelected) == 0 {
+			dialog.ShowError(fmt.Errorf("整理文件时必须选择至少一级子目录"), w)
+			return
+		}
+
+		minFreeMB := DefaultMinFreeMB
+		if text := strings.TrimSpace(minFreeEntry.Text); text != "" {
+			n, err := strconv.Atoi(text)
+			if err != nil || n < 0 {
+				dialog.ShowError(fmt.Errorf("最低剩余空间应为非负整数"), w)
+				return
+			}
+			minFreeMB = n
+		}
+
+		remindDays, err := strconv.Atoi(strings.TrimSpace(remindDaysEntry.Text))
+		if err != nil || remindDays <= 0 {
+			dialog.ShowError(fmt.Errorf("稍后提醒的天数应为正整数"), w)
+			return
+		}
+		updateCfg.CheckIntervalHours = intervalHours[updateIntervalSelect.Selected]
+		updateCfg.RemindDays = remindDays
+		updateCfg.AutoUpdateYtDlp = autoUpdateYtDlpCheck.Checked
+		updateCfg.Prerelease = prereleaseCheck.Checked
+
 		// Update configurations
 		newAppCfg := &AppConfig{
-			OutputDir:     strings.TrimSpace(outputDirEntry.Text),
-			DownloadProxy: strings.TrimSpace(downloadProxyEntry.Text),
-			YtDlpURL:      strings.TrimSpace(ytDlpURLEntry.Text),
+			OutputDir:           strings.TrimSpace(outputDirEntry.Text),
+			Proxy:               proxyCfg,
+			YtDlpURL:            strings.TrimSpace(ytDlpURLEntry.Text),
+			PostDownload:        postConfigs[""],
+			ProfilePostDownload: profilePost,
+			Schedule: ScheduleConfig{
+				Enabled: scheduleCheck.Checked,
+				Windows: scheduleWindows,
+			},
+			DiskGuard: DiskGuardConfig{
+				Enabled:   diskGuardCheck.Checked,
+				MinFreeMB: minFreeMB,
+			},
+			Organize: OrganizeConfig{
+				Enabled: organizeCheck.Checked,
+				Rules:   organizeRulesSelected,
+			},
+			Mirrors: mirrors,
+			Update:  updateCfg,
 		}
 
 		mergeFormat := mergeFormatSelect.Selected
@@ -202,33 +643,41 @@ func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig,
 		}
 
 		newYtdlpCfg := &YTDLPConfig{
-			UseProxy:          useProxyCheck.Checked,
-			ProxyAddress:      strings.TrimSpace(proxyAddressEntry.Text),
-			WriteSubs:         writeSubsCheck.Checked,
-			SubLangs:          strings.TrimSpace(subLangsEntry.Text),
-			EmbedSubs:         embedSubsCheck.Checked,
-			EmbedChapters:     embedChaptersCheck.Checked,
-			SplitChapters:     splitChaptersCheck.Checked,
-			EmbedMetadata:     embedMetadataCheck.Checked,
-			MergeOutputFormat: mergeFormat,
-			ExtraArgs:         extraArgsEntry.Text,
+			UseProxy:           ytdlpCfg.UseProxy,
+			ProxyAddress:       ytdlpCfg.ProxyAddress,
+			WriteSubs:          writeSubsCheck.Checked,
+			SubLangs:           strings.TrimSpace(subLangsEntry.Text),
+			EmbedSubs:          embedSubsCheck.Checked,
+			WriteAutoSubs:      writeAutoSubsCheck.Checked,
+			ConvertSubs:        subtitleConvertValue(convertSubsSelect.Selected),
+			SubFavorites:       ytdlpCfg.SubFavorites,
+			EmbedChapters:      embedChaptersCheck.Checked,
+			SplitChapters:      splitChaptersCheck.Checked,
+			EmbedMetadata:      embedMetadataCheck.Checked,
+			MergeOutputFormat:  mergeFormat,
+			UseDownloadArchive: useArchiveCheck.Checked,
+			DownloadArchive:    strings.TrimSpace(archiveEntry.Text),
+			LiveFromStart:      liveFromStartCheck.Checked,
+			UseWaitForVideo:    waitForVideoCheck.Checked,
+			WaitForVideo:       waitForVideo,
+			SponsorBlockMark:   sponsorMark,
+			SponsorBlockRemove: sponsorRemove,
+			SponsorBlockAPI:    sponsorAPI,
+			ExtraArgs:          extraArgsEntry.Text,
 		}
 
 		// Save configurations
-		if err := SaveAppConfig(IniFileName, newAppCfg); err != nil {
+		if err := SaveAppConfig(paths.IniPath(), newAppCfg); err != nil {
 			dialog.ShowError(fmt.Errorf("无法保存程序配置: %v", err), w)
 			return
 		}
-		if err := SaveYTDLPConf(YTDLPConfName, newYtdlpCfg); err != nil {
+		if err := SaveYTDLPConf(paths.YTDLPConfPath(), newYtdlpCfg); err != nil {
 			dialog.ShowError(fmt.Errorf("无法保存yt-dlp配置: %v", err), w)
 			return
 		}
 
 		// Ensure output directory exists
-		actualPath := newAppCfg.OutputDir
-		if !filepath.IsAbs(actualPath) {
-			actualPath = filepath.Join(exeDir, actualPath)
-		}
+		actualPath := paths.Resolve(newAppCfg.OutputDir)
 		if err := os.MkdirAll(actualPath, 0755); err != nil {
 			dialog.ShowError(fmt.Errorf("无法创建输出目录: %v", err), w)
 			return
@@ -248,14 +697,3 @@ func showSettingsDialog(w fyne.Window, appCfg *AppConfig, ytdlpCfg *YTDLPConfig,
 
 	dlg.Show()
 }
-
-// syncProxyFromApp syncs the app download proxy to yt-dlp proxy
-func syncProxyFromApp(appCfg *AppConfig, ytdlpCfg *YTDLPConfig) {
-	if appCfg.DownloadProxy != "" {
-		ytdlpCfg.ProxyAddress = appCfg.DownloadProxy
-		ytdlpCfg.UseProxy = true
-	} else {
-		ytdlpCfg.UseProxy = false
-		ytdlpCfg.ProxyAddress = ""
-	}
-}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultSponsorBlockAPI 为 yt-dlp 默认使用的 SponsorBlock API 地址。
+const DefaultSponsorBlockAPI = "https://sponsor.ajay.app"
+
+// sponsorBlockCategory 为 SponsorBlock 的片段类别。
+type sponsorBlockCategory struct {
+	Name  string
+	Label string
+	// MarkOnly 表示该类别只能标记为章节，不能移除
+	MarkOnly bool
+}
+
+// sponsorBlockCategories 为 yt-dlp 支持的 SponsorBlock 类别。
+var sponsorBlockCategories = []sponsorBlockCategory{
+	{Name: "sponsor", Label: "赞助广告"},
+	{Name: "intro", Label: "片头/间歇动画"},
+	{Name: "outro", Label: "片尾/结束画面"},
+	{Name: "selfpromo", Label: "自我推广"},
+	{Name: "preview", Label: "预览/回顾"},
+	{Name: "filler", Label: "无关闲聊"},
+	{Name: "interaction", Label: "互动提醒（点赞、订阅）"},
+	{Name: "music_offtopic", Label: "音乐中的非音乐部分"},
+	{Name: "poi_highlight", Label: "精彩时刻", MarkOnly: true},
+	{Name: "chapter", Label: "作者章节", MarkOnly: true},
+}
+
+// sponsorBlockOptions 返回可用于标记（removable 为 false）或移除的类别。
+func sponsorBlockOptions(removable bool) []sponsorBlockCategory {
+	var cats []sponsorBlockCategory
+	for _, c := range sponsorBlockCategories {
+		if !removable || !c.MarkOnly {
+			cats = append(cats, c)
+		}
+	}
+	return cats
+}
+
+// expandSponsorBlockCategories 将 --sponsorblock-mark/--sponsorblock-remove 的值展开为类别列表。
+// 支持 "all"、"default"（除 filler 外的全部类别）和以 "-" 开头的排除项，未知类别原样保留以便校验。
+func expandSponsorBlockCategories(value string, removable bool) []string {
+	var cats, excluded []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+		case strings.HasPrefix(name, "-"):
+			excluded = append(excluded, strings.TrimPrefix(name, "-"))
+		case name == "all" || name == "default":
+			for _, c := range sponsorBlockOptions(removable) {
+				if name == "default" && c.Name == "filler" {
+					continue
+				}
+				cats = append(cats, c.Name)
+			}
+		default:
+			cats = append(cats, name)
+		}
+	}
+	var result []string
+	for _, c := range cats {
+		if !slices.Contains(excluded, c) && !slices.Contains(result, c) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// validateSponsorBlock 检查标记和移除的类别，同一类别不能既标记又移除。
+func validateSponsorBlock(mark, remove []string, api string) error {
+	known := func(name string, removable bool) bool {
+		return slices.ContainsFunc(sponsorBlockOptions(removable), func(c sponsorBlockCategory) bool { return c.Name == name })
+	}
+	for _, name := range mark {
+		if !known(name, false) {
+			return fmt.Errorf("未知的 SponsorBlock 类别: %s", name)
+		}
+	}
+	for _, name := range remove {
+		if !known(name, true) {
+			return fmt.Errorf("SponsorBlock 类别不能移除: %s", name)
+		}
+		if slices.Contains(mark, name) {
+			return fmt.Errorf("SponsorBlock 类别 %s 不能同时标记和移除", name)
+		}
+	}
+	if api != "" {
+		if u, err := url.Parse(api); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("SponsorBlock API 地址必须是 http(s) 网址")
+		}
+	}
+	return nil
+}
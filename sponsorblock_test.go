@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandSponsorBlockCategories(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		removable bool
+		want      []string
+	}{
+		{"列表", " sponsor, Intro ,sponsor", false, []string{"sponsor", "intro"}},
+		{"空", "", false, nil},
+		{"all 标记", "all", false, []string{"sponsor", "intro", "outro", "selfpromo", "preview", "filler", "interaction", "music_offtopic", "poi_highlight", "chapter"}},
+		{"all 移除不含仅标记类别", "all", true, []string{"sponsor", "intro", "outro", "selfpromo", "preview", "filler", "interaction", "music_offtopic"}},
+		{"default 与排除", "default,-intro,-preview", true, []string{"sponsor", "outro", "selfpromo", "interaction", "music_offtopic"}},
+		{"未知类别保留", "sponsor,unknown", false, []string{"sponsor", "unknown"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandSponsorBlockCategories(tt.value, tt.removable); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandSponsorBlockCategories(%q, %v) = %q, want %q", tt.value, tt.removable, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSponsorBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		mark    []string
+		remove  []string
+		api     string
+		wantErr string
+	}{
+		{"有效", []string{"intro", "chapter"}, []string{"sponsor"}, "http://127.0.0.1:8080", ""},
+		{"全部为空", nil, nil, "", ""},
+		{"未知类别", []string{"unknown"}, nil, "", "未知"},
+		{"仅标记类别不能移除", nil, []string{"poi_highlight"}, "", "不能移除"},
+		{"同时标记和移除", []string{"sponsor"}, []string{"sponsor"}, "", "同时标记和移除"},
+		{"API 地址无效", nil, nil, "sponsor.ajay.app", "http(s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSponsorBlock(tt.mark, tt.remove, tt.api)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateSponsorBlock() 出错: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateSponsorBlock() err = %v, want 包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/ini.v1"
+
+	"yinr.cc/yt-dlp-simpgo/utils"
+)
+
+const (
+	SubscriptionsFileName = "subscriptions.ini"
+	// SubscriptionsDirName 为保存各订阅下载记录（--download-archive）的目录名。
+	SubscriptionsDirName = "subscriptions"
+)
+
+// defaultSubscriptionInterval 为默认的订阅检查间隔。
+const defaultSubscriptionInterval = 6 * time.Hour
+
+const subscriptionSectionPrefix = "subscription."
+
+// Subscription 为一个订阅的频道或播放列表。
+type Subscription struct {
+	ID           int
+	Name         string
+	URL          string
+	Enabled      bool
+	AutoDownload bool   // 检查到新内容时自动加入下载队列
+	DateAfter    string // YYYYMMDD，仅下载此日期及之后上传的内容
+	MaxDuration  int    // 最长时长（分钟），0 表示不限
+	TitleRegex   string // 标题需匹配的正则表达式，不区分大小写
+	Profile      string // 下载方案的名称（见 downloadProfiles），为空时使用默认方案
+	ExtraArgs    string // 该订阅额外的 yt-dlp 参数，位于下载方案的参数之后
+
+	LastChecked time.Time
+	NewCount    int
+	LastError   string
+}
+
+// FlatPlaylist 为 yt-dlp --flat-playlist -J 的输出。
+type FlatPlaylist struct {
+	Title        string      `json:"title"`
+	ExtractorKey string      `json:"extractor_key"`
+	Entries      []FlatEntry `json:"entries"`
+}
+
+// FlatEntry 为播放列表中的单个条目，扁平模式下部分字段可能缺失。
+type FlatEntry struct {
+	ID         string      `json:"id"`
+	Title      string      `json:"title"`
+	URL        string      `json:"url"`
+	IEKey      string      `json:"ie_key"`
+	Duration   float64     `json:"duration"`
+	UploadDate string      `json:"upload_date"`
+	Entries    []FlatEntry `json:"entries"` // 频道页中嵌套的标签页
+}
+
+// validate 检查订阅设置是否有效。
+func (s *Subscription) validate() error {
+	if strings.TrimSpace(s.URL) == "" {
+		return errors.New("订阅地址不能为空")
+	}
+	if s.DateAfter != "" {
+		if _, err := time.Parse("20060102", s.DateAfter); err != nil {
+			return fmt.Errorf("日期格式无效: %s（示例：20240101）", s.DateAfter)
+		}
+	}
+	if s.MaxDuration < 0 {
+		return errors.New("最长时长不能为负数")
+	}
+	if s.TitleRegex != "" {
+		if _, err := regexp.Compile(s.TitleRegex); err != nil {
+			return fmt.Errorf("标题正则无效: %w", err)
+		}
+	}
+	if _, err := FindDownloadProfile(s.Profile); err != nil {
+		return err
+	}
+	if _, err := splitCommandLine(s.ExtraArgs); err != nil {
+		return fmt.Errorf("额外参数无效: %w", err)
+	}
+	return nil
+}
+
+// matches 判断条目是否满足订阅的筛选条件，缺失的字段视为满足。
+func (s *Subscription) matches(e FlatEntry) bool {
+	if s.DateAfter != "" && e.UploadDate != "" && e.UploadDate < s.DateAfter {
+		return false
+	}
+	if s.MaxDuration > 0 && e.Duration > float64(s.MaxDuration*60) {
+		return false
+	}
+	if s.TitleRegex != "" {
+		re, err := regexp.Compile("(?i)" + s.TitleRegex)
+		if err != nil || !re.MatchString(e.Title) {
+			return false
+		}
+	}
+	return true
+}
+
+// newEntries 返回不在下载记录中且满足筛选条件的条目。
+func (s *Subscription) newEntries(pl *FlatPlaylist, archive map[string]bool) []FlatEntry {
+	var result []FlatEntry
+	var walk func(entries []FlatEntry)
+	walk = func(entries []FlatEntry) {
+		for _, e := range entries {
+			if len(e.Entries) > 0 {
+				walk(e.Entries)
+				continue
+			}
+			if e.ID == "" {
+				continue
+			}
+			ie := e.IEKey
+			if ie == "" {
+				ie = pl.ExtractorKey
+			}
+			if archive[archiveKey(ie, e.ID)] || !s.matches(e) {
+				continue
+			}
+			result = append(result, e)
+		}
+	}
+	walk(pl.Entries)
+	return result
+}
+
+// downloadArgs 返回下载该订阅新内容时追加的 yt-dlp 参数。
+func (s *Subscription) downloadArgs(archivePath string) []string {
+	args := []string{"--yes-playlist", "--download-archive", archivePath}
+	if s.DateAfter != "" {
+		args = append(args, "--dateafter", s.DateAfter)
+	}
+	var filters []string
+	if s.MaxDuration > 0 {
+		filters = append(filters, fmt.Sprintf("duration <= %d", s.MaxDuration*60))
+	}
+	if s.TitleRegex != "" {
+		filters = append(filters, "title ~= "+quoteMatchFilter("(?i)"+s.TitleRegex))
+	}
+	if len(filters) > 0 {
+		args = append(args, "--match-filters", strings.Join(filters, " & "))
+	}
+	if extra, err := splitCommandLine(s.ExtraArgs); err == nil {
+		args = append(args, extra...)
+	}
+	return args
+}
+
+// quoteMatchFilter 将值用单引号括起，并转义 yt-dlp 过滤表达式中的引号和 &。
+func quoteMatchFilter(v string) string {
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	v = strings.ReplaceAll(v, `&`, `\&`)
+	return "'" + v + "'"
+}
+
+// LoadSubscriptions 从 ini 文件加载订阅和检查间隔，文件不存在时返回空列表。
+func LoadSubscriptions(path string) ([]*Subscription, time.Duration, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, defaultSubscriptionInterval, nil
+	}
+	cfg, err := ini.Load(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("无法加载订阅文件: %w", err)
+	}
+	interval := time.Duration(cfg.Section("sync").Key("interval_minutes").MustInt(int(defaultSubscriptionInterval/time.Minute))) * time.Minute
+
+	var subs []*Subscription
+	for _, sec := range cfg.Sections() {
+		if !strings.HasPrefix(sec.Name(), subscriptionSectionPrefix) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(sec.Name(), subscriptionSectionPrefix))
+		if err != nil {
+			continue
+		}
+		sub := &Subscription{
+			ID:           id,
+			Name:         sec.Key("name").String(),
+			URL:          sec.Key("url").String(),
+			Enabled:      sec.Key("enabled").MustBool(true),
+			AutoDownload: sec.Key("auto_download").MustBool(false),
+			DateAfter:    sec.Key("date_after").String(),
+			MaxDuration:  sec.Key("max_duration").MustInt(0),
+			TitleRegex:   sec.Key("title_regex").String(),
+			Profile:      sec.Key("profile").String(),
+			ExtraArgs:    sec.Key("extra_args").String(),
+			NewCount:     sec.Key("new_count").MustInt(0),
+			LastError:    sec.Key("last_error").String(),
+		}
+		if t, err := time.Parse(time.RFC3339, sec.Key("last_checked").String()); err == nil {
+			sub.LastChecked = t
+		}
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs, interval, nil
+}
+
+// SaveSubscriptions 将订阅和检查间隔写入 ini 文件。
+func SaveSubscriptions(path string, subs []*Subscription, interval time.Duration) error {
+	cfg := ini.Empty()
+	cfg.Section("sync").Key("interval_minutes").SetValue(strconv.Itoa(int(interval / time.Minute)))
+	for _, sub := range subs {
+		sec := cfg.Section(subscriptionSectionPrefix + strconv.Itoa(sub.ID))
+		sec.Key("name").SetValue(sub.Name)
+		sec.Key("url").SetValue(sub.URL)
+		sec.Key("enabled").SetValue(strconv.FormatBool(sub.Enabled))
+		sec.Key("auto_download").SetValue(strconv.FormatBool(sub.AutoDownload))
+		sec.Key("date_after").SetValue(sub.DateAfter)
+		sec.Key("max_duration").SetValue(strconv.Itoa(sub.MaxDuration))
+		sec.Key("title_regex").SetValue(sub.TitleRegex)
+		sec.Key("profile").SetValue(sub.Profile)
+		sec.Key("extra_args").SetValue(sub.ExtraArgs)
+		if !sub.LastChecked.IsZero() {
+			sec.Key("last_checked").SetValue(sub.LastChecked.Format(time.RFC3339))
+		}
+		sec.Key("new_count").SetValue(strconv.Itoa(sub.NewCount))
+		sec.Key("last_error").SetValue(sub.LastError)
+	}
+	if err := cfg.SaveTo(path); err != nil {
+		return fmt.Errorf("无法保存订阅文件: %w", err)
+	}
+	return nil
+}
+
+// fetchFlatPlaylist 使用 yt-dlp 获取播放列表或频道的条目列表，不下载内容。proxy 为 yt-dlp 使用的代理。
+func fetchFlatPlaylist(ytDlpPath string, paths AppPaths, url string, proxy ProxyRoute) (*FlatPlaylist, error) {
+	args := []string{"--flat-playlist", "-J", "--no-warnings", "--config-location", paths.YTDLPConfPath()}
+	args = append(append(args, ytDlpProxyArgs(proxy)...), url)
+	cmd := utils.ExecCmd(ytDlpPath, args...)
+	cmd.Dir = paths.DataDir
+	applyProxyEnv(cmd, proxy)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("获取列表失败: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("获取列表失败: %w", err)
+	}
+	var pl FlatPlaylist
+	if err := json.Unmarshal(out, &pl); err != nil {
+		return nil, fmt.Errorf("无法解析列表: %w", err)
+	}
+	return &pl, nil
+}
+
+// SubscriptionManager 管理订阅列表，定期检查新内容并按需加入下载队列。
+type SubscriptionManager struct {
+	path       string
+	archiveDir string
+	clock      Clock
+	fetch      func(url string) (*FlatPlaylist, error)
+	enqueue    func(job Job)
+
+	mu        sync.Mutex
+	subs      []*Subscription
+	interval  time.Duration
+	checking  map[int]bool
+	listeners []func()
+	wake      chan struct{}
+}
+
+// NewSubscriptionManager 从 path 加载订阅，archiveDir 用于保存各订阅的下载记录。
+func NewSubscriptionManager(path, archiveDir string, clock Clock,
+	fetch func(url string) (*FlatPlaylist, error), enqueue func(job Job)) (*SubscriptionManager, error) {
+	subs, interval, err := LoadSubscriptions(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SubscriptionManager{
+		path:       path,
+		archiveDir: archiveDir,
+		clock:      clock,
+		fetch:      fetch,
+		enqueue:    enqueue,
+		subs:       subs,
+		interval:   interval,
+		checking:   map[int]bool{},
+		wake:       make(chan struct{}, 1),
+	}, nil
+}
+
+// archivePath 返回订阅的下载记录文件路径。
+func (m *SubscriptionManager) archivePath(id int) string {
+	return filepath.Join(m.archiveDir, fmt.Sprintf("%d.txt", id))
+}
+
+// List 返回所有订阅的副本。
+func (m *SubscriptionManager) List() []Subscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subs := make([]Subscription, len(m.subs))
+	for i, sub := range m.subs {
+		subs[i] = *sub
+	}
+	return subs
+}
+
+// Interval 返回自动检查间隔，0 表示不自动检查。
+func (m *SubscriptionManager) Interval() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.interval
+}
+
+// SetInterval 修改自动检查间隔并保存。
+func (m *SubscriptionManager) SetInterval(d time.Duration) error {
+	m.mu.Lock()
+	m.interval = d
+	err := m.saveLocked()
+	m.mu.Unlock()
+	m.wakeUp()
+	return err
+}
+
+// Put 添加（ID 为 0 时）或更新订阅并保存。
+func (m *SubscriptionManager) Put(sub Subscription) (Subscription, error) {
+	if err := sub.validate(); err != nil {
+		return sub, err
+	}
+	m.mu.Lock()
+	if sub.ID == 0 {
+		for _, s := range m.subs {
+			sub.ID = max(sub.ID, s.ID)
+		}
+		sub.ID++
+		m.subs = append(m.subs, &sub)
+	} else {
+		for i, s := range m.subs {
+			if s.ID == sub.ID {
+				// 检查状态不由编辑修改
+				sub.LastChecked, sub.NewCount, sub.LastError = s.LastChecked, s.NewCount, s.LastError
+				m.subs[i] = &sub
+			}
+		}
+	}
+	err := m.saveLocked()
+	m.mu.Unlock()
+	m.changed()
+	m.wakeUp()
+	return sub, err
+}
+
+// Remove 删除订阅及其下载记录。
+func (m *SubscriptionManager) Remove(id int) error {
+	m.mu.Lock()
+	kept := m.subs[:0]
+	for _, s := range m.subs {
+		if s.ID != id {
+			kept = append(kept, s)
+		}
+	}
+	m.subs = kept
+	err := m.saveLocked()
+	m.mu.Unlock()
+	_ = os.Remove(m.archivePath(id))
+	m.changed()
+	return err
+}
+
+// Check 检查订阅的新内容数量，开启自动下载时将新内容加入下载队列。
+func (m *SubscriptionManager) Check(id int) (int, error) {
+	m.mu.Lock()
+	sub := m.find(id)
+	if sub == nil || m.checking[id] {
+		m.mu.Unlock()
+		return 0, nil
+	}
+	m.checking[id] = true
+	snapshot := *sub
+	m.mu.Unlock()
+
+	var count int
+	pl, err := m.fetch(snapshot.URL)
+	if err == nil {
+		var archive map[string]bool
+		if archive, err = readArchive(m.archivePath(id)); err == nil {
+			count = len(snapshot.newEntries(pl, archive))
+		}
+	}
+
+	m.mu.Lock()
+	delete(m.checking, id)
+	sub = m.find(id)
+	if sub == nil {
+		m.mu.Unlock()
+		return 0, err
+	}
+	sub.LastChecked = m.clock.Now()
+	if err != nil {
+		sub.LastError = err.Error()
+	} else {
+		sub.LastError = ""
+		sub.NewCount = count
+		if snapshot.Name == "" && pl.Title != "" {
+			sub.Name = pl.Title
+		}
+	}
+	autoDownload := err == nil && count > 0 && sub.AutoDownload
+	_ = m.saveLocked()
+	m.mu.Unlock()
+	m.changed()
+
+	if autoDownload {
+		m.DownloadNew(id)
+	}
+	return count, err
+}
+
+// DownloadNew 将订阅加入下载队列，yt-dlp 根据下载记录跳过已下载的内容。
+func (m *SubscriptionManager) DownloadNew(id int) {
+	m.mu.Lock()
+	sub := m.find(id)
+	if sub == nil {
+		m.mu.Unlock()
+		return
+	}
+	job := Job{URL: sub.URL, Profile: sub.Profile, ExtraArgs: sub.downloadArgs(m.archivePath(id))}
+	sub.NewCount = 0
+	_ = m.saveLocked()
+	m.mu.Unlock()
+	m.changed()
+
+	_ = os.MkdirAll(m.archiveDir, 0755)
+	m.enqueue(job)
+}
+
+// OnChange 注册订阅状态变化时的回调。
+func (m *SubscriptionManager) OnChange(fn func()) {
+	m.mu.Lock()
+	m.listeners = append(m.listeners, fn)
+	m.mu.Unlock()
+}
+
+// Start 启动后台协程，按间隔自动检查已启用的订阅。
+func (m *SubscriptionManager) Start() {
+	go m.loop()
+}
+
+func (m *SubscriptionManager) loop() {
+	for {
+		due, wait := m.dueSubscriptions()
+		for _, id := range due {
+			_, _ = m.Check(id)
+		}
+		if len(due) > 0 {
+			continue
+		}
+		if wait <= 0 || wait > queueRecheckInterval {
+			wait = queueRecheckInterval
+		}
+		select {
+		case <-m.clock.After(wait):
+		case <-m.wake:
+		}
+	}
+}
+
+// dueSubscriptions 返回已到检查时间的订阅，以及距下一个订阅到期的时间。
+func (m *SubscriptionManager) dueSubscriptions() ([]int, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.interval <= 0 {
+		return nil, 0
+	}
+	now := m.clock.Now()
+	var due []int
+	var wait time.Duration
+	for _, sub := range m.subs {
+		if !sub.Enabled || m.checking[sub.ID] {
+			continue
+		}
+		next := sub.LastChecked.Add(m.interval)
+		if !next.After(now) {
+			due = append(due, sub.ID)
+		} else if d := next.Sub(now); wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	return due, wait
+}
+
+func (m *SubscriptionManager) find(id int) *Subscription {
+	for _, s := range m.subs {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m *SubscriptionManager) saveLocked() error {
+	return SaveSubscriptions(m.path, m.subs, m.interval)
+}
+
+func (m *SubscriptionManager) wakeUp() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (m *SubscriptionManager) changed() {
+	m.mu.Lock()
+	listeners := append([]func(){}, m.listeners...)
+	m.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testPlaylist() *FlatPlaylist {
+	return &FlatPlaylist{
+		Title:        "频道",
+		ExtractorKey: "YoutubeTab",
+		Entries: []FlatEntry{
+			{ID: "a", Title: "Episode 1", IEKey: "Youtube", Duration: 600, UploadDate: "20240101"},
+			{ID: "b", Title: "Episode 2", IEKey: "Youtube", Duration: 7200, UploadDate: "20240201"},
+			{ID: "c", Title: "Trailer", IEKey: "Youtube", Duration: 60},
+			{Title: "Shorts", Entries: []FlatEntry{
+				{ID: "d", Title: "Episode short", IEKey: "Youtube", Duration: 30, UploadDate: "20231201"},
+			}},
+		},
+	}
+}
+
+func entryIDs(entries []FlatEntry) []string {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func TestSubscriptionNewEntries(t *testing.T) {
+	archive := map[string]bool{"youtube a": true}
+	tests := []struct {
+		name string
+		sub  Subscription
+		want []string
+	}{
+		{"no filter", Subscription{}, []string{"b", "c", "d"}},
+		{"date after", Subscription{DateAfter: "20240101"}, []string{"b", "c"}},
+		{"max duration", Subscription{MaxDuration: 30}, []string{"c", "d"}},
+		{"title regex", Subscription{TitleRegex: "^episode"}, []string{"b", "d"}},
+		{"combined", Subscription{TitleRegex: "episode", MaxDuration: 60, DateAfter: "20240101"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := entryIDs(tt.sub.newEntries(testPlaylist(), archive))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("newEntries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriptionDownloadArgs(t *testing.T) {
+	sub := Subscription{DateAfter: "20240101", MaxDuration: 10, TitleRegex: "it's a & b", ExtraArgs: `-f "bv*+ba"`}
+	got := sub.downloadArgs("/tmp/1.txt")
+	want := []string{
+		"--yes-playlist", "--download-archive", "/tmp/1.txt",
+		"--dateafter", "20240101",
+		"--match-filters", `duration <= 600 & title ~= '(?i)it\'s a \& b'`,
+		"-f", "bv*+ba",
+	}
+	if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+		t.Errorf("downloadArgs = %q, want %q", got, want)
+	}
+
+	if got := (&Subscription{}).downloadArgs("x"); len(got) != 3 {
+		t.Errorf("无筛选条件时不应追加过滤参数: %q", got)
+	}
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sub     Subscription
+		wantErr bool
+	}{
+		{"ok", Subscription{URL: "https://example.com/list", DateAfter: "20240101", TitleRegex: "a|b"}, false},
+		{"empty url", Subscription{}, true},
+		{"bad date", Subscription{URL: "u", DateAfter: "2024-01-01"}, true},
+		{"bad regex", Subscription{URL: "u", TitleRegex: "("}, true},
+		{"bad args", Subscription{URL: "u", ExtraArgs: `-f "bv`}, true},
+		{"profile", Subscription{URL: "u", Profile: "audio"}, false},
+		{"unknown profile", Subscription{URL: "u", Profile: "4k"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sub.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaveAndLoadSubscriptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SubscriptionsFileName)
+	checked := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	subs := []*Subscription{
+		{ID: 2, Name: "b", URL: "https://example.com/b", Enabled: false, MaxDuration: 20},
+		{ID: 1, Name: "a", URL: "https://example.com/a", Enabled: true, AutoDownload: true, DateAfter: "20240101",
+			TitleRegex: "ep", Profile: "audio", ExtraArgs: "--embed-thumbnail", LastChecked: checked, NewCount: 3},
+	}
+	if err := SaveSubscriptions(path, subs, 90*time.Minute); err != nil {
+		t.Fatalf("保存订阅失败: %v", err)
+	}
+	loaded, interval, err := LoadSubscriptions(path)
+	if err != nil {
+		t.Fatalf("加载订阅失败: %v", err)
+	}
+	if interval != 90*time.Minute {
+		t.Errorf("interval = %v, want 90m", interval)
+	}
+	if len(loaded) != 2 || loaded[0].ID != 1 || loaded[1].ID != 2 {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+	if !loaded[0].LastChecked.Equal(checked) {
+		t.Errorf("LastChecked = %v, want %v", loaded[0].LastChecked, checked)
+	}
+	loaded[0].LastChecked = subs[1].LastChecked
+	if *loaded[0] != *subs[1] || *loaded[1] != *subs[0] {
+		t.Errorf("loaded = %+v %+v", *loaded[0], *loaded[1])
+	}
+
+	if _, interval, err := LoadSubscriptions(filepath.Join(t.TempDir(), "none.ini")); err != nil || interval != defaultSubscriptionInterval {
+		t.Errorf("文件不存在时应返回默认间隔: %v %v", interval, err)
+	}
+}
+
+func TestSubscriptionManagerCheck(t *testing.T) {
+	dir := t.TempDir()
+	clock := newFakeClock(at(10, 0))
+	var enqueued []Job
+	mgr, err := NewSubscriptionManager(filepath.Join(dir, SubscriptionsFileName), filepath.Join(dir, SubscriptionsDirName), clock,
+		func(url string) (*FlatPlaylist, error) { return testPlaylist(), nil },
+		func(job Job) { enqueued = append(enqueued, job) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := mgr.Put(Subscription{URL: "https://example.com/list", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, SubscriptionsDirName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mgr.archivePath(sub.ID), []byte("youtube a\nyoutube b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := mgr.Check(sub.ID)
+	if err != nil || n != 2 {
+		t.Fatalf("Check = %d, %v, want 2", n, err)
+	}
+	got := mgr.List()[0]
+	if got.NewCount != 2 || got.Name != "频道" || !got.LastChecked.Equal(at(10, 0)) {
+		t.Errorf("检查后订阅状态不正确: %+v", got)
+	}
+	if len(enqueued) != 0 {
+		t.Fatal("未开启自动下载时不应加入队列")
+	}
+
+	sub.AutoDownload = true
+	sub.Profile = "audio"
+	if _, err := mgr.Put(sub); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Check(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(enqueued) != 1 || enqueued[0].URL != sub.URL || enqueued[0].Profile != "audio" {
+		t.Fatalf("enqueued = %+v", enqueued)
+	}
+	if got := mgr.List()[0]; got.NewCount != 0 {
+		t.Errorf("加入下载队列后新内容数应清零: %d", got.NewCount)
+	}
+
+	// 重新加载后状态保留
+	reloaded, _, err := LoadSubscriptions(filepath.Join(dir, SubscriptionsFileName))
+	if err != nil || len(reloaded) != 1 || !reloaded[0].AutoDownload || reloaded[0].Name != "频道" {
+		t.Errorf("reloaded = %+v, %v", reloaded, err)
+	}
+}
+
+func TestSubscriptionManagerDue(t *testing.T) {
+	dir := t.TempDir()
+	clock := newFakeClock(at(10, 0))
+	mgr, err := NewSubscriptionManager(filepath.Join(dir, SubscriptionsFileName), dir, clock, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.subs = []*Subscription{
+		{ID: 1, Enabled: true, LastChecked: at(9, 0)},
+		{ID: 2, Enabled: true, LastChecked: at(4, 0)},
+		{ID: 3, Enabled: false},
+		{ID: 4, Enabled: true},
+	}
+	mgr.interval = 6 * time.Hour
+
+	due, wait := mgr.dueSubscriptions()
+	if fmt.Sprint(due) != "[2 4]" {
+		t.Errorf("due = %v, want [2 4]", due)
+	}
+	if wait != 5*time.Hour {
+		t.Errorf("wait = %v, want 5h", wait)
+	}
+
+	mgr.interval = 0
+	if due, _ := mgr.dueSubscriptions(); len(due) != 0 {
+		t.Errorf("间隔为 0 时不应自动检查: %v", due)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newSubscriptionsView 创建订阅列表，显示每个订阅的新内容数量，可检查、下载、编辑和删除。
+func newSubscriptionsView(w fyne.Window, mgr *SubscriptionManager) fyne.CanvasObject {
+	var subs []Subscription
+
+	check := func(id int) {
+		go func() {
+			if _, err := mgr.Check(id); err != nil {
+				fyne.Do(func() { dialog.ShowError(err, w) })
+			}
+		}()
+	}
+
+	list := widget.NewList(
+		func() int { return len(subs) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			buttons := container.NewHBox(
+				widget.NewButton("检查", nil),
+				widget.NewButton("下载新内容", nil),
+				widget.NewButton("编辑", nil),
+				widget.NewButton("删除", nil),
+			)
+			return container.NewBorder(nil, nil, nil, buttons, label)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(subs) {
+				return
+			}
+			sub := subs[id]
+			row := obj.(*fyne.Container)
+			label := row.Objects[0].(*widget.Label)
+			buttons := row.Objects[1].(*fyne.Container).Objects
+
+			name := sub.Name
+			if name == "" {
+				name = sub.URL
+			}
+			text := name
+			switch {
+			case sub.LastError != "":
+				text += "  [检查失败: " + sub.LastError + "]"
+				label.Importance = widget.DangerImportance
+			case sub.NewCount > 0:
+				text += fmt.Sprintf("  [%d 个新内容]", sub.NewCount)
+				label.Importance = widget.HighImportance
+			default:
+				label.Importance = widget.MediumImportance
+			}
+			if !sub.Enabled {
+				text += "  (已停用)"
+				label.Importance = widget.LowImportance
+			}
+			if !sub.LastChecked.IsZero() {
+				text += "  上次检查: " + sub.LastChecked.Format("01-02 15:04")
+			}
+			label.SetText(text)
+
+			buttons[0].(*widget.Button).OnTapped = func() { check(sub.ID) }
+			buttons[1].(*widget.Button).OnTapped = func() { mgr.DownloadNew(sub.ID) }
+			buttons[2].(*widget.Button).OnTapped = func() { showSubscriptionDialog(w, mgr, sub) }
+			buttons[3].(*widget.Button).OnTapped = func() {
+				dialog.ShowConfirm("删除订阅", "确定删除订阅“"+name+"”？其下载记录也会被删除。", func(ok bool) {
+					if !ok {
+						return
+					}
+					if err := mgr.Remove(sub.ID); err != nil {
+						dialog.ShowError(err, w)
+					}
+				}, w)
+			}
+		},
+	)
+
+	refresh := func() {
+		subs = mgr.List()
+		list.Refresh()
+	}
+	mgr.OnChange(func() { fyne.Do(refresh) })
+	refresh()
+
+	intervalEntry := widget.NewEntry()
+	intervalEntry.SetText(strconv.Itoa(int(mgr.Interval() / time.Minute)))
+	intervalEntry.OnSubmitted = func(s string) {
+		minutes, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || minutes < 0 {
+			dialog.ShowError(fmt.Errorf("检查间隔必须是非负整数"), w)
+			return
+		}
+		if err := mgr.SetInterval(time.Duration(minutes) * time.Minute); err != nil {
+			dialog.ShowError(err, w)
+		}
+	}
+
+	addBtn := widget.NewButton("添加订阅", func() {
+		showSubscriptionDialog(w, mgr, Subscription{Enabled: true})
+	})
+	checkAllBtn := widget.NewButton("全部检查", func() {
+		for _, sub := range mgr.List() {
+			if sub.Enabled {
+				check(sub.ID)
+			}
+		}
+	})
+	toolbar := container.NewHBox(
+		addBtn, checkAllBtn, layout.NewSpacer(),
+		widget.NewLabel("自动检查间隔（分钟，0 为不检查，回车生效）:"), intervalEntry,
+	)
+	return container.NewBorder(toolbar, nil, nil, nil, list)
+}
+
+// showSubscriptionDialog 显示添加或编辑订阅的对话框。
+func showSubscriptionDialog(w fyne.Window, mgr *SubscriptionManager, sub Subscription) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(sub.Name)
+	nameEntry.SetPlaceHolder("留空则使用列表标题")
+
+	urlEntry := widget.NewEntry()
+	urlEntry.SetText(sub.URL)
+	urlEntry.SetPlaceHolder("频道或播放列表地址，频道建议使用其“视频”页地址")
+
+	dateEntry := widget.NewEntry()
+	dateEntry.SetText(sub.DateAfter)
+	dateEntry.SetPlaceHolder("YYYYMMDD，留空不限")
+
+	durationEntry := widget.NewEntry()
+	if sub.MaxDuration > 0 {
+		durationEntry.SetText(strconv.Itoa(sub.MaxDuration))
+	}
+	durationEntry.SetPlaceHolder("分钟，留空不限")
+
+	regexEntry := widget.NewEntry()
+	regexEntry.SetText(sub.TitleRegex)
+	regexEntry.SetPlaceHolder("不区分大小写，留空不限")
+
+	extraArgsEntry := widget.NewEntry()
+	extraArgsEntry.SetText(sub.ExtraArgs)
+	extraArgsEntry.SetPlaceHolder("可选，位于下载方案的参数之后，例如：--embed-subs")
+
+	profileSelect := widget.NewSelect(profileLabels(), nil)
+	profileSelect.SetSelectedIndex(0)
+	for i, p := range downloadProfiles {
+		if strings.EqualFold(p.Name, sub.Profile) {
+			profileSelect.SetSelectedIndex(i)
+		}
+	}
+
+	enabledCheck := widget.NewCheck("启用自动检查", nil)
+	enabledCheck.Checked = sub.Enabled
+	autoCheck := widget.NewCheck("发现新内容时自动下载", nil)
+	autoCheck.Checked = sub.AutoDownload
+
+	title := "编辑订阅"
+	if sub.ID == 0 {
+		title = "添加订阅"
+	}
+	items := []*widget.FormItem{
+		widget.NewFormItem("名称", nameEntry),
+		widget.NewFormItem("地址", urlEntry),
+		widget.NewFormItem("上传日期不早于", dateEntry),
+		widget.NewFormItem("最长时长", durationEntry),
+		widget.NewFormItem("标题正则", regexEntry),
+		widget.NewFormItem("下载方案", profileSelect),
+		widget.NewFormItem("额外参数", extraArgsEntry),
+		widget.NewFormItem("", enabledCheck),
+		widget.NewFormItem("", autoCheck),
+	}
+	dlg := dialog.NewForm(title, "保存", "取消", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		maxDuration := 0
+		if s := strings.TrimSpace(durationEntry.Text); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("最长时长必须是整数"), w)
+				return
+			}
+			maxDuration = n
+		}
+		sub.Name = strings.TrimSpace(nameEntry.Text)
+		sub.URL = strings.TrimSpace(urlEntry.Text)
+		sub.DateAfter = strings.TrimSpace(dateEntry.Text)
+		sub.MaxDuration = maxDuration
+		sub.TitleRegex = strings.TrimSpace(regexEntry.Text)
+		sub.Profile = ""
+		if i := profileSelect.SelectedIndex(); i > 0 {
+			sub.Profile = downloadProfiles[i].Name
+		}
+		sub.ExtraArgs = strings.TrimSpace(extraArgsEntry.Text)
+		sub.Enabled = enabledCheck.Checked
+		sub.AutoDownload = autoCheck.Checked
+		saved, err := mgr.Put(sub)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if sub.ID == 0 {
+			go func() { _, _ = mgr.Check(saved.ID) }()
+		}
+	}, w)
+	dlg.Resize(fyne.NewSize(600, 0))
+	dlg.Show()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showSubtitleDialog 列出视频可用的字幕，选择结果通过 onChosen 返回，仅对该视频生效。
+// onSaveFavorite 保存当前勾选的语言为常用组合，返回保存后的全部常用组合。
+func showSubtitleDialog(w fyne.Window, info *VideoInfo, initial SubtitleChoice, favorites []SubtitleFavorite,
+	onSaveFavorite func(SubtitleFavorite) ([]SubtitleFavorite, error), onChosen func(SubtitleChoice)) {
+
+	var langs []SubtitleLang
+	selected := map[string]bool{}
+	for _, code := range initial.Langs {
+		selected[code] = true
+	}
+
+	group := widget.NewCheckGroup(nil, nil)
+	emptyLabel := widget.NewLabel("该视频没有可用的字幕")
+	rebuild := func(includeAuto bool) {
+		langs = info.SubtitleLangs(includeAuto)
+		options := make([]string, len(langs))
+		var checked []string
+		for i, l := range langs {
+			options[i] = l.Label()
+			if selected[l.Code] {
+				checked = append(checked, options[i])
+			}
+		}
+		group.Options = options
+		group.Selected = checked
+		group.Refresh()
+		if len(langs) == 0 {
+			emptyLabel.Show()
+		} else {
+			emptyLabel.Hide()
+		}
+	}
+	group.OnChanged = func(labels []string) {
+		selected = map[string]bool{}
+		for _, l := range langs {
+			for _, label := range labels {
+				if label == l.Label() {
+					selected[l.Code] = true
+				}
+			}
+		}
+	}
+
+	includeAutoCheck := widget.NewCheck("显示并下载自动生成的字幕", rebuild)
+	includeAutoCheck.SetChecked(initial.IncludeAuto)
+	rebuild(initial.IncludeAuto)
+
+	convertSelect := widget.NewSelect(subtitleConvertLabels(), nil)
+	convertSelect.SetSelected(subtitleConvertLabel(initial.Convert))
+
+	favoriteNames := func() []string {
+		names := make([]string, len(favorites))
+		for i, f := range favorites {
+			names[i] = f.Name
+		}
+		return names
+	}
+	favoriteSelect := widget.NewSelect(favoriteNames(), func(name string) {
+		for _, f := range favorites {
+			if f.Name != name {
+				continue
+			}
+			selected = map[string]bool{}
+			for _, code := range matchSubtitleLangs(langs, f.LangList()) {
+				selected[code] = true
+			}
+			rebuild(includeAutoCheck.Checked)
+		}
+	})
+	favoriteSelect.PlaceHolder = "选择常用语言"
+
+	saveFavoriteBtn := widget.NewButton("保存为常用", func() {
+		var codes []string
+		for _, l := range langs {
+			if selected[l.Code] {
+				codes = append(codes, l.Code)
+			}
+		}
+		if len(codes) == 0 {
+			dialog.ShowInformation("提示", "请先勾选字幕语言", w)
+			return
+		}
+		nameEntry := widget.NewEntry()
+		nameEntry.SetPlaceHolder("例如：中英")
+		dialog.ShowForm("保存常用语言", "保存", "取消",
+			[]*widget.FormItem{
+				widget.NewFormItem("名称", nameEntry),
+				widget.NewFormItem("语言", widget.NewLabel(strings.Join(codes, ","))),
+			},
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				name := strings.TrimSpace(nameEntry.Text)
+				if name == "" || strings.Contains(name, "=") {
+					dialog.ShowError(errors.New("名称不能为空，且不能包含 ="), w)
+					return
+				}
+				saved, err := onSaveFavorite(SubtitleFavorite{Name: name, Langs: strings.Join(codes, ",")})
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("无法保存常用语言: %v", err), w)
+					return
+				}
+				favorites = saved
+				favoriteSelect.Options = favoriteNames()
+				favoriteSelect.SetSelected(name)
+			}, w)
+	})
+
+	scroll := container.NewVScroll(container.NewVBox(emptyLabel, group))
+	scroll.SetMinSize(fyne.NewSize(0, 320))
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("勾选要下载的字幕语言，设置仅对当前视频生效："),
+			container.NewBorder(nil, nil, nil, saveFavoriteBtn, favoriteSelect),
+			includeAutoCheck,
+		),
+		container.NewHBox(widget.NewLabel("转换格式："), convertSelect),
+		nil, nil, scroll,
+	)
+
+	dlg := dialog.NewCustomConfirm("选择字幕", "确定", "取消", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		var choice SubtitleChoice
+		choice.IncludeAuto = includeAutoCheck.Checked
+		choice.Convert = subtitleConvertValue(convertSelect.Selected)
+		for _, l := range langs {
+			if selected[l.Code] {
+				choice.Langs = append(choice.Langs, l.Code)
+			}
+		}
+		onChosen(choice)
+	}, w)
+	dlg.Resize(fyne.NewSize(520, 560))
+	dlg.Show()
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// subtitleConvertFormats 为 --convert-subs 支持的字幕格式，空字符串表示保持原格式。
+var subtitleConvertFormats = []string{"", "srt", "ass", "vtt"}
+
+// subtitleConvertLabel 返回转换格式在界面中的名称。
+func subtitleConvertLabel(format string) string {
+	if format == "" {
+		return "保持原格式"
+	}
+	return format
+}
+
+// subtitleConvertLabels 返回所有转换格式的界面名称。
+func subtitleConvertLabels() []string {
+	labels := make([]string, len(subtitleConvertFormats))
+	for i, f := range subtitleConvertFormats {
+		labels[i] = subtitleConvertLabel(f)
+	}
+	return labels
+}
+
+// subtitleConvertValue 为 subtitleConvertLabel 的逆操作。
+func subtitleConvertValue(label string) string {
+	for _, f := range subtitleConvertFormats {
+		if subtitleConvertLabel(f) == label {
+			return f
+		}
+	}
+	return ""
+}
+
+// subFavoritePrefix 标记 yt-dlp.conf 中保存常用字幕语言组合的注释行，yt-dlp 会忽略这些行。
+const subFavoritePrefix = "# simpgo-sub-favorite:"
+
+// SubtitleTrack 为某种语言的一个字幕文件格式。
+type SubtitleTrack struct {
+	Ext  string `json:"ext"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// SubtitleLang 为视频提供的一种字幕语言。
+type SubtitleLang struct {
+	Code    string
+	Name    string
+	Auto    bool // 自动生成的字幕
+	Formats []string
+}
+
+// Label 返回用于界面显示的语言说明。
+func (l SubtitleLang) Label() string {
+	label := l.Code
+	if l.Name != "" && l.Name != l.Code {
+		label += "（" + l.Name + "）"
+	}
+	if l.Auto {
+		label += " [自动生成]"
+	}
+	if len(l.Formats) > 0 {
+		label += " " + strings.Join(l.Formats, "/")
+	}
+	return label
+}
+
+// SubtitleLangs 返回视频可用的字幕语言，人工字幕在前，各自按语言代码排序。
+// includeAuto 为 true 时包含只有自动生成字幕的语言。
+func (v *VideoInfo) SubtitleLangs(includeAuto bool) []SubtitleLang {
+	collect := func(tracks map[string][]SubtitleTrack, auto bool, skip map[string][]SubtitleTrack) []SubtitleLang {
+		var langs []SubtitleLang
+		for code, formats := range tracks {
+			// live_chat 为直播聊天记录而非字幕
+			if code == "live_chat" || len(formats) == 0 {
+				continue
+			}
+			if _, ok := skip[code]; ok {
+				continue
+			}
+			lang := SubtitleLang{Code: code, Auto: auto}
+			for _, f := range formats {
+				if lang.Name == "" {
+					lang.Name = f.Name
+				}
+				if f.Ext != "" && !slices.Contains(lang.Formats, f.Ext) {
+					lang.Formats = append(lang.Formats, f.Ext)
+				}
+			}
+			langs = append(langs, lang)
+		}
+		sort.Slice(langs, func(i, j int) bool { return langs[i].Code < langs[j].Code })
+		return langs
+	}
+	langs := collect(v.Subtitles, false, nil)
+	if includeAuto {
+		langs = append(langs, collect(v.AutomaticCaptions, true, v.Subtitles)...)
+	}
+	return langs
+}
+
+// HasSubtitles 表示视频有可下载的字幕或自动生成字幕。
+func (v *VideoInfo) HasSubtitles() bool {
+	return len(v.SubtitleLangs(true)) > 0
+}
+
+// matchSubtitleLangs 返回与 patterns 匹配的语言代码。patterns 与 --sub-langs 相同，
+// 每项为完整匹配的正则表达式，如 "zh.*"；以 "-" 开头的排除项和无效表达式被忽略。
+func matchSubtitleLangs(langs []SubtitleLang, patterns []string) []string {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "-") {
+			continue
+		}
+		if p == "all" {
+			p = ".*"
+		}
+		if re, err := regexp.Compile("^(?:" + p + ")$"); err == nil {
+			res = append(res, re)
+		}
+	}
+	var codes []string
+	for _, l := range langs {
+		for _, re := range res {
+			if re.MatchString(l.Code) {
+				if !slices.Contains(codes, l.Code) {
+					codes = append(codes, l.Code)
+				}
+				break
+			}
+		}
+	}
+	return codes
+}
+
+// defaultSubtitleChoice 根据 yt-dlp.conf 中的字幕设置生成视频的初始字幕选择。
+func defaultSubtitleChoice(cfg *YTDLPConfig, info *VideoInfo) SubtitleChoice {
+	choice := SubtitleChoice{IncludeAuto: cfg.WriteAutoSubs, Convert: cfg.ConvertSubs}
+	if cfg.WriteSubs {
+		choice.Langs = matchSubtitleLangs(info.SubtitleLangs(cfg.WriteAutoSubs), strings.Split(cfg.SubLangs, ","))
+	}
+	return choice
+}
+
+// SubtitleChoice 为针对单个视频选择的字幕设置，覆盖 yt-dlp.conf 中的字幕选项。
+type SubtitleChoice struct {
+	Langs       []string
+	IncludeAuto bool
+	Convert     string // 空字符串表示保持原格式
+}
+
+// Args 返回对应的 yt-dlp 参数。未选择任何语言时不下载字幕。
+func (c SubtitleChoice) Args() []string {
+	if len(c.Langs) == 0 {
+		return []string{"--no-write-subs", "--no-write-auto-subs"}
+	}
+	args := []string{"--write-subs", "--sub-langs", strings.Join(c.Langs, ",")}
+	if c.IncludeAuto {
+		args = append(args, "--write-auto-subs")
+	} else {
+		args = append(args, "--no-write-auto-subs")
+	}
+	if c.Convert != "" {
+		args = append(args, "--convert-subs", c.Convert)
+	}
+	return args
+}
+
+// SubtitleFavorite 为保存的常用字幕语言组合。
+type SubtitleFavorite struct {
+	Name  string
+	Langs string // 以逗号分隔的语言代码，与 --sub-langs 格式相同
+}
+
+// LangList 返回语言代码列表。
+func (f SubtitleFavorite) LangList() []string {
+	var langs []string
+	for _, l := range strings.Split(f.Langs, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
+
+// confLine 返回保存到 yt-dlp.conf 中的注释行。
+func (f SubtitleFavorite) confLine() string {
+	return fmt.Sprintf("%s %s=%s", subFavoritePrefix, f.Name, strings.Join(f.LangList(), ","))
+}
+
+// parseSubFavoriteLine 解析 confLine 生成的注释行。
+func parseSubFavoriteLine(line string) (SubtitleFavorite, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), subFavoritePrefix)
+	if !ok {
+		return SubtitleFavorite{}, false
+	}
+	name, langs, ok := strings.Cut(rest, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return SubtitleFavorite{}, false
+	}
+	f := SubtitleFavorite{Name: name, Langs: strings.TrimSpace(langs)}
+	return f, len(f.LangList()) > 0
+}
+
+// putSubtitleFavorite 添加常用组合，同名时替换。
+func putSubtitleFavorite(favs []SubtitleFavorite, f SubtitleFavorite) []SubtitleFavorite {
+	result := make([]SubtitleFavorite, 0, len(favs)+1)
+	replaced := false
+	for _, existing := range favs {
+		if existing.Name == f.Name {
+			result = append(result, f)
+			replaced = true
+		} else {
+			result = append(result, existing)
+		}
+	}
+	if !replaced {
+		result = append(result, f)
+	}
+	return result
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const subtitleInfoJSON = `{
+	"subtitles": {
+		"en": [{"ext": "vtt", "name": "English"}, {"ext": "srv3", "name": "English"}],
+		"zh-Hans": [{"ext": "vtt", "name": "Chinese (Simplified)"}],
+		"live_chat": [{"ext": "json"}]
+	},
+	"automatic_captions": {
+		"en": [{"ext": "vtt", "name": "English"}],
+		"ja": [{"ext": "vtt", "name": "Japanese"}],
+		"fr": []
+	}
+}`
+
+func TestVideoInfoSubtitleLangs(t *testing.T) {
+	var info VideoInfo
+	if err := json.Unmarshal([]byte(subtitleInfoJSON), &info); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		includeAuto bool
+		want        []SubtitleLang
+	}{
+		{
+			name: "仅人工字幕",
+			want: []SubtitleLang{
+				{Code: "en", Name: "English", Formats: []string{"vtt", "srv3"}},
+				{Code: "zh-Hans", Name: "Chinese (Simplified)", Formats: []string{"vtt"}},
+			},
+		},
+		{
+			name:        "包含自动生成字幕",
+			includeAuto: true,
+			want: []SubtitleLang{
+				{Code: "en", Name: "English", Formats: []string{"vtt", "srv3"}},
+				{Code: "zh-Hans", Name: "Chinese (Simplified)", Formats: []string{"vtt"}},
+				{Code: "ja", Name: "Japanese", Auto: true, Formats: []string{"vtt"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := info.SubtitleLangs(tt.includeAuto)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SubtitleLangs(%v) = %+v, want %+v", tt.includeAuto, got, tt.want)
+			}
+		})
+	}
+
+	if !info.HasSubtitles() {
+		t.Error("HasSubtitles() = false, want true")
+	}
+	if (&VideoInfo{}).HasSubtitles() {
+		t.Error("无字幕时 HasSubtitles() = true")
+	}
+}
+
+func TestSubtitleLangLabel(t *testing.T) {
+	l := SubtitleLang{Code: "ja", Name: "Japanese", Auto: true, Formats: []string{"vtt", "srv3"}}
+	if got, want := l.Label(), "ja（Japanese） [自动生成] vtt/srv3"; got != want {
+		t.Errorf("Label() = %q, want %q", got, want)
+	}
+	if got := (SubtitleLang{Code: "en", Name: "en"}).Label(); got != "en" {
+		t.Errorf("Label() = %q, want %q", got, "en")
+	}
+}
+
+func TestMatchSubtitleLangs(t *testing.T) {
+	langs := []SubtitleLang{{Code: "en"}, {Code: "en-US"}, {Code: "zh-Hans"}, {Code: "zh-Hant"}, {Code: "ja"}}
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{"精确匹配", []string{"en"}, []string{"en"}},
+		{"正则匹配", []string{"zh.*"}, []string{"zh-Hans", "zh-Hant"}},
+		{"多项按视频顺序", []string{"ja", "en.*"}, []string{"en", "en-US", "ja"}},
+		{"all", []string{"all", "-ja"}, []string{"en", "en-US", "zh-Hans", "zh-Hant", "ja"}},
+		{"忽略空白和无效表达式", []string{" ja ", "(", ""}, []string{"ja"}},
+		{"无匹配", []string{"ko"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchSubtitleLangs(langs, tt.patterns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchSubtitleLangs(%q) = %q, want %q", tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultSubtitleChoice(t *testing.T) {
+	var info VideoInfo
+	if err := json.Unmarshal([]byte(subtitleInfoJSON), &info); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &YTDLPConfig{WriteSubs: true, SubLangs: "zh.*,ja", ConvertSubs: "srt"}
+	got := defaultSubtitleChoice(cfg, &info)
+	want := SubtitleChoice{Langs: []string{"zh-Hans"}, Convert: "srt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("defaultSubtitleChoice() = %+v, want %+v", got, want)
+	}
+
+	cfg.WriteAutoSubs = true
+	got = defaultSubtitleChoice(cfg, &info)
+	want = SubtitleChoice{Langs: []string{"zh-Hans", "ja"}, IncludeAuto: true, Convert: "srt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("包含自动字幕时 defaultSubtitleChoice() = %+v, want %+v", got, want)
+	}
+
+	cfg.WriteSubs = false
+	if got := defaultSubtitleChoice(cfg, &info); len(got.Langs) != 0 {
+		t.Errorf("未启用字幕时 Langs = %q, want 空", got.Langs)
+	}
+}
+
+func TestSubtitleChoiceArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		choice SubtitleChoice
+		want   []string
+	}{
+		{"不下载字幕", SubtitleChoice{IncludeAuto: true}, []string{"--no-write-subs", "--no-write-auto-subs"}},
+		{"人工字幕", SubtitleChoice{Langs: []string{"en", "zh-Hans"}},
+			[]string{"--write-subs", "--sub-langs", "en,zh-Hans", "--no-write-auto-subs"}},
+		{"自动字幕并转换", SubtitleChoice{Langs: []string{"ja"}, IncludeAuto: true, Convert: "srt"},
+			[]string{"--write-subs", "--sub-langs", "ja", "--write-auto-subs", "--convert-subs", "srt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.choice.Args(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Args() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtitleFavoriteLine(t *testing.T) {
+	f := SubtitleFavorite{Name: "中英", Langs: " zh-Hans , en ,"}
+	line := f.confLine()
+	if want := subFavoritePrefix + " 中英=zh-Hans,en"; line != want {
+		t.Errorf("confLine() = %q, want %q", line, want)
+	}
+	got, ok := parseSubFavoriteLine(line)
+	if !ok || got.Name != "中英" || !reflect.DeepEqual(got.LangList(), []string{"zh-Hans", "en"}) {
+		t.Errorf("parseSubFavoriteLine(%q) = %+v, %v", line, got, ok)
+	}
+
+	for _, line := range []string{
+		"# 其他注释",
+		subFavoritePrefix + " 无等号",
+		subFavoritePrefix + " =en",
+		subFavoritePrefix + " 空=",
+	} {
+		if _, ok := parseSubFavoriteLine(line); ok {
+			t.Errorf("parseSubFavoriteLine(%q) 应失败", line)
+		}
+	}
+}
+
+func TestPutSubtitleFavorite(t *testing.T) {
+	favs := []SubtitleFavorite{{Name: "a", Langs: "en"}, {Name: "b", Langs: "ja"}}
+	got := putSubtitleFavorite(favs, SubtitleFavorite{Name: "a", Langs: "zh-Hans"})
+	want := []SubtitleFavorite{{Name: "a", Langs: "zh-Hans"}, {Name: "b", Langs: "ja"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("替换后 = %v, want %v", got, want)
+	}
+	if favs[0].Langs != "en" {
+		t.Error("putSubtitleFavorite 不应修改原切片")
+	}
+	got = putSubtitleFavorite(favs, SubtitleFavorite{Name: "c", Langs: "fr"})
+	if len(got) != 3 || got[2].Name != "c" {
+		t.Errorf("添加后 = %v", got)
+	}
+}
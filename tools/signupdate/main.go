@@ -0,0 +1,106 @@
+// signupdate 生成更新签名密钥，并为发布文件签名。
+//
+// 用法:
+//
+//	go run ./tools/signupdate keygen -out update.key
+//	go run ./tools/signupdate sign -key update.key -version v1.2.0 dist/yt-dlp-simpgo-*
+//
+// keygen 输出的公钥写入 version.go 中的 releaseUpdatePublicKey（测试时也可在构建时通过
+// -X 'main.UpdatePublicKey=...' 替换）；
+// sign 为每个文件生成同名的 .sig 签名文件，与发布文件一起上传。
+// 私钥也可以通过环境变量 UPDATE_SIGNING_KEY 提供。
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"yinr.cc/yt-dlp-simpgo/utils"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "keygen":
+		err = keygen(os.Args[2:])
+	case "sign":
+		err = sign(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: signupdate keygen -out <私钥文件>")
+	fmt.Fprintln(os.Stderr, "      signupdate sign [-key <私钥文件>] -version <版本号> <文件>...")
+	os.Exit(2)
+}
+
+func keygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := fs.String("out", "update.key", "私钥文件")
+	fs.Parse(args)
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return fmt.Errorf("生成密钥失败: %w", err)
+	}
+	if err := os.WriteFile(*out, []byte(base64.StdEncoding.EncodeToString(priv.Seed())+"\n"), 0600); err != nil {
+		return fmt.Errorf("保存私钥失败: %w", err)
+	}
+	fmt.Println(base64.StdEncoding.EncodeToString(pub))
+	return nil
+}
+
+func sign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyPath := fs.String("key", "", "私钥文件，为空时读取环境变量 UPDATE_SIGNING_KEY")
+	version := fs.String("version", "", "发布的版本号，如 v1.2.0")
+	fs.Parse(args)
+	if *version == "" || fs.NArg() == 0 {
+		usage()
+	}
+
+	encoded := os.Getenv("UPDATE_SIGNING_KEY")
+	if *keyPath != "" {
+		data, err := os.ReadFile(*keyPath)
+		if err != nil {
+			return fmt.Errorf("读取私钥失败: %w", err)
+		}
+		encoded = string(data)
+	}
+	if strings.TrimSpace(encoded) == "" {
+		return fmt.Errorf("未提供签名私钥")
+	}
+	key, err := utils.ParseUpdatePrivateKey(encoded)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range fs.Args() {
+		if strings.HasSuffix(path, utils.UpdateSignatureExt) {
+			continue
+		}
+		sig, err := utils.SignUpdate(key, path, filepath.Base(path), *version)
+		if err != nil {
+			return fmt.Errorf("签名 %s 失败: %w", path, err)
+		}
+		if err := os.WriteFile(path+utils.UpdateSignatureExt, sig, 0644); err != nil {
+			return fmt.Errorf("保存签名失败: %w", err)
+		}
+		fmt.Println(path + utils.UpdateSignatureExt)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 自动检查更新的间隔（小时）的特殊值。
+const (
+	UpdateCheckEveryLaunch = 0
+	UpdateCheckNever       = -1
+)
+
+const (
+	DefaultUpdateCheckHours = 24
+	DefaultRemindDays       = 3
+)
+
+// UpdateConfig 为程序和 yt-dlp 的更新设置，以及检查更新的记录。
+type UpdateConfig struct {
+	// 允许安装没有签名或无法校验签名的更新，默认拒绝
+	AllowUnsigned bool
+	// 启动时自动检查更新的间隔（小时），UpdateCheckEveryLaunch 为每次启动都检查，UpdateCheckNever 为不检查
+	CheckIntervalHours int
+	// yt-dlp 有新版本时直接更新，不再询问
+	AutoUpdateYtDlp bool
+	// 检查程序更新时包含预发布版本
+	Prerelease bool
+	// 选择稍后提醒后，再次提醒前等待的天数
+	RemindDays int
+	// 上次成功检查更新的时间
+	LastCheck time.Time
+	// 对程序和 yt-dlp 更新提示的选择
+	App   UpdatePrompt
+	YtDlp UpdatePrompt
+}
+
+// UpdatePrompt 记录用户对更新提示的选择。
+type UpdatePrompt struct {
+	SkipVersion string    // 跳过的版本，该版本不再提示，更新的版本仍会提示
+	RemindAfter time.Time // 在此之前不提示
+}
+
+// CheckDue 返回 now 时是否应自动检查更新。
+func (c UpdateConfig) CheckDue(now time.Time) bool {
+	switch {
+	case c.CheckIntervalHours < 0:
+		return false
+	case c.CheckIntervalHours == UpdateCheckEveryLaunch || c.LastCheck.IsZero():
+		return true
+	}
+	// 系统时间被调回时上次检查的时间可能在将来，此时也检查
+	elapsed := now.Sub(c.LastCheck)
+	return elapsed < 0 || elapsed >= time.Duration(c.CheckIntervalHours)*time.Hour
+}
+
+// RemindAt 返回在 now 选择稍后提醒时，再次提醒的时间。
+func (c UpdateConfig) RemindAt(now time.Time) time.Time {
+	return now.AddDate(0, 0, c.remindDays())
+}
+
+func (c UpdateConfig) remindDays() int {
+	if c.RemindDays <= 0 {
+		return DefaultRemindDays
+	}
+	return c.RemindDays
+}
+
+// ShouldPrompt 返回 now 时是否提示更新到 version。
+func (p UpdatePrompt) ShouldPrompt(version string, now time.Time) bool {
+	if p.SkipVersion != "" && strings.TrimPrefix(p.SkipVersion, "v") == strings.TrimPrefix(version, "v") {
+		return false
+	}
+	return !now.Before(p.RemindAfter)
+}
+
+// updateIntervalOptions 为设置中可选的检查更新间隔。
+var updateIntervalOptions = []struct {
+	Label string
+	Hours int
+}{
+	{"每次启动", UpdateCheckEveryLaunch},
+	{"每天", 24},
+	{"每周", 7 * 24},
+	{"不自动检查", UpdateCheckNever},
+}
+
+// updateIntervalLabel 返回检查更新间隔的显示名称，手动编辑配置文件设置的其他间隔显示为小时数。
+func updateIntervalLabel(hours int) string {
+	for _, o := range updateIntervalOptions {
+		if o.Hours == hours {
+			return o.Label
+		}
+	}
+	return fmt.Sprintf("每 %d 小时", hours)
+}
+
+// formatIniTime 将时间保存为 RFC 3339 格式，零值保存为空。
+func formatIniTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// parseIniTime 解析 formatIniTime 保存的时间，格式错误时返回零值。
+func parseIniTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateCheckDue(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		cfg  UpdateConfig
+		want bool
+	}{
+		{name: "every launch", cfg: UpdateConfig{CheckIntervalHours: UpdateCheckEveryLaunch, LastCheck: now}, want: true},
+		{name: "never", cfg: UpdateConfig{CheckIntervalHours: UpdateCheckNever}, want: false},
+		{name: "never checked", cfg: UpdateConfig{CheckIntervalHours: 24}, want: true},
+		{name: "checked recently", cfg: UpdateConfig{CheckIntervalHours: 24, LastCheck: now.Add(-23 * time.Hour)}, want: false},
+		{name: "interval elapsed", cfg: UpdateConfig{CheckIntervalHours: 24, LastCheck: now.Add(-24 * time.Hour)}, want: true},
+		{name: "clock moved back", cfg: UpdateConfig{CheckIntervalHours: 24, LastCheck: now.Add(time.Hour)}, want: true},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.CheckDue(now); got != tt.want {
+			t.Errorf("%s: CheckDue = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpdatePromptShouldPrompt(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		prompt  UpdatePrompt
+		version string
+		want    bool
+	}{
+		{name: "no choice", version: "v1.2.0", want: true},
+		{name: "skipped", prompt: UpdatePrompt{SkipVersion: "v1.2.0"}, version: "v1.2.0", want: false},
+		{name: "skipped without prefix", prompt: UpdatePrompt{SkipVersion: "2024.05.01"}, version: "v2024.05.01", want: false},
+		{name: "newer than skipped", prompt: UpdatePrompt{SkipVersion: "v1.2.0"}, version: "v1.3.0", want: true},
+		{name: "remind later", prompt: UpdatePrompt{RemindAfter: now.Add(time.Hour)}, version: "v1.2.0", want: false},
+		{name: "remind time reached", prompt: UpdatePrompt{RemindAfter: now}, version: "v1.2.0", want: true},
+	}
+	for _, tt := range tests {
+		if got := tt.prompt.ShouldPrompt(tt.version, now); got != tt.want {
+			t.Errorf("%s: ShouldPrompt = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateRemindAt(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	if got := (UpdateConfig{RemindDays: 7}).RemindAt(now); !got.Equal(now.AddDate(0, 0, 7)) {
+		t.Errorf("RemindAt = %v", got)
+	}
+	if got := (UpdateConfig{}).RemindAt(now); !got.Equal(now.AddDate(0, 0, DefaultRemindDays)) {
+		t.Errorf("未设置天数时应使用默认值: %v", got)
+	}
+}
+
+func TestParseIniTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 30, 0, 0, time.FixedZone("CST", 8*3600))
+	if got := parseIniTime(formatIniTime(now)); !got.Equal(now) {
+		t.Errorf("parseIniTime(formatIniTime(%v)) = %v", now, got)
+	}
+	if formatIniTime(time.Time{}) != "" || !parseIniTime("").IsZero() || !parseIniTime("yesterday").IsZero() {
+		t.Error("零值和格式错误的时间应为空")
+	}
+}
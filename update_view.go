@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// updateChoice 为用户在更新提示中的选择。
+type updateChoice int
+
+const (
+	updateChoiceInstall updateChoice = iota
+	updateChoiceRemind
+	updateChoiceSkip
+)
+
+// showUpdateDialog 显示更新提示和发布说明，notes 为 Markdown，为空时不显示。
+func showUpdateDialog(w fyne.Window, title, message, notes string, remindDays int, onChoice func(updateChoice)) {
+	msg := widget.NewLabel(message)
+	msg.Wrapping = fyne.TextWrapWord
+	var content fyne.CanvasObject = msg
+	if strings.TrimSpace(notes) != "" {
+		rt := widget.NewRichTextFromMarkdown(notes)
+		rt.Wrapping = fyne.TextWrapWord
+		scroll := container.NewVScroll(rt)
+		scroll.SetMinSize(fyne.NewSize(560, 300))
+		content = container.NewBorder(container.NewVBox(msg, widget.NewLabel("更新说明：")), nil, nil, nil, scroll)
+	}
+
+	d := dialog.NewCustomWithoutButtons(title, content, w)
+	choose := func(c updateChoice) func() {
+		return func() {
+			d.Hide()
+			onChoice(c)
+		}
+	}
+	installBtn := widget.NewButton("立即更新", choose(updateChoiceInstall))
+	installBtn.Importance = widget.HighImportance
+	d.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("跳过此版本", choose(updateChoiceSkip)),
+		widget.NewButton(fmt.Sprintf("%d 天后提醒", remindDays), choose(updateChoiceRemind)),
+		installBtn,
+	})
+	d.Show()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// URLScheme 为程序注册的链接协议，如 ytdlp-simpgo://download?url=https%3A%2F%2F...&profile=audio。
+const URLScheme = "ytdlp-simpgo"
+
+// urlSchemeDesktopFile 为 Linux 上注册链接协议使用的 .desktop 文件名。
+const urlSchemeDesktopFile = "yt-dlp-simpgo.desktop"
+
+// LaunchRequest 为命令行参数或链接中要下载的一个网址。
+type LaunchRequest struct {
+	URL     string
+	Profile DownloadProfile
+}
+
+// parseLaunchArgs 解析启动程序时的参数（包括后启动的程序转发的参数），支持：
+//
+//	https://...                         按默认方案下载
+//	--profile audio https://...          为之后的网址指定下载方案，也可写作 --profile=audio
+//	ytdlp-simpgo://download?url=...      链接协议，见 parseSchemeURL
+//
+// 返回有效的请求，无效的参数合并为一个错误返回。
+func parseLaunchArgs(args []string) ([]LaunchRequest, error) {
+	var reqs []LaunchRequest
+	var errs []error
+	profile := DefaultDownloadProfile()
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimSpace(args[i])
+		lower := strings.ToLower(arg)
+		switch {
+		case arg == "":
+		case arg == "--profile" || strings.HasPrefix(arg, "--profile="):
+			name, ok := strings.CutPrefix(arg, "--profile=")
+			if !ok {
+				if i+1 >= len(args) {
+					errs = append(errs, errors.New("--profile 缺少方案名称"))
+					continue
+				}
+				i++
+				name = strings.TrimSpace(args[i])
+			}
+			p, err := FindDownloadProfile(name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			profile = p
+		case strings.HasPrefix(lower, URLScheme+":"):
+			r, err := parseSchemeURL(arg)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			reqs = append(reqs, r...)
+		case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+			u, err := validateDownloadURL(arg)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			reqs = append(reqs, LaunchRequest{URL: u, Profile: profile})
+		default:
+			errs = append(errs, fmt.Errorf("无法识别的参数 %q", arg))
+		}
+	}
+	return reqs, errors.Join(errs...)
+}
+
+// parseSchemeURL 解析链接协议，格式为 ytdlp-simpgo://download?url=<网址>&profile=<方案>。
+// url 必须经过 URL 编码，可重复以下载多个网址；profile 可省略，见 downloadProfiles。
+func parseSchemeURL(raw string) ([]LaunchRequest, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("链接无效: %w", err)
+	}
+	if !strings.EqualFold(u.Scheme, URLScheme) {
+		return nil, fmt.Errorf("链接协议应为 %s: %q", URLScheme, raw)
+	}
+	// 兼容 ytdlp-simpgo:download?... 和 ytdlp-simpgo:///download?... 的写法
+	action := u.Opaque
+	if action == "" {
+		action = u.Host + u.Path
+	}
+	if action = strings.ToLower(strings.Trim(action, "/")); action != "download" {
+		return nil, fmt.Errorf("链接中不支持的操作 %q", action)
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("链接参数无效: %w", err)
+	}
+	for key := range query {
+		if key != "url" && key != "profile" {
+			return nil, fmt.Errorf("链接中未知的参数 %q（url 参数中的网址需要进行 URL 编码）", key)
+		}
+	}
+	if len(query["profile"]) > 1 {
+		return nil, errors.New("链接中只能指定一个 profile")
+	}
+	profile, err := FindDownloadProfile(query.Get("profile"))
+	if err != nil {
+		return nil, err
+	}
+	if len(query["url"]) == 0 {
+		return nil, errors.New("链接中缺少 url 参数")
+	}
+	var reqs []LaunchRequest
+	for _, v := range query["url"] {
+		target, err := validateDownloadURL(v)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, LaunchRequest{URL: target, Profile: profile})
+	}
+	return reqs, nil
+}
+
+// validateDownloadURL 检查要下载的网址，只接受带主机名的 http/https 地址。
+func validateDownloadURL(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("网址无效: %w", err)
+	}
+	if scheme := strings.ToLower(u.Scheme); scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("只支持 http 和 https 网址: %q", s)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("网址缺少主机名: %q", s)
+	}
+	if strings.ContainsAny(s, " \t\r\n") {
+		return "", fmt.Errorf("网址不能包含空白字符: %q", s)
+	}
+	return s, nil
+}
+
+// RegisterURLScheme 将链接协议关联到当前程序，之后点击 ytdlp-simpgo:// 链接时启动程序（或转发给已运行的程序）。
+func RegisterURLScheme(paths AppPaths) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("无法定位当前程序: %w", err)
+	}
+	if p, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = p
+	}
+	// AppImage 每次运行时挂载的路径不同，应使用 AppImage 文件本身
+	if p := os.Getenv("APPIMAGE"); p != "" {
+		exePath = p
+	}
+	if err := registerURLScheme(exePath, paths); err != nil {
+		return fmt.Errorf("注册链接协议失败: %w", err)
+	}
+	return nil
+}
+
+// desktopEntry 返回 Linux 上注册链接协议的 .desktop 文件内容。
+func desktopEntry(exePath, iconPath string) string {
+	var b strings.Builder
+	b.WriteString("[Desktop Entry]\n")
+	b.WriteString("Type=Application\n")
+	b.WriteString("Name=yt-dlp-simpgo\n")
+	b.WriteString("Comment=基于 yt-dlp 的视频下载工具\n")
+	b.WriteString("Exec=" + desktopExecQuote(exePath) + " %u\n")
+	if iconPath != "" {
+		b.WriteString("Icon=" + desktopEscape(iconPath) + "\n")
+	}
+	b.WriteString("Terminal=false\n")
+	b.WriteString("NoDisplay=true\n")
+	b.WriteString("MimeType=x-scheme-handler/" + URLScheme + ";\n")
+	return b.String()
+}
+
+// desktopExecQuote 按 Desktop Entry 规范为 Exec 中的程序路径加引号：
+// 引号内的 " ` $ \ 需转义，% 写作 %%，整个值再按字符串值转义反斜杠。
+func desktopExecQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '`', '$', '\\':
+			b.WriteByte('\\')
+		case '%':
+			b.WriteByte('%')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return desktopEscape(b.String())
+}
+
+// desktopEscape 按 Desktop Entry 规范转义字符串值。
+func desktopEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s)
+}
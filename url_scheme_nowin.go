@@ -0,0 +1,48 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// registerURLScheme 在 Linux 等系统上写入 .desktop 文件，并用 xdg-mime 设为链接协议的默认程序。
+// macOS 需要在应用包的 Info.plist 中声明链接协议，不支持在运行时注册。
+func registerURLScheme(exePath string, paths AppPaths) error {
+	if runtime.GOOS == "darwin" {
+		return errors.New("macOS 不支持在程序中注册链接协议")
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if !filepath.IsAbs(dataHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	appsDir := filepath.Join(dataHome, "applications")
+	if err := os.MkdirAll(appsDir, 0755); err != nil {
+		return err
+	}
+	// 图标保存在数据目录中，程序移动后重新注册即可
+	iconPath := paths.DataPath("icon.png")
+	if err := os.WriteFile(iconPath, iconData, 0644); err != nil {
+		iconPath = ""
+	}
+	desktopPath := filepath.Join(appsDir, urlSchemeDesktopFile)
+	if err := os.WriteFile(desktopPath, []byte(desktopEntry(exePath, iconPath)), 0644); err != nil {
+		return err
+	}
+	// 更新 MIME 缓存，失败时不影响 xdg-mime 的设置
+	_ = exec.Command("update-desktop-database", appsDir).Run()
+	out, err := exec.Command("xdg-mime", "default", urlSchemeDesktopFile, "x-scheme-handler/"+URLScheme).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("已写入 %s，但无法设为默认程序（需要 xdg-utils）: %v %s", desktopPath, err, out)
+	}
+	return nil
+}
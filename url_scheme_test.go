@@ -0,0 +1,146 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSchemeURL(t *testing.T) {
+	audio, _ := FindDownloadProfile("audio")
+	video := DefaultDownloadProfile()
+	tests := []struct {
+		name    string
+		raw     string
+		want    []LaunchRequest
+		wantErr string
+	}{
+		{
+			name: "default profile",
+			raw:  "ytdlp-simpgo://download?url=https%3A%2F%2Fwww.youtube.com%2Fwatch%3Fv%3Dabc%26t%3D10",
+			want: []LaunchRequest{{URL: "https://www.youtube.com/watch?v=abc&t=10", Profile: video}},
+		},
+		{
+			name: "profile",
+			raw:  "ytdlp-simpgo://download?url=https%3A%2F%2Fexample.com%2Fv&profile=audio",
+			want: []LaunchRequest{{URL: "https://example.com/v", Profile: audio}},
+		},
+		{
+			name: "case insensitive and trailing slash",
+			raw:  "YTDLP-SIMPGO://Download/?profile=AUDIO&url=https%3A%2F%2Fexample.com%2Fv",
+			want: []LaunchRequest{{URL: "https://example.com/v", Profile: audio}},
+		},
+		{
+			name: "opaque form",
+			raw:  "ytdlp-simpgo:download?url=https%3A%2F%2Fexample.com%2Fv",
+			want: []LaunchRequest{{URL: "https://example.com/v", Profile: video}},
+		},
+		{
+			name: "multiple urls",
+			raw:  "ytdlp-simpgo:///download?url=https%3A%2F%2Fa.com%2F1&url=http%3A%2F%2Fb.com%2F2&profile=audio",
+			want: []LaunchRequest{{URL: "https://a.com/1", Profile: audio}, {URL: "http://b.com/2", Profile: audio}},
+		},
+		{name: "wrong scheme", raw: "https://download?url=https%3A%2F%2Fa.com", wantErr: "链接协议"},
+		{name: "unknown action", raw: "ytdlp-simpgo://delete?url=https%3A%2F%2Fa.com", wantErr: "不支持的操作"},
+		{name: "missing url", raw: "ytdlp-simpgo://download?profile=audio", wantErr: "缺少 url"},
+		{name: "unknown profile", raw: "ytdlp-simpgo://download?url=https%3A%2F%2Fa.com&profile=flac", wantErr: "未知的下载方案"},
+		{name: "duplicate profile", raw: "ytdlp-simpgo://download?url=https%3A%2F%2Fa.com&profile=audio&profile=720p", wantErr: "只能指定一个"},
+		{name: "unencoded url", raw: "ytdlp-simpgo://download?url=https://a.com/watch?v=1&t=10", wantErr: "未知的参数 \"t\""},
+		{name: "non http url", raw: "ytdlp-simpgo://download?url=file%3A%2F%2F%2Fetc%2Fpasswd", wantErr: "只支持 http"},
+		{name: "url without host", raw: "ytdlp-simpgo://download?url=https%3A%2F%2F%2Fpath", wantErr: "缺少主机名"},
+		{name: "url with space", raw: "ytdlp-simpgo://download?url=https%3A%2F%2Fa.com%2Fa%20b", wantErr: "空白字符"},
+		{name: "bad query", raw: "ytdlp-simpgo://download?url=%zz", wantErr: "链接"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSchemeURL(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].URL != tt.want[i].URL || got[i].Profile.Name != tt.want[i].Profile.Name {
+					t.Errorf("[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseLaunchArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string // URL@profile
+		wantErr string
+	}{
+		{name: "empty", args: nil},
+		{name: "plain urls", args: []string{"https://a.com/1", " HTTP://b.com/2 "}, want: []string{"https://a.com/1@video", "HTTP://b.com/2@video"}},
+		{name: "profile flag", args: []string{"https://a.com/1", "--profile", "audio", "https://a.com/2", "--profile=720p", "https://a.com/3"}, want: []string{"https://a.com/1@video", "https://a.com/2@audio", "https://a.com/3@720p"}},
+		{name: "scheme", args: []string{"ytdlp-simpgo://download?url=https%3A%2F%2Fa.com%2F1&profile=audio"}, want: []string{"https://a.com/1@audio"}},
+		{name: "invalid args kept apart", args: []string{"https://a.com/1", "--profile", "flac", "file.txt", "https://a.com/2"}, want: []string{"https://a.com/1@video", "https://a.com/2@video"}, wantErr: "无法识别的参数 \"file.txt\""},
+		{name: "profile without name", args: []string{"--profile"}, wantErr: "缺少方案名称"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs, err := parseLaunchArgs(tt.args)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+			var got []string
+			for _, r := range reqs {
+				got = append(got, r.URL+"@"+r.Profile.Name)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDesktopEntry(t *testing.T) {
+	entry := desktopEntry(`/opt/my apps/yt-dlp-simpgo`, "/home/u/.local/share/yt-dlp-simpgo/icon.png")
+	for _, line := range []string{
+		`Exec="/opt/my apps/yt-dlp-simpgo" %u`,
+		"Icon=/home/u/.local/share/yt-dlp-simpgo/icon.png",
+		"MimeType=x-scheme-handler/ytdlp-simpgo;",
+	} {
+		if !strings.Contains(entry, line+"\n") {
+			t.Errorf("desktop entry 缺少 %q:\n%s", line, entry)
+		}
+	}
+
+	tests := []struct{ path, want string }{
+		{`/usr/bin/app`, `"/usr/bin/app"`},
+		{`/tmp/100%/app`, `"/tmp/100%%/app"`},
+		{`/tmp/$HOME/a"b`, `"/tmp/\\$HOME/a\\"b"`},
+		{`/tmp/back\slash`, `"/tmp/back\\\\slash"`},
+	}
+	for _, tt := range tests {
+		if got := desktopExecQuote(tt.path); got != tt.want {
+			t.Errorf("desktopExecQuote(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFindDownloadProfile(t *testing.T) {
+	if p, err := FindDownloadProfile(""); err != nil || p.Name != "video" || len(p.Args) != 0 {
+		t.Errorf("默认方案 = %+v, %v", p, err)
+	}
+	if p, err := FindDownloadProfile("Audio"); err != nil || p.Name != "audio" {
+		t.Errorf("audio = %+v, %v", p, err)
+	}
+	if _, err := FindDownloadProfile("flac"); err == nil || !strings.Contains(err.Error(), "720p") {
+		t.Errorf("未知方案应列出可用方案: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package main
+
+import (
+	"golang.org/x/sys/windows/registry"
+)
+
+// registerURLScheme 在当前用户的 Software\Classes 下注册链接协议，不需要管理员权限。
+func registerURLScheme(exePath string, paths AppPaths) error {
+	root := `Software\Classes\` + URLScheme
+	set := func(path, name, value string) error {
+		k, _, err := registry.CreateKey(registry.CURRENT_USER, path, registry.SET_VALUE)
+		if err != nil {
+			return err
+		}
+		defer k.Close()
+		return k.SetStringValue(name, value)
+	}
+	values := []struct{ path, name, value string }{
+		{root, "", "URL:yt-dlp-simpgo"},
+		{root, "URL Protocol", ""},
+		{root + `\DefaultIcon`, "", `"` + exePath + `",0`},
+		{root + `\shell\open\command`, "", `"` + exePath + `" "%1"`},
+	}
+	for _, v := range values {
+		if err := set(v.path, v.name, v.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
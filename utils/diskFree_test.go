@@ -0,0 +1,19 @@
+package utils
+
+import "testing"
+
+func TestDiskFree(t *testing.T) {
+	free, err := DiskFree(t.TempDir())
+	if err != nil {
+		t.Fatalf("获取剩余空间失败: %v", err)
+	}
+	if free == 0 {
+		t.Error("临时目录所在分区剩余空间不应为 0")
+	}
+}
+
+func TestDiskFree_NotExists(t *testing.T) {
+	if _, err := DiskFree("/path/does/not/exist/at/all"); err == nil {
+		t.Error("不存在的路径应返回错误")
+	}
+}
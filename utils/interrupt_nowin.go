@@ -0,0 +1,10 @@
+//go:build !windows
+
+package utils
+
+import "os"
+
+// Interrupt 向进程发送中断信号（相当于按下 Ctrl+C），让进程有机会正常结束。
+func Interrupt(p *os.Process) error {
+	return p.Signal(os.Interrupt)
+}
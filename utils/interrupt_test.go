@@ -0,0 +1,30 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestInterrupt(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("无法启动 sleep: %v", err)
+	}
+	if err := Interrupt(cmd.Process); err != nil {
+		t.Fatalf("Interrupt 失败: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("进程应因中断信号退出")
+		}
+	case <-time.After(5 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("进程未响应中断信号")
+	}
+}
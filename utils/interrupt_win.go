@@ -0,0 +1,14 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"os"
+)
+
+// Interrupt 在 Windows 上不受支持：无控制台的子进程无法接收 Ctrl+C，
+// 调用方应改为结束进程并自行处理未完成的文件。
+func Interrupt(p *os.Process) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// UpdateSignatureExt 为发布文件签名的扩展名，签名文件与发布文件一起上传，如 yt-dlp-simpgo-linux-amd64.sig。
+const UpdateSignatureExt = ".sig"
+
+// updateSignatureContext 区分更新签名和同一密钥的其他用途。
+const updateSignatureContext = "yt-dlp-simpgo update signature v1"
+
+// ErrBadUpdateSignature 表示签名与文件不符。
+var ErrBadUpdateSignature = errors.New("更新文件签名无效")
+
+// updateSignatureMessage 返回被签名的内容：发布文件名、版本号和文件的 SHA-256。
+// 包含文件名和版本号，防止用其他平台或旧版本的已签名文件替换。
+func updateSignatureMessage(assetName, version string, sum []byte) []byte {
+	return []byte(updateSignatureContext + "\n" + assetName + "\n" + version + "\n" + hex.EncodeToString(sum) + "\n")
+}
+
+// FileSHA256 返回文件的 SHA-256。
+func FileSHA256(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// SignUpdate 用私钥为发布文件签名，返回签名文件的内容（base64 文本）。
+func SignUpdate(key ed25519.PrivateKey, path, assetName, version string) ([]byte, error) {
+	sum, err := FileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(key, updateSignatureMessage(assetName, version, sum))
+	return []byte(base64.StdEncoding.EncodeToString(sig) + "\n"), nil
+}
+
+// VerifyUpdate 用公钥校验发布文件的签名，sig 为签名文件的内容。
+func VerifyUpdate(key ed25519.PublicKey, path, assetName, version string, sig []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || len(raw) != ed25519.SignatureSize {
+		return fmt.Errorf("签名文件格式错误: %w", ErrBadUpdateSignature)
+	}
+	sum, err := FileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("无法读取更新文件: %w", err)
+	}
+	if !ed25519.Verify(key, updateSignatureMessage(assetName, version, sum), raw) {
+		return ErrBadUpdateSignature
+	}
+	return nil
+}
+
+// ParseUpdatePublicKey 解析 base64 编码的公钥。
+func ParseUpdatePublicKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("更新签名公钥格式错误")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// ParseUpdatePrivateKey 解析 base64 编码的私钥（ed25519 种子或完整私钥）。
+func ParseUpdatePrivateKey(s string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("签名私钥格式错误: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("签名私钥长度错误: %d", len(raw))
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignAndVerifyUpdate(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	path := filepath.Join(t.TempDir(), "yt-dlp-simpgo-linux-amd64")
+	if err := os.WriteFile(path, []byte("binary"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignUpdate(priv, path, "yt-dlp-simpgo-linux-amd64", "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     ed25519.PublicKey
+		asset   string
+		version string
+		sig     []byte
+		wantErr bool
+	}{
+		{name: "valid", key: pub, asset: "yt-dlp-simpgo-linux-amd64", version: "v1.2.0", sig: sig},
+		{name: "other key", key: otherPub, asset: "yt-dlp-simpgo-linux-amd64", version: "v1.2.0", sig: sig, wantErr: true},
+		{name: "other asset", key: pub, asset: "yt-dlp-simpgo-windows-amd64.exe", version: "v1.2.0", sig: sig, wantErr: true},
+		{name: "other version", key: pub, asset: "yt-dlp-simpgo-linux-amd64", version: "v1.1.0", sig: sig, wantErr: true},
+		{name: "garbage", key: pub, asset: "yt-dlp-simpgo-linux-amd64", version: "v1.2.0", sig: []byte("not base64!"), wantErr: true},
+	}
+	for _, tt := range tests {
+		err := VerifyUpdate(tt.key, path, tt.asset, tt.version, tt.sig)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: VerifyUpdate = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrBadUpdateSignature) {
+			t.Errorf("%s: 应返回 ErrBadUpdateSignature: %v", tt.name, err)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyUpdate(pub, path, "yt-dlp-simpgo-linux-amd64", "v1.2.0", sig); !errors.Is(err, ErrBadUpdateSignature) {
+		t.Errorf("文件被修改后校验应失败: %v", err)
+	}
+}
+
+func TestParseUpdateKeys(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	if got, err := ParseUpdatePublicKey(base64.StdEncoding.EncodeToString(pub)); err != nil || !got.Equal(pub) {
+		t.Errorf("ParseUpdatePublicKey = %v, %v", got, err)
+	}
+	if _, err := ParseUpdatePublicKey("c2hvcnQ="); err == nil {
+		t.Error("长度错误的公钥应返回错误")
+	}
+	for _, raw := range [][]byte{priv.Seed(), priv} {
+		if got, err := ParseUpdatePrivateKey(base64.StdEncoding.EncodeToString(raw)); err != nil || !got.Equal(priv) {
+			t.Errorf("ParseUpdatePrivateKey(%d 字节) = %v", len(raw), err)
+		}
+	}
+}
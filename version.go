@@ -3,4 +3,11 @@ package main
 // Version 通过构建时 ldflags 注入，默认值用于开发环境
 var Version = "dev"
 
+// releaseUpdatePublicKey 为发布文件签名使用的 ed25519 公钥（base64），私钥由发布流程保管。
+const releaseUpdatePublicKey = "WA8S9AYCOX9CS1J/HYuPbyAHOip9MTul1XOeg5aeiwU="
+
+// UpdatePublicKey 为校验程序更新签名的公钥，默认为 releaseUpdatePublicKey，
+// 测试时可通过构建时 ldflags 换成测试用的公钥
+var UpdatePublicKey = releaseUpdatePublicKey
+
 const Repository = "https://github.com/Yinr/yt-dlp-simpgo"
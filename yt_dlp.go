@@ -1,11 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -15,120 +14,70 @@ import (
 	"yinr.cc/yt-dlp-simpgo/utils"
 )
 
+// ytDlpLatestAPI 为 yt-dlp 最新版本的发布信息地址。
+const ytDlpLatestAPI = "https://api.github.com/repos/yt-dlp/yt-dlp/releases/latest"
+
+// ytDlpReleaseURL 返回当前平台 yt-dlp 最新版本的 GitHub 下载地址。
+func ytDlpReleaseURL() string {
+	if runtime.GOOS == "windows" {
+		return "https://github.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp.exe"
+	}
+	return "https://github.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp"
+}
+
 // DownloadYtDlpWithProgress 下载 yt-dlp 并定期调用 onProgress(received, total)。
-// total 为 -1 时表示未知。downloadProxy 非空时用作 HTTP(S) 代理。
-// ytDlpURL 非空时替换默认的 GitHub 下载地址。
-func DownloadYtDlpWithProgress(destDir string, downloadProxy string, ytDlpURL string, onProgress func(received, total int64)) (string, error) {
+// total 为 -1 时表示未知。src 为下载时使用的代理和镜像。
+// ytDlpURL 非空时先从该地址下载，失败后再从 GitHub 及其镜像下载。中断后再次下载时从断点继续。
+func DownloadYtDlpWithProgress(destDir string, src UpdateSource, ytDlpURL string, onProgress func(received, total int64)) (string, error) {
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return "", fmt.Errorf("无法创建目录: %w", err)
 	}
 	exeName := "yt-dlp"
-	urlStr := "https://github.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp"
 	if runtime.GOOS == "windows" {
 		exeName = "yt-dlp.exe"
-		urlStr = "https://github.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp.exe"
 	}
 	outPath := filepath.Join(destDir, exeName)
-
-	if ytDlpURL != "" {
-		urlStr = ytDlpURL
-	}
-
-	client := &http.Client{}
-	if downloadProxy != "" {
-		proxyURL, perr := url.Parse(downloadProxy)
-		if perr == nil {
-			client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
-		}
-	}
-
-	resp, err := client.Get(urlStr)
-	if err != nil {
-		return "", fmt.Errorf("下载请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("下载失败: %s", resp.Status)
+	if err := downloadYtDlpTo(outPath, src, ytDlpURL, onProgress); err != nil {
+		return "", err
 	}
+	return outPath, nil
+}
 
-	total := resp.ContentLength
-	tmp := outPath + ".tmp"
-	f, err := os.Create(tmp)
+func downloadYtDlpTo(outPath string, src UpdateSource, ytDlpURL string, onProgress func(received, total int64)) error {
+	urls := src.Mirrors.DownloadURLs(ytDlpReleaseURL())
+	if ytDlpURL != "" {
+		urls = append([]string{ytDlpURL}, urls...)
+	}
+	err := newHTTPDownloader(src.Proxy).Download(context.Background(), httpDownload{
+		URLs:       urls,
+		Dest:       outPath,
+		UserAgent:  "yt-dlp-simpgo/" + Version,
+		OnProgress: onProgress,
+	})
 	if err != nil {
-		return "", fmt.Errorf("无法创建临时文件: %w", err)
-	}
-
-	var written int64
-	buf := make([]byte, 32*1024)
-	for {
-		n, rerr := resp.Body.Read(buf)
-		if n > 0 {
-			wn, werr := f.Write(buf[:n])
-			if werr != nil {
-				_ = f.Close()
-				_ = os.Remove(tmp)
-				return "", fmt.Errorf("写入文件失败: %w", werr)
-			}
-			written += int64(wn)
-			if onProgress != nil {
-				onProgress(written, total)
-			}
-		}
-		if rerr != nil {
-			if rerr == io.EOF {
-				break
-			}
-			_ = f.Close()
-			_ = os.Remove(tmp)
-			return "", fmt.Errorf("读取响应失败: %w", rerr)
-		}
-	}
-
-	// Windows 上必须先关闭文件再重命名，否则会报"文件被占用"
-	if err := f.Close(); err != nil {
-		_ = os.Remove(tmp)
-		return "", fmt.Errorf("关闭文件失败: %w", err)
-	}
-
-	// Windows 上目标文件可能被其他进程锁定（杀毒扫描等），多次重试
-	var renameErr error
-	for i := 0; i < 6; i++ {
-		renameErr = os.Rename(tmp, outPath)
-		if renameErr == nil {
-			break
-		}
-		if _, statErr := os.Stat(outPath); statErr == nil {
-			_ = os.Remove(outPath)
-		}
-		time.Sleep(200 * time.Millisecond)
-	}
-	if renameErr != nil {
-		_ = os.Remove(tmp)
-		return "", fmt.Errorf("重命名文件失败: %w", renameErr)
+		return err
 	}
 
 	if runtime.GOOS != "windows" {
 		if err := os.Chmod(outPath, 0755); err != nil {
-			return outPath, fmt.Errorf("设置文件权限失败: %w", err)
+			return fmt.Errorf("设置文件权限失败: %w", err)
 		}
 	}
-	return outPath, nil
+	return nil
 }
 
-// UpdateYtDlp 运行 yt-dlp --update 并返回输出。
-// downloadProxy 非空时注入 HTTP_PROXY/HTTPS_PROXY 环境变量。
-func UpdateYtDlp(exePath string, downloadProxy string) (string, error) {
-	cmd := utils.ExecCmd(exePath, "--update")
-
-	if downloadProxy != "" {
-		env := os.Environ()
-		env = append(env, "HTTP_PROXY="+downloadProxy)
-		env = append(env, "HTTPS_PROXY="+downloadProxy)
-		env = append(env, "http_proxy="+downloadProxy)
-		env = append(env, "https_proxy="+downloadProxy)
-		cmd.Env = env
+// UpdateYtDlp 更新 yt-dlp 并返回输出。设置了下载镜像且 exePath 为程序下载到 dataDir 中的 yt-dlp 时，
+// 直接下载最新版本替换它；否则运行 yt-dlp --update，经由 src 中的代理更新。
+// PATH 中由系统或 pip 安装的 yt-dlp 不会被替换，由其自身决定能否更新。
+func UpdateYtDlp(exePath, dataDir string, src UpdateSource) (string, error) {
+	if len(src.Mirrors.Download) > 0 && isDataDirYtDlp(exePath, dataDir) {
+		if err := downloadYtDlpTo(exePath, src, "", nil); err != nil {
+			return "", fmt.Errorf("更新失败: %w", err)
+		}
+		return "已下载最新版本: " + exePath, nil
 	}
+	cmd := utils.ExecCmd(exePath, append([]string{"--update"}, ytDlpProxyArgs(src.Proxy)...)...)
+	applyProxyEnv(cmd, src.Proxy)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return string(out), fmt.Errorf("更新失败: %w", err)
@@ -136,6 +85,19 @@ func UpdateYtDlp(exePath string, downloadProxy string) (string, error) {
 	return string(out), nil
 }
 
+// isDataDirYtDlp 表示 exePath 直接位于 dataDir 中，即由程序下载的 yt-dlp。
+func isDataDirYtDlp(exePath, dataDir string) bool {
+	exe, err := filepath.Abs(exePath)
+	if err != nil {
+		return false
+	}
+	dir, err := filepath.Abs(dataDir)
+	if err != nil {
+		return false
+	}
+	return filepath.Dir(exe) == dir
+}
+
 // GetYtDlpVersion 获取本地 yt-dlp 版本。
 func GetYtDlpVersion(exePath string) (string, error) {
 	cmd := utils.ExecCmd(exePath, "--version")
@@ -149,56 +111,47 @@ func GetYtDlpVersion(exePath string) (string, error) {
 // GitHubRelease 用于解析 GitHub API 响应。
 type GitHubRelease struct {
 	TagName string `json:"tag_name"`
+	Body    string `json:"body"`
 }
 
-// GetLatestYtDlpVersion 从 GitHub API 获取最新版本号。
-func GetLatestYtDlpVersion(downloadProxy string) (string, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	if downloadProxy != "" {
-		proxyURL, perr := url.Parse(downloadProxy)
-		if perr == nil {
-			client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
-		}
-	}
+// GetLatestYtDlpRelease 从 GitHub API 获取最新版本的发布信息，失败时依次尝试镜像。
+func GetLatestYtDlpRelease(src UpdateSource) (*GitHubRelease, error) {
+	client := appHTTPClient(src.Proxy, 10*time.Second)
 
-	resp, err := client.Get("https://api.github.com/repos/yt-dlp/yt-dlp/releases/latest")
+	resp, err := getWithFallback(client, src.Mirrors.APIURLs(ytDlpLatestAPI), nil)
 	if err != nil {
-		return "", fmt.Errorf("请求 GitHub API 失败: %w", err)
+		return nil, fmt.Errorf("请求 GitHub API 失败: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("GitHub API 返回错误: %s", resp.Status)
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	var release GitHubRelease
 	if err := json.Unmarshal(body, &release); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
-	return release.TagName, nil
+	return &release, nil
 }
 
-// CheckYtDlpUpdate 检查是否有新版本，返回 (当前版本, 最新版本, 是否需要更新, 错误)。
-func CheckYtDlpUpdate(exePath string, downloadProxy string) (current, latest string, needUpdate bool, err error) {
+// CheckYtDlpUpdate 检查是否有新版本，返回 (当前版本, 最新版本的发布信息, 是否需要更新, 错误)。
+func CheckYtDlpUpdate(exePath string, src UpdateSource) (current string, latest *GitHubRelease, needUpdate bool, err error) {
 	current, err = GetYtDlpVersion(exePath)
 	if err != nil {
-		return "", "", false, err
+		return "", nil, false, err
 	}
 
-	latest, err = GetLatestYtDlpVersion(downloadProxy)
+	latest, err = GetLatestYtDlpRelease(src)
 	if err != nil {
-		return current, "", false, err
+		return current, nil, false, err
 	}
 
 	// 简单比较：去除可能的 v 前缀后比较
 	currentClean := strings.TrimPrefix(current, "v")
-	latestClean := strings.TrimPrefix(latest, "v")
+	latestClean := strings.TrimPrefix(latest.TagName, "v")
 
 	needUpdate = currentClean != latestClean
 	return current, latest, needUpdate, nil
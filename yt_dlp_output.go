@@ -10,11 +10,32 @@ import (
 
 var ytDlpDownloadProgressPattern = regexp.MustCompile(`^\[download\]\s+([0-9.]+)%\s+of\s+(.+?)(?:\s+at\s+(.+?))?(?:\s+ETA\s+(.+))?$`)
 
+// ytDlpLiveProgressPattern 匹配总大小未知时（如直播录制）的进度行，
+// 形如 "[download]   12.34MiB at  1.23MiB/s (00:01:23)"，可能附带分片信息。
+var ytDlpLiveProgressPattern = regexp.MustCompile(`^\[download\]\s+([0-9.]+\s*[KMGT]?i?B)\s+at\s+(.+?)\s+\((\d+:\d{2}(?::\d{2})?)\)`)
+
+// ffmpegProgressPattern 匹配 ffmpeg 录制直播时输出的状态行中的大小和时长。
+var ffmpegProgressPattern = regexp.MustCompile(`size=\s*(\S+)\s+time=\s*(\d+:\d{2}:\d{2})`)
+
+// ytDlpWaitPattern 匹配 --wait-for-video 等待直播开始时的倒计时。
+var ytDlpWaitPattern = regexp.MustCompile(`^\[wait\] (?:Waiting for|Remaining time until next attempt:) (\d+:\d{2}:\d{2})`)
+
+// liveStatusPrefix 标记 liveStatusPrintArgs 产生的输出行。
+const liveStatusPrefix = "simpgo-live-status:"
+
+// liveStatusPrintArgs 让 yt-dlp 在开始下载每个视频前打印其直播状态，
+// 值为 is_live、is_upcoming、was_live、not_live 等。需与 afterMovePrintArgs 中的 --no-quiet 一起使用。
+var liveStatusPrintArgs = []string{"--print", "before_dl:" + liveStatusPrefix + "%(live_status)s"}
+
 type YtDlpEvent struct {
 	Text        string
+	Level       LogLevel
 	RewriteLast bool
 	Progress    float64
 	HasProgress bool
+	File        *DownloadedFile
+	Live        bool   // 开始录制直播
+	Destination string // 正在写入的文件
 	Ok          bool
 }
 
@@ -23,12 +44,23 @@ func friendlyYtDlpLine(line string) YtDlpEvent {
 		return YtDlpEvent{}
 	}
 
+	if f, ok := parseAfterMoveLine(line); ok {
+		return YtDlpEvent{Text: "已保存: " + f.FilePath, File: f, Ok: true}
+	}
+
+	if status, ok := strings.CutPrefix(line, liveStatusPrefix); ok {
+		if status == "is_live" {
+			return YtDlpEvent{Text: "检测到直播，开始录制", Live: true, Progress: -1, HasProgress: true, Ok: true}
+		}
+		return YtDlpEvent{}
+	}
+
 	lower := strings.ToLower(line)
 	if strings.Contains(line, "ERROR:") || strings.Contains(lower, "error:") {
-		return YtDlpEvent{Text: line, Ok: true}
+		return YtDlpEvent{Text: line, Level: LogError, Ok: true}
 	}
 	if strings.Contains(line, "WARNING:") || strings.Contains(lower, "warning:") {
-		return YtDlpEvent{Text: line, Ok: true}
+		return YtDlpEvent{Text: line, Level: LogWarning, Ok: true}
 	}
 
 	if matches := ytDlpDownloadProgressPattern.FindStringSubmatch(line); matches != nil {
@@ -57,12 +89,28 @@ func friendlyYtDlpLine(line string) YtDlpEvent {
 		}
 	}
 
-	if strings.HasPrefix(line, "[download] Destination:") {
-		return YtDlpEvent{Text: "保存文件: " + strings.TrimSpace(strings.TrimPrefix(line, "[download] Destination:")), Ok: true}
+	if matches := ytDlpLiveProgressPattern.FindStringSubmatch(line); matches != nil {
+		text := fmt.Sprintf("录制中: 已录制 %s，时长 %s，速度 %s", matches[1], matches[3], strings.TrimSpace(matches[2]))
+		return YtDlpEvent{Text: text, RewriteLast: true, Progress: -1, HasProgress: true, Ok: true}
+	}
+	if matches := ffmpegProgressPattern.FindStringSubmatch(line); matches != nil {
+		text := fmt.Sprintf("录制中: 已录制 %s，时长 %s", matches[1], matches[2])
+		return YtDlpEvent{Text: text, RewriteLast: true, Progress: -1, HasProgress: true, Ok: true}
+	}
+	if matches := ytDlpWaitPattern.FindStringSubmatch(line); matches != nil {
+		return YtDlpEvent{Text: "等待直播开始，剩余 " + matches[1], RewriteLast: true, Progress: -1, HasProgress: true, Ok: true}
+	}
+
+	if dest, ok := strings.CutPrefix(line, "[download] Destination:"); ok {
+		dest = strings.TrimSpace(dest)
+		return YtDlpEvent{Text: "保存文件: " + dest, Destination: dest, Ok: true}
 	}
 	if strings.HasPrefix(line, "[download] ") && strings.Contains(line, "has already been downloaded") {
 		return YtDlpEvent{Text: "文件已存在，跳过下载", Progress: 1, HasProgress: true, Ok: true}
 	}
+	if strings.HasPrefix(line, "[download] ") && strings.Contains(line, "has already been recorded in the archive") {
+		return YtDlpEvent{Text: "已在下载记录中，跳过下载", Progress: 1, HasProgress: true, Ok: true}
+	}
 	if strings.HasPrefix(line, "[download] Downloading item ") {
 		return YtDlpEvent{Text: strings.TrimPrefix(line, "[download] "), Ok: true}
 	}
@@ -88,19 +136,30 @@ func friendlyYtDlpLine(line string) YtDlpEvent {
 	return YtDlpEvent{}
 }
 
-func readYtDlpPipe(r io.Reader, reporter ProgressReporter) {
+// readYtDlpPipe 读取 yt-dlp 输出：每行原样写入任务日志和原始输出，
+// 能识别的行再整理为友好的日志和进度，并通过 onEvent 回调。
+func readYtDlpPipe(r io.Reader, stream string, jobLog *JobLog, reporter ProgressReporter, onEvent func(YtDlpEvent)) {
 	readPipe(r, func(line string) {
+		jobLog.WriteLine(stream, line)
+		if line != "" {
+			reporter.log(LogRaw, line, isRewritableRawLine(line))
+		}
 		ev := friendlyYtDlpLine(line)
 		if !ev.Ok {
 			return
 		}
+		if onEvent != nil {
+			onEvent(ev)
+		}
 		if ev.HasProgress {
 			reporter.setProgress(ev.Text, ev.Progress)
 		}
-		if ev.RewriteLast {
-			reporter.rewriteLog(ev.Text)
-		} else {
-			reporter.appendLog(ev.Text)
-		}
+		reporter.log(ev.Level, ev.Text, ev.RewriteLast)
 	})
 }
+
+// isRewritableRawLine 表示原始输出行是会不断刷新的进度，在日志中只保留最新一行。
+func isRewritableRawLine(line string) bool {
+	return ytDlpDownloadProgressPattern.MatchString(line) || ytDlpLiveProgressPattern.MatchString(line) ||
+		ffmpegProgressPattern.MatchString(line) || ytDlpWaitPattern.MatchString(line)
+}
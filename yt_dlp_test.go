@@ -0,0 +1,24 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsDataDirYtDlp(t *testing.T) {
+	dataDir := t.TempDir()
+	tests := []struct {
+		exe  string
+		want bool
+	}{
+		{exe: filepath.Join(dataDir, "yt-dlp"), want: true},
+		{exe: filepath.Join(dataDir, "sub", "..", "yt-dlp.exe"), want: true},
+		{exe: filepath.Join(dataDir, "bin", "yt-dlp"), want: false},
+		{exe: "/usr/bin/yt-dlp", want: false},
+	}
+	for _, tt := range tests {
+		if got := isDataDirYtDlp(tt.exe, dataDir); got != tt.want {
+			t.Errorf("isDataDirYtDlp(%q) = %v, want %v", tt.exe, got, tt.want)
+		}
+	}
+}